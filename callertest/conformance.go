@@ -0,0 +1,139 @@
+package callertest
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/betwins/numbers-apply/generator"
+)
+
+const (
+	constAppName     = "callertest"
+	constBizType     = "conformance"
+	constDay         = "20240101"
+	constOtherDay    = "20240102"
+	constStep        = 100
+	constSequential  = 20
+	constConcurrency = 8
+	constPerWorker   = 10
+)
+
+// CallerConformanceTest 校验号段申请函数是否满足生成器依赖的约定：
+// 同一 appName + bizType + day 下号段单调递增且互不重叠、并发申请不重叠、按申请步长分配（声明了 GrantedStep 时可以更小）、
+// 不同日期和不同 bizType 相互隔离、返回的日期（如有）与申请日期一致
+// newCaller 每个子测试调用一次，应返回一个干净的（或至少与其它子测试隔离的）申请函数
+func CallerConformanceTest(t *testing.T, newCaller func() generator.NumbersReqFunc) {
+	t.Run("Sequential", func(t *testing.T) {
+		caller := newCaller()
+		var last int64
+		for i := 0; i < constSequential; i++ {
+			r := apply(t, caller, constAppName, constBizType, constDay, constStep)
+			if r.RangeStart <= last {
+				t.Fatalf("range %d-%d is not above previous end %d", r.RangeStart, r.RangeEnd, last)
+			}
+			last = r.RangeEnd
+		}
+	})
+
+	t.Run("StepHonored", func(t *testing.T) {
+		caller := newCaller()
+		for _, step := range []int{1, 10, constStep, 1000} {
+			r := apply(t, caller, constAppName, constBizType, constDay, step)
+			got := r.RangeEnd - r.RangeStart + 1
+			//服务端声明了实际分配的步长时允许小于申请的步长，但必须与号段宽度一致
+			if r.GrantedStep != 0 {
+				if r.GrantedStep != got || got > int64(step) {
+					t.Errorf("requested step %d, got range %d-%d of width %d with granted step %d", step, r.RangeStart, r.RangeEnd, got, r.GrantedStep)
+				}
+				continue
+			}
+			if got != int64(step) {
+				t.Errorf("requested step %d, got range %d-%d of width %d", step, r.RangeStart, r.RangeEnd, got)
+			}
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		caller := newCaller()
+		var mu sync.Mutex
+		var ranges []*generator.NewRangeResp
+		var wg sync.WaitGroup
+		for w := 0; w < constConcurrency; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < constPerWorker; i++ {
+					req := &generator.ApplyReq{AppName: constAppName, BizType: constBizType, Day: constDay, Step: constStep}
+					r, err := caller(req)
+					if err != nil {
+						t.Errorf("apply: %s", err.Error())
+						return
+					}
+					mu.Lock()
+					ranges = append(ranges, r)
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if err := checkDisjoint(ranges); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("DayIsolation", func(t *testing.T) {
+		caller := newCaller()
+		first := apply(t, caller, constAppName, constBizType, constDay, constStep)
+		other := apply(t, caller, constAppName, constBizType, constOtherDay, constStep)
+		next := apply(t, caller, constAppName, constBizType, constDay, constStep)
+		if next.RangeStart <= first.RangeEnd {
+			t.Errorf("day %s range %d-%d not above previous %d-%d after allocating day %s", constDay, next.RangeStart, next.RangeEnd, first.RangeStart, first.RangeEnd, constOtherDay)
+		}
+		if other.Day != "" && other.Day != constOtherDay {
+			t.Errorf("requested day %s, got %s", constOtherDay, other.Day)
+		}
+	})
+
+	t.Run("BizTypeIsolation", func(t *testing.T) {
+		caller := newCaller()
+		first := apply(t, caller, constAppName, constBizType, constDay, constStep)
+		apply(t, caller, constAppName, constBizType+"-other", constDay, constStep)
+		apply(t, caller, constAppName+"-other", constBizType, constDay, constStep)
+		next := apply(t, caller, constAppName, constBizType, constDay, constStep)
+		if next.RangeStart <= first.RangeEnd {
+			t.Errorf("range %d-%d not above previous %d-%d after allocating other biz types", next.RangeStart, next.RangeEnd, first.RangeStart, first.RangeEnd)
+		}
+	})
+}
+
+func apply(t *testing.T, caller generator.NumbersReqFunc, appName, bizType, day string, step int) *generator.NewRangeResp {
+	t.Helper()
+	req := &generator.ApplyReq{AppName: appName, BizType: bizType, Day: day, Step: step}
+	r, err := caller(req)
+	if err != nil {
+		t.Fatalf("apply %s %s %s step %d: %s", appName, bizType, day, step, err.Error())
+	}
+	if r == nil {
+		t.Fatalf("apply %s %s %s step %d: nil response", appName, bizType, day, step)
+	}
+	if r.RangeStart <= 0 || r.RangeEnd < r.RangeStart {
+		t.Fatalf("apply %s %s %s step %d: invalid range %d-%d", appName, bizType, day, step, r.RangeStart, r.RangeEnd)
+	}
+	if r.Day != "" && r.Day != day {
+		t.Fatalf("apply %s %s %s step %d: response day %s", appName, bizType, day, step, r.Day)
+	}
+	return r
+}
+
+func checkDisjoint(ranges []*generator.NewRangeResp) error {
+	sorted := append([]*generator.NewRangeResp(nil), ranges...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].RangeStart < sorted[j].RangeStart })
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].RangeStart <= sorted[i-1].RangeEnd {
+			return fmt.Errorf("ranges %d-%d and %d-%d overlap", sorted[i-1].RangeStart, sorted[i-1].RangeEnd, sorted[i].RangeStart, sorted[i].RangeEnd)
+		}
+	}
+	return nil
+}
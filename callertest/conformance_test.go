@@ -0,0 +1,37 @@
+package callertest
+
+import (
+	"testing"
+
+	"github.com/betwins/numbers-apply/generator"
+	"github.com/betwins/numbers-apply/testsupport"
+)
+
+func TestMemoryCallerConformance(t *testing.T) {
+	CallerConformanceTest(t, func() generator.NumbersReqFunc {
+		return testsupport.NewMemoryCaller().Caller()
+	})
+}
+
+// cappedCaller 每次最多分配 max 个号码并通过 GrantedStep 声明实际步长
+func cappedCaller(max int) generator.NumbersReqFunc {
+	inner := testsupport.NewMemoryCaller()
+	return func(req *generator.ApplyReq) (*generator.NewRangeResp, error) {
+		capped := *req
+		if capped.Step > max {
+			capped.Step = max
+		}
+		resp, err := inner.Apply(&capped)
+		if err != nil {
+			return nil, err
+		}
+		resp.GrantedStep = int64(capped.Step)
+		return resp, nil
+	}
+}
+
+func TestCappedCallerConformance(t *testing.T) {
+	CallerConformanceTest(t, func() generator.NumbersReqFunc {
+		return cappedCaller(50)
+	})
+}
@@ -0,0 +1,100 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// RawNumbersReqFunc 返回服务端原始响应的号段申请函数，需配合 WithResponseAdapter 使用
+type RawNumbersReqFunc func(req *ApplyReq) (any, error)
+
+// ResponseAdapter 将服务端原始响应转换为号段
+type ResponseAdapter func(raw any) (*NewRangeResp, error)
+
+// MaxIdStepResp 常见的号段服务响应：服务端把 max_id 增加 step 后返回新的 max_id
+type MaxIdStepResp struct {
+	MaxId int64 `json:"max_id"`
+	Step  int64 `json:"step"`
+}
+
+// invokeCaller 调用号段申请函数，配置了原始申请函数时先调用再经适配器转换
+// 申请函数或适配器返回 nil 号段且没有错误时按 ErrInvalidResponse 处理，避免调用方解引用 nil，panic 时按 ErrCallerPanic 处理
+func (usage *RangeUsageInfoStruct) invokeCaller(req *ApplyReq) (resp *NewRangeResp, err error) {
+	defer usage.recoverCaller(req, &err)
+	if usage.cfg.rawCaller == nil {
+		if usage.reqNumbersCaller == nil {
+			return nil, ErrNotInitialized
+		}
+		if resp, err = usage.reqNumbersCaller(req); err != nil {
+			return nil, wrapError(ErrCallerFailed, err)
+		}
+	} else {
+		var raw any
+		if raw, err = usage.cfg.rawCaller(req); err != nil {
+			return nil, wrapError(ErrCallerFailed, err)
+		}
+		resp, err = usage.cfg.responseAdapter(raw)
+	}
+	if err == nil && resp == nil {
+		return nil, fmt.Errorf("%w: nil range response", ErrInvalidResponse)
+	}
+	return resp, err
+}
+
+// MaxIdStepAdapter 将 {max_id, step} 响应转换为号段：RangeStart = max_id - step + 1，RangeEnd = max_id
+// 支持 MaxIdStepResp、*MaxIdStepResp、json 原始数据以及 json 解码后的 map（键为 max_id 或 maxId，以及 step）
+func MaxIdStepAdapter(raw any) (*NewRangeResp, error) {
+	var r MaxIdStepResp
+	switch v := raw.(type) {
+	case MaxIdStepResp:
+		r = v
+	case *MaxIdStepResp:
+		if v == nil {
+			return nil, fmt.Errorf("%w: nil max_id/step response", ErrInvalidResponse)
+		}
+		r = *v
+	case []byte:
+		if err := json.Unmarshal(v, &r); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidResponse, err.Error())
+		}
+	case json.RawMessage:
+		if err := json.Unmarshal(v, &r); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidResponse, err.Error())
+		}
+	case map[string]any:
+		maxId, ok := v["max_id"]
+		if !ok {
+			maxId = v["maxId"]
+		}
+		var err error
+		if r.MaxId, err = toInt64(maxId); err != nil {
+			return nil, fmt.Errorf("%w: max_id %s", ErrInvalidResponse, err.Error())
+		}
+		if r.Step, err = toInt64(v["step"]); err != nil {
+			return nil, fmt.Errorf("%w: step %s", ErrInvalidResponse, err.Error())
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported max_id/step response %T", ErrInvalidResponse, raw)
+	}
+	if r.Step <= 0 || r.MaxId < r.Step {
+		return nil, fmt.Errorf("%w: max_id %d step %d", ErrInvalidResponse, r.MaxId, r.Step)
+	}
+	return &NewRangeResp{RangeStart: r.MaxId - r.Step + 1, RangeEnd: r.MaxId}, nil
+}
+
+func toInt64(v any) (int64, error) {
+	switch n := v.(type) {
+	case int64:
+		return n, nil
+	case int:
+		return int64(n), nil
+	case float64:
+		if n != float64(int64(n)) {
+			return 0, fmt.Errorf("%v is not an integer", n)
+		}
+		return int64(n), nil
+	case json.Number:
+		return n.Int64()
+	}
+	return 0, fmt.Errorf("unsupported value %T", v)
+}
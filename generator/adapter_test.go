@@ -0,0 +1,84 @@
+package generator
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestMaxIdStepAdapter(t *testing.T) {
+	want := NewRangeResp{RangeStart: 91, RangeEnd: 100}
+	cases := []struct {
+		name string
+		raw  any
+	}{
+		{"struct", MaxIdStepResp{MaxId: 100, Step: 10}},
+		{"pointer", &MaxIdStepResp{MaxId: 100, Step: 10}},
+		{"json", []byte(`{"max_id":100,"step":10}`)},
+		{"raw message", json.RawMessage(`{"max_id":100,"step":10}`)},
+		{"decoded map", map[string]any{"max_id": float64(100), "step": float64(10)}},
+		{"camel case map", map[string]any{"maxId": 100, "step": 10}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := MaxIdStepAdapter(tc.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *resp != want {
+				t.Fatalf("range %+v, want %+v", *resp, want)
+			}
+		})
+	}
+}
+
+func TestMaxIdStepAdapterInvalid(t *testing.T) {
+	for _, raw := range []any{
+		(*MaxIdStepResp)(nil),
+		MaxIdStepResp{MaxId: 5, Step: 10},
+		MaxIdStepResp{MaxId: 100},
+		[]byte(`not json`),
+		map[string]any{"max_id": 1.5, "step": 1},
+		"100,10",
+	} {
+		if resp, err := MaxIdStepAdapter(raw); !errors.Is(err, ErrInvalidResponse) {
+			t.Fatalf("MaxIdStepAdapter(%#v) = %+v, %v, want ErrInvalidResponse", raw, resp, err)
+		}
+	}
+}
+
+// TestRawCallerMaxIdStep 号段服务只返回 {max_id, step}，生成器经适配器得到连续且不重复的号段
+func TestRawCallerMaxIdStep(t *testing.T) {
+	var m sync.Mutex
+	var maxId int64
+	raw := func(req *ApplyReq) (any, error) {
+		m.Lock()
+		defer m.Unlock()
+		maxId += int64(req.Step)
+		return map[string]any{"max_id": float64(maxId), "step": float64(req.Step)}, nil
+	}
+	usage, err := NewWithOptions(nil, testOptions(WithStep(10), WithRawCaller(raw), WithResponseAdapter(MaxIdStepAdapter))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	var last int64
+	for i := 0; i < 35; i++ {
+		id, err := usage.GenerateId("app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts, err := usage.Parse(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.Lock()
+		issued := maxId
+		m.Unlock()
+		if parts.Fallback || parts.Sequence <= last || parts.Sequence > issued {
+			t.Fatalf("id %s sequence %d, want above %d and within max_id %d", id, parts.Sequence, last, issued)
+		}
+		last = parts.Sequence
+	}
+}
@@ -0,0 +1,17 @@
+package generator
+
+import "fmt"
+
+// bindAppName 校验生成调用传入的应用名：必须在构造时通过 WithAppName 设置应用名，没有设置时返回 ErrNotInitialized；
+// 传入不同的应用名返回 ErrAppNameMismatch，传入空串时使用构造时设置的应用名
+// appName 构造后不再改变，避免同一个实例被不同的应用共用而申请到其它应用的号段
+func (usage *RangeUsageInfoStruct) bindAppName(applicationName string) (string, error) {
+	if usage.appName == "" {
+		return "", fmt.Errorf("%w: app name is not set, see WithAppName", ErrNotInitialized)
+	}
+	if applicationName != "" && applicationName != usage.appName {
+		usage.logs.Error("{} {} {} 应用名与实例不一致 {}", usage.appName, usage.bizType, usage.prefix, applicationName)
+		return "", fmt.Errorf("%w: generator is bound to %q, got %q", ErrAppNameMismatch, usage.appName, applicationName)
+	}
+	return usage.appName, nil
+}
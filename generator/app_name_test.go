@@ -0,0 +1,47 @@
+package generator
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewRequiresAppName(t *testing.T) {
+	caller := newMemCaller()
+	usage := New(caller.apply, WithPrefix("T"))
+	defer usage.Close()
+	for _, app := range []string{"app", "other", ""} {
+		if id, err := usage.GenerateId(app); !errors.Is(err, ErrNotInitialized) {
+			t.Fatalf("GenerateId(%q) = %q, %v, want ErrNotInitialized", app, id, err)
+		}
+	}
+	if _, err := usage.GenerateInt64("app"); !errors.Is(err, ErrNotInitialized) {
+		t.Fatalf("GenerateInt64 error %v, want ErrNotInitialized", err)
+	}
+	if caller.callCount() != 0 {
+		t.Fatalf("%d range requests without an app name", caller.callCount())
+	}
+}
+
+func TestAppNameMismatch(t *testing.T) {
+	cases := []struct {
+		name    string
+		app     string
+		wantErr error
+	}{
+		{"same", "app", nil},
+		{"empty uses bound name", "", nil},
+		{"other", "other", ErrAppNameMismatch},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			usage := New(newMemCaller().apply, testOptions()...)
+			defer usage.Close()
+			if _, err := usage.GenerateId(tc.app); !errors.Is(err, tc.wantErr) {
+				t.Fatalf("GenerateId(%q) error %v, want %v", tc.app, err, tc.wantErr)
+			}
+			if usage.appName != "app" {
+				t.Fatalf("app name %q, want app", usage.appName)
+			}
+		})
+	}
+}
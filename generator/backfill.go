@@ -0,0 +1,177 @@
+package generator
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// GenerateBatchForDateRange 为数据回填生成 from 到 to（含）之间每一天各 perDay 个 id，按日期（20060102，WithPeriod 设置了其它周期时为周期标识）分组返回：
+// 每一天单独按该日期申请号段，生成的 id 使用对应的日期，不影响当前正在使用的号段；日期范围包含今天时同样单独申请号段，
+// 号段服务按日期独占分配，与正常生成的 id 不会重复。号段申请失败或服务端返回了其它日期的号段时返回错误，不会降级生成随机 id；
+// 静态节点模式（WithStaticNodeAssignment）下不支持，返回 ErrInvalidOption
+func (usage *RangeUsageInfoStruct) GenerateBatchForDateRange(applicationName string, from, to time.Time, perDay int) (map[string][]string, error) {
+	if atomic.LoadInt32(&usage.closed) != 0 {
+		return nil, ErrClosed
+	}
+	if err := usage.checkBackfill(); err != nil {
+		return nil, err
+	}
+	if perDay <= 0 || to.Before(from) {
+		return nil, fmt.Errorf("%w: backfill %s - %s per day %d", ErrInvalidOption, from.Format(time.RFC3339), to.Format(time.RFC3339), perDay)
+	}
+	applicationName, err := usage.bindAppName(applicationName)
+	if err != nil {
+		return nil, err
+	}
+	loc := usage.cfg.clock.Now().Location()
+	day, last := usage.periodKey(from.In(loc)), usage.periodKey(to.In(loc))
+
+	finalPrefix := usage.currentPrefix()
+	if usage.cfg.bizCode != "" {
+		finalPrefix = joinPrefix(finalPrefix, usage.cfg.bizCode)
+	}
+	batches := make(map[string][]string)
+	for {
+		ids, err := usage.backfillDay(applicationName, day, perDay, finalPrefix)
+		if err != nil {
+			return nil, err
+		}
+		batches[day] = ids
+		if day == last {
+			return batches, nil
+		}
+		if day, err = usage.cfg.period.next(day, loc); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// backfillDay 按 day 申请号段并生成 n 个 id，服务端返回的号段不足 n 个时继续申请
+func (usage *RangeUsageInfoStruct) backfillDay(applicationName string, day string, n int, finalPrefix string) ([]string, error) {
+	ids := make([]string, 0, n)
+	for len(ids) < n {
+		req := ApplyReq{
+			AppName: applicationName,
+			BizType: usage.bizType,
+			Day:     day,
+			Step:    n - len(ids),
+		}
+		resp, err := usage.callNumbers(&req)
+		if err != nil {
+			usage.logs.Error("{} {} {} 回填申请号段出错 {} {}", applicationName, usage.bizType, usage.prefix, day, err.Error())
+			return nil, err
+		}
+		if resp.Day != "" && resp.Day != day {
+			return nil, fmt.Errorf("%w: requested %s, got %s", ErrRangeDayMismatch, day, resp.Day)
+		}
+		if resp.RangeStart <= 0 || resp.RangeEnd < resp.RangeStart {
+			return nil, fmt.Errorf("%w: range %d-%d", ErrInvalidResponse, resp.RangeStart, resp.RangeEnd)
+		}
+		for seq := resp.RangeStart; seq <= resp.RangeEnd && len(ids) < n; seq++ {
+			id, err := usage.backfillKey(seq, finalPrefix, day)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+	}
+	atomic.AddInt64(&usage.counters.generated, int64(n))
+	return ids, nil
+}
+
+// backfillKey 按指定日期生成 id，开启 UUID 输出时同样转换为 UUID
+func (usage *RangeUsageInfoStruct) backfillKey(seq int64, finalPrefix string, day string) (string, error) {
+	id, err := usage.generateKey(seq, finalPrefix, day, usage.cfg.typeFlag)
+	if err != nil {
+		return "", err
+	}
+	if usage.cfg.uuidNamespace != nil {
+		id = usage.toUUID(id)
+	}
+	if usage.cfg.maxIdLen > 0 && len(id) > usage.cfg.maxIdLen {
+		return "", fmt.Errorf("%w: %s longer than %d", ErrIdTooLong, id, usage.cfg.maxIdLen)
+	}
+	return id, nil
+}
+
+// datedRanges GenerateIdForDate 按日期缓存的号段，最多缓存 constMaxDatedRanges 个日期，超出时淘汰最早缓存的日期
+type datedRanges struct {
+	m      sync.Mutex
+	ranges map[string]*idRange //start 为下一个可用号码
+	order  []string            //缓存日期的先后顺序
+}
+
+const constMaxDatedRanges = 32
+
+// GenerateIdForDate 为 date 所在的日期（WithPeriod 设置了其它周期时为所在周期）生成一个 id，用于回填历史日期或预先生成未来日期的 id：
+// 每个日期单独向号段服务按 WithStep 的步长申请号段并缓存，后续同一日期的调用从缓存的号段取号，与当前正在使用的号段互不影响，
+// date 为今天时同样单独申请号段。同一实例上的调用串行执行，号段申请失败时返回错误，不会降级生成随机 id；
+// 与 GenerateBatchForDateRange 相同，静态节点模式下不支持
+func (usage *RangeUsageInfoStruct) GenerateIdForDate(applicationName string, date time.Time) (string, error) {
+	if atomic.LoadInt32(&usage.closed) != 0 {
+		return "", ErrClosed
+	}
+	if err := usage.checkBackfill(); err != nil {
+		return "", err
+	}
+	applicationName, err := usage.bindAppName(applicationName)
+	if err != nil {
+		return "", err
+	}
+	day := usage.periodKey(date.In(usage.cfg.clock.Now().Location()))
+	finalPrefix := usage.currentPrefix()
+	if usage.cfg.bizCode != "" {
+		finalPrefix = joinPrefix(finalPrefix, usage.cfg.bizCode)
+	}
+
+	dated := &usage.dated
+	dated.m.Lock()
+	defer dated.m.Unlock()
+	r := dated.ranges[day]
+	if r == nil || r.start > r.end {
+		req := ApplyReq{
+			AppName: applicationName,
+			BizType: usage.bizType,
+			Day:     day,
+			Step:    usage.step(),
+		}
+		resp, err := usage.callNumbers(&req)
+		if err != nil {
+			usage.logs.Error("{} {} {} 按日期申请号段出错 {} {}", applicationName, usage.bizType, usage.prefix, day, err.Error())
+			return "", err
+		}
+		if resp.Day != "" && resp.Day != day {
+			return "", fmt.Errorf("%w: requested %s, got %s", ErrRangeDayMismatch, day, resp.Day)
+		}
+		if resp.RangeStart <= 0 || resp.RangeEnd < resp.RangeStart {
+			return "", fmt.Errorf("%w: range %d-%d", ErrInvalidResponse, resp.RangeStart, resp.RangeEnd)
+		}
+		r = &idRange{start: resp.RangeStart, end: resp.RangeEnd, day: day, rangeDay: day}
+		dated.put(day, r)
+	}
+	seq := r.start
+	r.start++
+	id, err := usage.backfillKey(seq, finalPrefix, day)
+	if err != nil {
+		return "", err
+	}
+	atomic.AddInt64(&usage.counters.generated, 1)
+	return id, nil
+}
+
+// put 缓存 day 的号段，调用方需持有 m
+func (d *datedRanges) put(day string, r *idRange) {
+	if d.ranges == nil {
+		d.ranges = make(map[string]*idRange)
+	}
+	if _, ok := d.ranges[day]; !ok {
+		if len(d.order) >= constMaxDatedRanges {
+			delete(d.ranges, d.order[0])
+			d.order = d.order[1:]
+		}
+		d.order = append(d.order, day)
+	}
+	d.ranges[day] = r
+}
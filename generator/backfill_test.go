@@ -0,0 +1,108 @@
+package generator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateBatchForDateRange(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 5, 10, 0, 0, 0, time.Local))
+	usage, err := NewWithOptions(newMemCaller().apply, testOptions(WithClock(clock), WithStep(10))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2024, 1, 1, 23, 0, 0, 0, time.Local)
+	to := time.Date(2024, 1, 3, 1, 0, 0, 0, time.Local)
+
+	seen := make(map[string]bool)
+	for round := 0; round < 2; round++ {
+		batches, err := usage.GenerateBatchForDateRange("app", from, to, 25)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(batches) != 3 {
+			t.Fatalf("got %d days, want 3", len(batches))
+		}
+		for _, day := range []string{"20240101", "20240102", "20240103"} {
+			ids := batches[day]
+			if len(ids) != 25 {
+				t.Fatalf("%s: got %d ids, want 25", day, len(ids))
+			}
+			for _, id := range ids {
+				if !strings.HasPrefix(id, "T-"+day) {
+					t.Fatalf("%s: id %s has wrong date", day, id)
+				}
+				if seen[id] {
+					t.Fatalf("%s issued twice", id)
+				}
+				seen[id] = true
+			}
+		}
+		//回填之间的正常生成不影响回填日期的号段
+		id, err := usage.GenerateId("app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(id, "T-20240105") || seen[id] {
+			t.Fatalf("live id %s", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestGenerateIdForDate(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 5, 10, 0, 0, 0, time.Local))
+	usage, err := NewWithOptions(newMemCaller().apply, testOptions(WithClock(clock), WithStep(3))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		date := time.Date(2024, 1, 1+i%3, 12, 0, 0, 0, time.Local)
+		id, err := usage.GenerateIdForDate("app", date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(id, "T-"+date.Format("20060102")) || seen[id] {
+			t.Fatalf("backfill id %s", id)
+		}
+		seen[id] = true
+		if id, err = usage.GenerateId("app"); err != nil || seen[id] {
+			t.Fatal(id, err)
+		}
+		seen[id] = true
+	}
+}
+
+func TestBackfillRejectedWithStaticNodes(t *testing.T) {
+	usage, err := NewWithOptions(nil, testOptions(WithStaticNodeAssignment(0, 2))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().AddDate(0, 0, -1)
+	if _, err := usage.GenerateBatchForDateRange("app", past, past, 3); !errors.Is(err, ErrInvalidOption) {
+		t.Fatalf("GenerateBatchForDateRange: got %v, want ErrInvalidOption", err)
+	}
+	if _, err := usage.GenerateIdForDate("app", past); !errors.Is(err, ErrInvalidOption) {
+		t.Fatalf("GenerateIdForDate: got %v, want ErrInvalidOption", err)
+	}
+}
+
+func TestStaticNodeCallerPerDayCursor(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local))
+	c := &staticNodeCaller{clock: clock, period: PeriodDay}
+	seen := make(map[string]int64)
+	//当前周期与下一周期交替申请，各自的号段不能回退重叠
+	for i, day := range []string{"20240101", "20240102", "20240101", "20240102", "20240101"} {
+		resp, err := c.apply(&ApplyReq{Day: day, Step: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.RangeStart <= seen[day] {
+			t.Fatalf("request %d for %s: range %d-%d overlaps previous end %d", i, day, resp.RangeStart, resp.RangeEnd, seen[day])
+		}
+		seen[day] = resp.RangeEnd
+	}
+}
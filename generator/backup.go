@@ -0,0 +1,32 @@
+package generator
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// callBackup 主号段服务申请失败后改用 WithBackupCaller 设置的备用申请函数，同样持有分布式锁；
+// 备用服务不受主服务熔断器影响，也不计入主服务的健康状态
+func (usage *RangeUsageInfoStruct) callBackup(req *ApplyReq, primaryErr error) (*NewRangeResp, error) {
+	usage.logs.Warn("{} {} {} 主号段服务申请失败，改用备用号段服务 {}", usage.appName, usage.bizType, usage.prefix, primaryErr.Error())
+	resp, err := usage.invokeLocked(req, usage.invokeBackup)
+	if err != nil {
+		atomic.AddInt64(&usage.counters.backupErrors, 1)
+		usage.logs.Error("{} {} {} 备用号段服务申请失败 {}", usage.appName, usage.bizType, usage.prefix, err.Error())
+		return nil, fmt.Errorf("%w; backup: %s", primaryErr, err.Error())
+	}
+	atomic.AddInt64(&usage.counters.backupRanges, 1)
+	usage.detectDuplicateRange(req, resp)
+	return resp, nil
+}
+
+func (usage *RangeUsageInfoStruct) invokeBackup(req *ApplyReq) (resp *NewRangeResp, err error) {
+	defer usage.recoverCaller(req, &err)
+	if resp, err = usage.cfg.backupCaller(req); err != nil {
+		return nil, wrapError(ErrCallerFailed, err)
+	}
+	if resp == nil {
+		return nil, fmt.Errorf("%w: nil range response", ErrInvalidResponse)
+	}
+	return resp, err
+}
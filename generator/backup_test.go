@@ -0,0 +1,88 @@
+package generator
+
+import (
+	"sync"
+	"testing"
+)
+
+// replicaCaller 共用同一个号段分配器的主备号段服务之一，可以单独设置为失败
+type replicaCaller struct {
+	store *memCaller
+	m     sync.Mutex
+	fail  bool
+	calls int
+}
+
+func (c *replicaCaller) apply(req *ApplyReq) (*NewRangeResp, error) {
+	c.m.Lock()
+	c.calls++
+	fail := c.fail
+	c.m.Unlock()
+	if fail {
+		return nil, errDown
+	}
+	return c.store.apply(req)
+}
+
+func (c *replicaCaller) setFail(fail bool) {
+	c.m.Lock()
+	c.fail = fail
+	c.m.Unlock()
+}
+
+func (c *replicaCaller) callCount() int {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.calls
+}
+
+// TestBackupCallerUsed 主号段服务失败时改用备用服务的号段生成正常 id，两者都失败时才降级
+func TestBackupCallerUsed(t *testing.T) {
+	store := newMemCaller()
+	primary, backup := &replicaCaller{store: store}, &replicaCaller{store: store}
+	primary.setFail(true)
+	usage, err := NewWithOptions(primary.apply, testOptions(WithStep(10), WithBackupCaller(backup.apply))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	generate := func(n int, wantFallback bool) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			id, err := usage.GenerateId("app")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parts, err := usage.Parse(id); err != nil || parts.Fallback != wantFallback {
+				t.Fatalf("id %s parsed to %+v (%v), want fallback %v", id, parts, err, wantFallback)
+			}
+		}
+	}
+
+	generate(25, false)
+	waitRefreshIdle(t, usage)
+	s := usage.Stats()
+	if backupCalls := int64(backup.callCount()); backupCalls < 3 || s.BackupRanges != backupCalls {
+		t.Fatalf("BackupRanges %d, backup calls %d, want every range from the backup", s.BackupRanges, backupCalls)
+	}
+	if s.PrimaryRanges != 0 || s.Fallbacks != 0 {
+		t.Fatalf("stats %+v, want no primary ranges and no fallbacks", s)
+	}
+
+	primary.setFail(false)
+	generate(25, false)
+	waitRefreshIdle(t, usage)
+	if s := usage.Stats(); s.PrimaryRanges == 0 {
+		t.Fatalf("stats %+v, want ranges from the recovered primary", s)
+	}
+
+	primary.setFail(true)
+	backup.setFail(true)
+	usage.usageM.Lock()
+	usage.currentMaxId = usage.currentRangeEnd
+	usage.usageM.Unlock()
+	generate(1, true)
+	if s := usage.Stats(); s.BackupErrors == 0 || s.Fallbacks != 1 {
+		t.Fatalf("stats %+v, want a backup error and one fallback", s)
+	}
+}
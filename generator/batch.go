@@ -0,0 +1,134 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// idBlock 一次在锁内从当前号段预留的连续号码
+type idBlock struct {
+	start    int64
+	count    int64 //为 0 时当前号段没有可用号码，需要按单个 id 的流程申请新号段
+	day      string
+	prefetch bool
+	err      error
+}
+
+// GenerateIds 批量生成 n 个 id，用于批量导入等场景：每次加锁从当前号段预留尽量多的连续号码再逐个格式化，
+// 不必像循环调用 GenerateId 那样每个 id 都加锁、判断是否申请号段；当前号段用完时按 GenerateId 的流程申请新号段（失败时同样降级），
+// 再继续按段预留。任一 id 生成失败时返回错误，已预留的号码不再发放；开启自检或溢出到后一天时逐个生成
+func (usage *RangeUsageInfoStruct) GenerateIds(applicationName string, n int) ([]string, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("%w: batch size %d", ErrInvalidOption, n)
+	}
+	if _, err := usage.bindAppName(applicationName); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, n)
+	if usage.cfg.selfCheck || usage.cfg.overflowToNextDay {
+		for len(ids) < n {
+			id, err := usage.GenerateId(applicationName)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+		return ids, nil
+	}
+
+	ctx := context.Background()
+	release, err := usage.acquireInflight(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	flag := usage.cfg.typeFlag
+	finalPrefix := usage.currentPrefix()
+	if usage.cfg.bizCode != "" {
+		finalPrefix = joinPrefix(finalPrefix, usage.cfg.bizCode)
+	}
+	rejected := 0
+	for len(ids) < n {
+		if atomic.LoadInt32(&usage.closed) != 0 {
+			return nil, ErrClosed
+		}
+		now := usage.cfg.clock.Now()
+		block := usage.takeBlock(now, int64(n-len(ids)))
+		usage.flushDayEvents()
+		if block.err != nil {
+			return nil, block.err
+		}
+		if block.prefetch {
+			usage.triggerPrefetch(ApplyReq{
+				AppName: usage.appName,
+				BizType: usage.bizType,
+				Day:     usage.requestDay(usage.periodKey(now)),
+				Step:    usage.step(),
+			})
+		}
+		if block.count == 0 {
+			//当前号段已用完，按单个 id 的流程申请新号段，拿到新号段后继续按段预留
+			id, err := usage.nextValidId(ctx, applicationName, "", flag, nil, time.Time{})
+			if err != nil {
+				return nil, err
+			}
+			if err := usage.acceptId(id); err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+			continue
+		}
+		usage.logs.Debug("{} {} {} 批量预留号码 {} {}", usage.appName, usage.bizType, usage.prefix, block.start, block.count)
+		for seq := block.start; seq < block.start+block.count; seq++ {
+			id, err := usage.generateKey(seq, finalPrefix, block.day, flag)
+			if err != nil {
+				usage.recordGenerateError(err)
+				return nil, err
+			}
+			if usage.cfg.uuidNamespace != nil {
+				id = usage.toUUID(id)
+			}
+			if usage.cfg.idValidator != nil {
+				if verr := usage.cfg.idValidator(id); verr != nil {
+					atomic.AddInt64(&usage.counters.rejectedIds, 1)
+					if rejected++; rejected > constMaxValidatorRetries {
+						usage.logs.Error("{} {} {} 连续 {} 个 id 被校验函数拒绝 {} {}", usage.appName, usage.bizType, usage.prefix, rejected, id, verr.Error())
+						return nil, fmt.Errorf("%w: %s after %d attempts: %s", ErrIdRejected, id, rejected, verr.Error())
+					}
+					continue
+				}
+				rejected = 0
+			}
+			if err := usage.acceptId(id); err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// takeBlock 在锁内从当前号段预留最多 want 个连续号码，不会越过当前号段的结束号码；
+// 跨天或当前号段已用完时返回 count 为 0
+func (usage *RangeUsageInfoStruct) takeBlock(now time.Time, want int64) idBlock {
+	usage.usageM.Lock()
+	defer usage.usageM.Unlock()
+	if err := usage.checkFutureDateLocked(now); err != nil {
+		return idBlock{err: err}
+	}
+	if !usage.samePeriod(now, usage.applyDate) {
+		return idBlock{}
+	}
+	available := usage.currentRangeEnd - usage.currentMaxId
+	if available <= 0 {
+		return idBlock{}
+	}
+	if want > available {
+		want = available
+	}
+	start := usage.currentMaxId + 1
+	atomic.AddInt64(&usage.currentMaxId, want)
+	return idBlock{start: start, count: want, day: usage.rangeDay, prefetch: usage.prefetchNeededLocked()}
+}
@@ -0,0 +1,52 @@
+package generator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBizCodeInID(t *testing.T) {
+	cases := []struct {
+		name       string
+		opts       []Option
+		appendPfx  string
+		wantPrefix string
+	}{
+		{"plain", nil, "", "T"},
+		{"append prefix", nil, "PAY", "T-PAY"},
+		{"date separator", []Option{WithDateSeparator("_")}, "", "T"},
+		{"empty prefix", []Option{WithPrefix(""), WithBizType("ORD")}, "", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			usage, err := NewWithOptions(newMemCaller().apply, testOptions(append(tc.opts, WithBizCodeInID("OD1"))...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer usage.Close()
+			id, err := usage.GenerateIdWithAppendPrefix("app", tc.appendPfx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(id, "OD1-") {
+				t.Fatalf("id %s does not contain the biz code", id)
+			}
+			parts, err := usage.Parse(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parts.BizCode != "OD1" || parts.Prefix != tc.wantPrefix || parts.Sequence != 1 {
+				t.Fatalf("Parse(%s) = %+v, want biz code OD1, prefix %q, sequence 1", id, parts, tc.wantPrefix)
+			}
+		})
+	}
+}
+
+func TestBizCodeInvalid(t *testing.T) {
+	for _, code := range []string{"toolong", "od", "A-B"} {
+		if _, err := NewWithOptions(newMemCaller().apply, testOptions(WithBizCodeInID(code))...); !errors.Is(err, ErrInvalidOption) {
+			t.Fatalf("biz code %q error %v, want ErrInvalidOption", code, err)
+		}
+	}
+}
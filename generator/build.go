@@ -0,0 +1,69 @@
+package generator
+
+import "fmt"
+
+// BuildID 不依赖号段服务，按 opts 中的格式和编码选项用给定的前缀、日期（格式 20060102，WithPeriod 设置了其它周期时为周期标识，无日期模式下忽略）和序号拼出 id，
+// 用于测试和排查时构造期望的 id：seq 是 id 中的序号，即 Parse 返回的 IdParts.Sequence，静态节点模式下为映射到本节点之后的序号；
+// prefix 覆盖 WithPrefix 的设置，可以带追加前缀，如 ORD-PAY；开启 UUID 输出时同样转换为 UUID；
+// 开启 WithInstanceTagInID 时需要实例标识，请使用 BuildIDFromParts；选项不合法时返回 ErrInvalidOption
+func BuildID(prefix, date string, seq int64, opts ...Option) (string, error) {
+	return BuildIDFromParts(&IdParts{Prefix: prefix, Day: date, Sequence: seq}, opts...)
+}
+
+// BuildIDFromParts 按 opts 中的格式和编码选项用 Parse 解析出的各部分拼出 id，BuildIDFromParts(Parse(id)) 得到原来的 id；
+// 只使用 parts 中的内容，不查询本机标识：业务线代码和类型标识为空时使用 opts 中的设置，实例标识取自 parts.InstanceTag，
+// 分片字符按 WithShardFunc 重新计算；降级 id 的随机部分无法重建，返回 ErrInvalidId
+func BuildIDFromParts(parts *IdParts, opts ...Option) (string, error) {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	cfg.prefix = parts.Prefix
+	cfg.expvarName = ""
+	if err := cfg.validate(); err != nil {
+		return "", err
+	}
+	if parts.Fallback {
+		return "", fmt.Errorf("%w: fallback id can not be rebuilt", ErrInvalidId)
+	}
+	if !cfg.dateless && !cfg.period.isKey(parts.Day) {
+		return "", fmt.Errorf("%w: date %q", ErrInvalidOption, parts.Day)
+	}
+	if cfg.instanceTag && len(parts.InstanceTag) != constFallbackTagLen {
+		return "", fmt.Errorf("%w: instance tag %q", ErrInvalidOption, parts.InstanceTag)
+	}
+	cfg.normalize()
+	//只需要编码相关的字段，不经过 newRangeUsage，避免查询本机标识
+	usage := &RangeUsageInfoStruct{
+		logs:        cfg.logs,
+		prefix:      cfg.prefix,
+		idPrefix:    cfg.prefix,
+		fallbackTag: parts.InstanceTag,
+		cfg:         cfg,
+	}
+	//generateKey 会再做一次节点映射，先还原为本地序号
+	local, ok := usage.localSeq(parts.Sequence)
+	if !ok {
+		return "", fmt.Errorf("%w: sequence %d does not belong to node %d of %d", ErrInvalidOption, parts.Sequence, cfg.staticNodeID, cfg.staticNodes)
+	}
+	finalPrefix := parts.Prefix
+	bizCode := parts.BizCode
+	if bizCode == "" {
+		bizCode = cfg.bizCode
+	}
+	if bizCode != "" {
+		finalPrefix = joinPrefix(finalPrefix, bizCode)
+	}
+	flag := parts.TypeFlag
+	if flag == 0 {
+		flag = cfg.typeFlag
+	}
+	id, err := usage.generateKey(local, finalPrefix, parts.Day, flag)
+	if err != nil {
+		return "", err
+	}
+	if cfg.uuidNamespace != nil {
+		id = usage.toUUID(id)
+	}
+	return id, nil
+}
@@ -0,0 +1,85 @@
+package generator
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBuildIDRoundTrip(t *testing.T) {
+	shard := func(seq int64) byte { return constShardAlphabet[seq%int64(len(constShardAlphabet))] }
+	cases := []struct {
+		name string
+		opts []Option
+	}{
+		{"default", nil},
+		{"scramble", []Option{WithScramble(true)}},
+		{"radix", []Option{WithSequenceRadix(36, 0)}},
+		{"dateless", []Option{WithDateless(true)}},
+		{"biz code shard", []Option{WithBizCodeInID("PAY"), WithShardFunc(shard)}},
+		{"type flag check char", []Option{WithTypeFlag('K', nil), WithCheckChar(true)}},
+		{"static nodes", []Option{WithStaticNodeAssignment(2, 3)}},
+		{"static nodes scramble shard", []Option{WithStaticNodeAssignment(1, 4), WithScramble(true), WithShardFunc(shard)}},
+		{"instance tag", []Option{WithInstanceTagInID(true)}},
+		{"static nodes instance tag", []Option{WithStaticNodeAssignment(0, 2), WithInstanceTagInID(true)}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := newFakeClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local))
+			opts := testOptions(append(tc.opts, WithClock(clock), WithStep(10))...)
+			usage, err := NewWithOptions(newMemCaller().apply, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer usage.Close()
+			for i := 0; i < 30; i++ {
+				appendPrefix := ""
+				if i%2 == 1 {
+					appendPrefix = "SUB"
+				}
+				id, err := usage.GenerateIdWithAppendPrefix("app", appendPrefix)
+				if err != nil {
+					t.Fatal(err)
+				}
+				parts, err := usage.Parse(id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				built, err := BuildIDFromParts(parts, opts...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if built != id {
+					t.Fatalf("BuildIDFromParts(Parse(%s)) = %s", id, built)
+				}
+				if parts.InstanceTag != "" {
+					continue
+				}
+				if built, err = BuildID(parts.Prefix, parts.Day, parts.Sequence, opts...); err != nil || built != id {
+					t.Fatalf("BuildID(%s, %s, %d) = %s, %v, want %s", parts.Prefix, parts.Day, parts.Sequence, built, err, id)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildIDInvalid(t *testing.T) {
+	cases := []struct {
+		name  string
+		parts IdParts
+		opts  []Option
+		want  error
+	}{
+		{"bad date", IdParts{Prefix: "T", Day: "2024", Sequence: 1}, nil, ErrInvalidOption},
+		{"missing instance tag", IdParts{Prefix: "T", Day: "20240101", Sequence: 1}, []Option{WithInstanceTagInID(true)}, ErrInvalidOption},
+		{"foreign node sequence", IdParts{Prefix: "T", Day: "20240101", Sequence: 5}, []Option{WithStaticNodeAssignment(1, 3)}, ErrInvalidOption},
+		{"fallback", IdParts{Prefix: "T", Day: "20240101", Fallback: true}, nil, ErrInvalidId},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if id, err := BuildIDFromParts(&tc.parts, tc.opts...); !errors.Is(err, tc.want) {
+				t.Fatalf("BuildIDFromParts %q, %v, want %v", id, err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package generator
+
+import (
+	"sync"
+	"time"
+)
+
+const constMaxCacheTTL = time.Second //CachingCaller 缓存时间上限，号段是一次性分配的，不能长时间缓存
+
+// cachedRange 一次进行中或刚完成的号段申请
+type cachedRange struct {
+	done    chan struct{}
+	resp    *NewRangeResp
+	err     error
+	expires time.Time
+}
+
+// CachingCaller 包装号段申请函数：ttl 内参数完全相同（appName、bizType、日期、步长）的申请只请求一次号段服务，
+// 并发或快速重试的申请方共用同一个结果，避免重试风暴时号段服务重复分配号段；失败结果不缓存
+//
+// 号段是一次性分配的，共用号段只对同一个生成器实例安全（生成器切换号段时会丢弃与已用号码重叠的部分），
+// 不要让多个生成器实例共用同一个包装后的申请函数；ttl 最长 1 秒，步长为 1 的单次号码申请不缓存
+// 共用的号段会被生成器计为重复号段（Stats.DuplicateRanges）
+func CachingCaller(caller NumbersReqFunc, ttl time.Duration) NumbersReqFunc {
+	if ttl > constMaxCacheTTL {
+		ttl = constMaxCacheTTL
+	}
+	var m sync.Mutex
+	cache := make(map[ApplyReq]*cachedRange)
+	return func(req *ApplyReq) (*NewRangeResp, error) {
+		if ttl <= 0 || req.Step <= 1 {
+			return caller(req)
+		}
+		key := *req
+		key.tags, key.ctx = nil, nil
+		now := time.Now()
+		m.Lock()
+		for k, c := range cache {
+			if !c.expires.IsZero() && now.After(c.expires) {
+				delete(cache, k)
+			}
+		}
+		if c, ok := cache[key]; ok {
+			m.Unlock()
+			<-c.done
+			return copyRange(c.resp), c.err
+		}
+		c := &cachedRange{done: make(chan struct{})}
+		cache[key] = c
+		m.Unlock()
+
+		c.resp, c.err = caller(req)
+		m.Lock()
+		if c.err != nil {
+			delete(cache, key)
+		} else {
+			c.expires = time.Now().Add(ttl)
+		}
+		m.Unlock()
+		close(c.done)
+		return copyRange(c.resp), c.err
+	}
+}
+
+func copyRange(resp *NewRangeResp) *NewRangeResp {
+	if resp == nil {
+		return nil
+	}
+	copied := *resp
+	return &copied
+}
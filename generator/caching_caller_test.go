@@ -0,0 +1,111 @@
+package generator
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingCaller 记录号段申请次数，每次申请前等待 delay 让并发的申请方重叠
+type countingCaller struct {
+	*memCaller
+	delay time.Duration
+}
+
+func (c *countingCaller) apply(req *ApplyReq) (*NewRangeResp, error) {
+	time.Sleep(c.delay)
+	return c.memCaller.apply(req)
+}
+
+func TestCachingCallerConcurrent(t *testing.T) {
+	inner := &countingCaller{memCaller: newMemCaller(), delay: 20 * time.Millisecond}
+	caller := CachingCaller(inner.apply, 200*time.Millisecond)
+	const workers = 8
+	resps := make([]*NewRangeResp, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := caller(&ApplyReq{AppName: "app", BizType: "T", Day: "20240101", Step: 100})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resps[i] = resp
+		}(i)
+	}
+	wg.Wait()
+	if calls := inner.callCount(); calls != 1 {
+		t.Fatalf("caller invoked %d times for identical concurrent requests, want 1", calls)
+	}
+	for i, resp := range resps {
+		if resp == nil || *resp != *resps[0] {
+			t.Fatalf("worker %d got %+v, want the shared range %+v", i, resp, resps[0])
+		}
+	}
+}
+
+func TestCachingCallerDistinct(t *testing.T) {
+	inner := &countingCaller{memCaller: newMemCaller()}
+	caller := CachingCaller(inner.apply, 200*time.Millisecond)
+	reqs := []ApplyReq{
+		{AppName: "app", BizType: "T", Day: "20240101", Step: 100},
+		{AppName: "app", BizType: "T", Day: "20240102", Step: 100},
+		{AppName: "app", BizType: "T", Day: "20240101", Step: 50},
+		{AppName: "app", BizType: "U", Day: "20240101", Step: 100},
+		{AppName: "other", BizType: "T", Day: "20240101", Step: 100},
+	}
+	for i := range reqs {
+		if _, err := caller(&reqs[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls := inner.callCount(); calls != len(reqs) {
+		t.Fatalf("caller invoked %d times for %d distinct requests", calls, len(reqs))
+	}
+	//单次号码申请不缓存
+	for i := 0; i < 2; i++ {
+		if _, err := caller(&ApplyReq{AppName: "app", BizType: "T", Day: "20240101", Step: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls := inner.callCount(); calls != len(reqs)+2 {
+		t.Fatalf("step 1 requests were cached: %d calls", calls)
+	}
+}
+
+func TestCachingCallerExpiry(t *testing.T) {
+	inner := &countingCaller{memCaller: newMemCaller()}
+	caller := CachingCaller(inner.apply, 20*time.Millisecond)
+	req := ApplyReq{AppName: "app", BizType: "T", Day: "20240101", Step: 100}
+	first, err := caller(&req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := caller(&req); err != nil || *again != *first || inner.callCount() != 1 {
+		t.Fatalf("request within ttl got %+v, %v after %d calls, want the cached range", again, err, inner.callCount())
+	}
+	time.Sleep(40 * time.Millisecond)
+	next, err := caller(&req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inner.callCount() != 2 || next.RangeStart <= first.RangeEnd {
+		t.Fatalf("request after ttl got %+v, want a new range after %+v", next, first)
+	}
+}
+
+func TestCachingCallerErrorNotCached(t *testing.T) {
+	inner := &countingCaller{memCaller: newMemCaller()}
+	inner.setFail(true)
+	caller := CachingCaller(inner.apply, 200*time.Millisecond)
+	req := ApplyReq{AppName: "app", BizType: "T", Day: "20240101", Step: 100}
+	if _, err := caller(&req); err == nil {
+		t.Fatal("want the caller error")
+	}
+	inner.setFail(false)
+	if _, err := caller(&req); err != nil {
+		t.Fatalf("failed result was cached: %v", err)
+	}
+}
@@ -0,0 +1,19 @@
+package generator
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// recoverCaller 号段申请函数（含原始申请函数、响应适配器和备用申请函数）panic 时恢复并转换为 ErrCallerPanic，
+// 与普通的申请失败一样计入失败次数和熔断器，之后按配置降级或返回错误，一个有问题的申请函数不会导致整个进程退出；
+// 错误信息中带有 panic 时的调用栈。需要 defer 调用
+func (usage *RangeUsageInfoStruct) recoverCaller(req *ApplyReq, err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	stack := debug.Stack()
+	usage.logs.Error("{} {} {} 号段申请函数 panic {} {}\n{}", req.AppName, req.BizType, req.Day, r, string(stack))
+	*err = fmt.Errorf("%w: %v\n%s", ErrCallerPanic, r, stack)
+}
@@ -0,0 +1,106 @@
+package generator
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// panicCaller 每次申请都 panic，记录被调用的次数
+type panicCaller struct {
+	calls int32
+}
+
+func (c *panicCaller) apply(*ApplyReq) (*NewRangeResp, error) {
+	atomic.AddInt32(&c.calls, 1)
+	panic("caller bug")
+}
+
+// TestCallerPanicPolicy 申请函数 panic 时生成器恢复，按配置降级、返回错误或改用备用服务，并计入熔断器
+func TestCallerPanicPolicy(t *testing.T) {
+	t.Run("fallback", func(t *testing.T) {
+		caller := &panicCaller{}
+		usage, err := NewWithOptions(caller.apply, testOptions()...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer usage.Close()
+		id, err := usage.GenerateId("app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parts := mustParse(t, usage, id); !parts.Fallback {
+			t.Fatalf("id %s after a caller panic, want a fallback id", id)
+		}
+		if s := usage.Stats(); s.RangeErrors != 1 || s.Fallbacks != 1 {
+			t.Fatalf("stats RangeErrors %d Fallbacks %d, want 1 and 1", s.RangeErrors, s.Fallbacks)
+		}
+	})
+
+	t.Run("no fallback", func(t *testing.T) {
+		usage, err := NewWithOptions((&panicCaller{}).apply, testOptions(WithNoFallback())...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer usage.Close()
+		_, err = usage.GenerateId("app")
+		if !errors.Is(err, ErrSegmentUnavailable) || !errors.Is(err, ErrCallerPanic) {
+			t.Fatalf("got %v, want ErrSegmentUnavailable caused by ErrCallerPanic", err)
+		}
+		if msg := err.Error(); !strings.Contains(msg, "caller bug") || !strings.Contains(msg, "caller_panic_test.go") {
+			t.Fatalf("error %q, want the panic value and stack", msg)
+		}
+	})
+
+	t.Run("backup", func(t *testing.T) {
+		usage, err := NewWithOptions((&panicCaller{}).apply, testOptions(WithBackupCaller(newMemCaller().apply))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer usage.Close()
+		id, err := usage.GenerateId("app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parts := mustParse(t, usage, id); parts.Fallback {
+			t.Fatalf("id %s, want a range id from the backup", id)
+		}
+	})
+
+	t.Run("raw caller", func(t *testing.T) {
+		raw := func(*ApplyReq) (any, error) { panic("raw caller bug") }
+		usage, err := NewWithOptions(nil, testOptions(WithRawCaller(raw), WithResponseAdapter(func(any) (*NewRangeResp, error) {
+			return nil, errors.New("unreachable")
+		}), WithNoFallback())...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer usage.Close()
+		if _, err := usage.GenerateId("app"); !errors.Is(err, ErrCallerPanic) {
+			t.Fatalf("got %v, want ErrCallerPanic", err)
+		}
+	})
+
+	t.Run("circuit breaker", func(t *testing.T) {
+		caller := &panicCaller{}
+		clock := newFakeClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local))
+		usage, err := NewWithOptions(caller.apply, testOptions(WithClock(clock), WithStep(1), WithCircuitBreaker(2, time.Minute))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer usage.Close()
+		for i := 0; i < 5; i++ {
+			if _, err := usage.GenerateId("app"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if calls := atomic.LoadInt32(&caller.calls); calls != 2 {
+			t.Fatalf("caller invoked %d times, want the breaker to open after 2 panics", calls)
+		}
+		if info := usage.DegradedReason(); info.Reason != ReasonCircuitOpen {
+			t.Fatalf("DegradedReason %+v, want circuit open", info)
+		}
+	})
+}
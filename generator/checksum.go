@@ -0,0 +1,74 @@
+package generator
+
+import "fmt"
+
+const (
+	constCheckAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	constCheckModulus  = len(constCheckAlphabet)
+)
+
+// checkCodePoint 校验字符计算时字符对应的值，小写字母按大写处理，分隔符等其它字符不参与计算
+func checkCodePoint(ch byte) (int, bool) {
+	switch {
+	case ch >= '0' && ch <= '9':
+		return int(ch - '0'), true
+	case ch >= 'A' && ch <= 'Z':
+		return int(ch-'A') + 10, true
+	case ch >= 'a' && ch <= 'z':
+		return int(ch-'a') + 10, true
+	}
+	return 0, false
+}
+
+// luhnSum 按 Luhn mod 36 从右往左累加，doubleFirst 为 true 时最右边的字符加倍（计算校验字符时），否则从倒数第二个字符开始加倍（校验时）
+func luhnSum(s string, doubleFirst bool) int {
+	factor := 1
+	if doubleFirst {
+		factor = 2
+	}
+	sum := 0
+	for i := len(s) - 1; i >= 0; i-- {
+		v, ok := checkCodePoint(s[i])
+		if !ok {
+			continue
+		}
+		addend := factor * v
+		addend = addend/constCheckModulus + addend%constCheckModulus
+		sum += addend
+		factor = 3 - factor
+	}
+	return sum
+}
+
+// checkChar 计算 id 的校验字符，能发现单个字符输错和绝大多数相邻字符颠倒
+func checkChar(id string) byte {
+	remainder := luhnSum(id, true) % constCheckModulus
+	return constCheckAlphabet[(constCheckModulus-remainder)%constCheckModulus]
+}
+
+// withCheckChar 开启 WithCheckChar 时在 id 末尾追加校验字符
+func (usage *RangeUsageInfoStruct) withCheckChar(id string) string {
+	if !usage.cfg.checkChar {
+		return id
+	}
+	return id + string(checkChar(id))
+}
+
+// stripCheckChar 校验并去掉 id 末尾的校验字符
+func stripCheckChar(id string) (string, error) {
+	if len(id) < 2 {
+		return "", fmt.Errorf("%w: %s too short", ErrInvalidId, id)
+	}
+	if _, ok := checkCodePoint(id[len(id)-1]); !ok || luhnSum(id, false)%constCheckModulus != 0 {
+		return "", fmt.Errorf("%w: %s", ErrChecksumMismatch, id)
+	}
+	return id[:len(id)-1], nil
+}
+
+// Validate 离线校验 id：开启 WithCheckChar 时先校验末尾的校验字符，再按当前格式和 WithLegacyFormats 注册的历史格式解析，
+// 用于在查库之前拒绝输错的 id；校验字符不一致时返回 ErrChecksumMismatch，格式不对时返回 ErrInvalidId
+// 校验通过只说明 id 可能由本生成器生成，不代表 id 已经发放或存在
+func (usage *RangeUsageInfoStruct) Validate(id string) error {
+	_, err := usage.Parse(id)
+	return err
+}
@@ -0,0 +1,109 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+const constReleaseTimeout = 3 * time.Second //Close 时归还每个号段的超时时间
+
+// NumbersClient 号段服务客户端，在 NumbersReqFunc 的基础上增加了归还号段和关闭的生命周期钩子，
+// Apply 的 ctx 与 req.Context() 相同；NumbersReqFunc 实现了该接口，Release、Close 为空操作
+type NumbersClient interface {
+	Apply(ctx context.Context, req *ApplyReq) (*NewRangeResp, error)
+	Release(ctx context.Context, req *ReleaseReq) error //归还申请到但没有使用的号码，号段服务可以忽略
+	Close() error
+}
+
+// ReleaseReq 归还未使用的号码，RangeStart 到 RangeEnd（含）之间的号码都没有发出过
+type ReleaseReq struct {
+	AppName    string `json:"appName"`
+	BizType    string `json:"bizType"`
+	Day        string `json:"day"` //号段申请时的日期，与 ApplyReq.Day 相同
+	RangeStart int64  `json:"rangeStart"`
+	RangeEnd   int64  `json:"rangeEnd"`
+}
+
+// Apply 调用 f 申请号段，号段申请函数通过 req.Context() 取得 ctx，req 没有 ctx 时为 Apply 的 ctx
+func (f NumbersReqFunc) Apply(ctx context.Context, req *ApplyReq) (*NewRangeResp, error) {
+	if ctx != nil && req.ctx == nil {
+		//未带 ctx 的申请使用 Apply 的 ctx，f 通过 req.Context 获取
+		req = ApplyReqWithContext(ctx, *req)
+	}
+	return f(req)
+}
+
+// Release NumbersReqFunc 不支持归还号段，直接返回
+func (f NumbersReqFunc) Release(context.Context, *ReleaseReq) error {
+	return nil
+}
+
+// Close NumbersReqFunc 没有需要释放的资源
+func (f NumbersReqFunc) Close() error {
+	return nil
+}
+
+// NewWithClient 与 NewWithOptions 相同，号段通过 client 申请；Close 时把未用完的号码（当前号段剩余部分、预取和预热的号段、
+// GenerateIdForDate 缓存的号段）通过 client.Release 归还，再调用 client.Close
+func NewWithClient(client NumbersClient, opts ...Option) (*RangeUsageInfoStruct, error) {
+	if client == nil {
+		return nil, fmt.Errorf("%w: numbers client is nil", ErrInvalidOption)
+	}
+	caller := func(req *ApplyReq) (*NewRangeResp, error) {
+		return client.Apply(req.Context(), req)
+	}
+	return NewWithOptions(caller, append(opts, func(c *config) { c.client = client })...)
+}
+
+// releaseRanges 收回并归还所有未用完的号码，收回后进行中的生成调用不会再从这些号段取号；
+// 当前号段已保存到 StateStore 时不归还，留给重启后的实例继续使用
+func (usage *RangeUsageInfoStruct) releaseRanges() {
+	var reqs []ReleaseReq
+	keepCurrent := usage.cfg.gapPolicy == MinimizeGaps && usage.cfg.stateStore != nil
+	usage.usageM.Lock()
+	if !keepCurrent && usage.currentRangeEnd > 0 && usage.currentMaxId < usage.currentRangeEnd {
+		day := usage.rangeDay
+		if day == "" {
+			day = usage.periodKey(usage.applyDate)
+		}
+		reqs = append(reqs, ReleaseReq{Day: day, RangeStart: usage.currentMaxId + 1, RangeEnd: usage.currentRangeEnd})
+		atomic.StoreInt64(&usage.currentRangeEnd, usage.currentMaxId)
+	}
+	for _, r := range usage.rangeQueue {
+		reqs = append(reqs, ReleaseReq{Day: r.day, RangeStart: r.start, RangeEnd: r.end})
+	}
+	usage.rangeQueue = nil
+	if r := usage.prewarmed; r != nil {
+		reqs = append(reqs, ReleaseReq{Day: r.day, RangeStart: r.start, RangeEnd: r.end})
+		usage.prewarmed = nil
+	}
+	usage.usageM.Unlock()
+
+	dated := &usage.dated
+	dated.m.Lock()
+	for _, day := range dated.order {
+		if r := dated.ranges[day]; r.start <= r.end {
+			reqs = append(reqs, ReleaseReq{Day: day, RangeStart: r.start, RangeEnd: r.end})
+		}
+	}
+	dated.ranges, dated.order = nil, nil
+	dated.m.Unlock()
+
+	for i := range reqs {
+		req := &reqs[i]
+		req.AppName, req.BizType = usage.appName, usage.bizType
+		if usage.cfg.dateless {
+			req.Day = constDatelessNamespace
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), constReleaseTimeout)
+		err := usage.cfg.client.Release(ctx, req)
+		cancel()
+		if err != nil {
+			usage.logs.Warn("{} {} {} 归还号段失败 {} {} {} {}", usage.appName, usage.bizType, usage.prefix, req.Day, req.RangeStart, req.RangeEnd, err.Error())
+			continue
+		}
+		usage.logs.Info("{} {} {} 归还号段 {} {} {}", usage.appName, usage.bizType, usage.prefix, req.Day, req.RangeStart, req.RangeEnd)
+	}
+}
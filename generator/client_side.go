@@ -0,0 +1,39 @@
+package generator
+
+import "sync/atomic"
+
+// step 申请号段的步长，客户端模式下为 WithClientSideMode 设置的块大小
+func (usage *RangeUsageInfoStruct) step() int {
+	live := usage.tunables()
+	configured := live.rangeStep()
+	//服务端分配不足时按 adaptStep 缩小的步长申请，不超过配置的步长，Reconfigure 调小步长后立即生效；客户端模式始终申请整块
+	if adaptive := atomic.LoadInt64(&usage.counters.adaptiveStep); live.clientBlockSize == 0 && adaptive > 0 && adaptive < int64(configured) {
+		return int(adaptive)
+	}
+	return configured
+}
+
+// rangeStep 按配置计算申请号段的步长，依次为客户端模式的块大小、WithStep、constIncrementStep
+func (c *config) rangeStep() int {
+	if c.clientBlockSize > 0 {
+		return c.clientBlockSize
+	}
+	if c.step > 0 {
+		return c.step
+	}
+	return constIncrementStep
+}
+
+// refreshThreshold 剩余号码少于该值时申请新号段，客户端模式下号段完全用完才申请
+// 服务端实际分配的步长过小时按步长缩小阈值，避免新号段刚拿到就低于阈值、每次取号都申请号段
+func (usage *RangeUsageInfoStruct) refreshThreshold() int64 {
+	live := usage.tunables()
+	if live.clientBlockSize > 0 {
+		return 1
+	}
+	base := int64(LeastAvailableIdNum)
+	if live.threshold > 0 {
+		base = live.threshold
+	}
+	return grantedThreshold(atomic.LoadInt64(&usage.counters.grantedStep), base)
+}
@@ -0,0 +1,76 @@
+package generator
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClientSideMode(t *testing.T) {
+	cases := []struct {
+		name      string
+		blockSize int //块足够大时每天只申请一次号段
+		perDay    int
+		wantCalls int
+	}{
+		{"one block per day", 100000, 5000, 2},
+		{"block exhausted", 1000, 2500, 6},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			caller := newMemCaller()
+			clock := newFakeClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local))
+			usage, err := NewWithOptions(caller.apply, testOptions(WithClock(clock), WithClientSideMode(tc.blockSize))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer usage.Close()
+			seen := make(map[string]bool)
+			for day := 0; day < 2; day++ {
+				for _, id := range generateConcurrently(t, usage, 8, tc.perDay/8) {
+					if seen[id] {
+						t.Fatalf("%s issued twice", id)
+					}
+					seen[id] = true
+				}
+				clock.Add(24 * time.Hour)
+			}
+			if got := caller.callCount(); got != tc.wantCalls {
+				t.Fatalf("%d range requests, want %d", got, tc.wantCalls)
+			}
+			if stats := usage.Stats(); stats.Fallbacks != 0 {
+				t.Fatalf("%d fallback ids", stats.Fallbacks)
+			}
+		})
+	}
+}
+
+// BenchmarkClientSideMode 比较普通模式与客户端模式下每个 id 对应的号段服务调用次数
+func BenchmarkClientSideMode(b *testing.B) {
+	cases := []struct {
+		name string
+		opt  Option
+	}{
+		{"threshold refresh", WithStep(1000)},
+		{"client side", WithClientSideMode(1 << 30)},
+	}
+	for _, tc := range cases {
+		b.Run(tc.name, func(b *testing.B) {
+			caller := newMemCaller()
+			usage, err := NewWithOptions(caller.apply, testOptions(tc.opt)...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer usage.Close()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := usage.GenerateId("app"); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+			b.ReportMetric(float64(caller.callCount()), "calls")
+		})
+	}
+}
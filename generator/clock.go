@@ -0,0 +1,51 @@
+package generator
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Clock 时间来源，默认使用系统时间，测试时可注入假时钟
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// locationClock 把时间转换到 WithLocation 设置的时区，id 日期、跨天和周期切换都按该时区计算
+type locationClock struct {
+	clock Clock
+	loc   *time.Location
+}
+
+func (c locationClock) Now() time.Time {
+	return c.clock.Now().In(c.loc)
+}
+
+// fallbackClock 降级路径使用的时间，保证不会回退
+type fallbackClock struct {
+	m    sync.Mutex
+	last time.Time
+}
+
+// fallbackTime 返回降级 id 使用的时间：时钟回拨（NTP 校正、虚拟机暂停恢复等）时不使用回退后的时间，
+// 而是在上一次降级时间的基础上递增，保证基于时间的降级方案不会生成重复 id，并记录回拨次数
+func (usage *RangeUsageInfoStruct) fallbackTime(now time.Time) time.Time {
+	c := &usage.fallbackClock
+	c.m.Lock()
+	defer c.m.Unlock()
+	if !c.last.IsZero() && !now.After(c.last) {
+		if now.Before(c.last) {
+			atomic.AddInt64(&usage.counters.clockBackward, 1)
+			usage.logs.Warn("{} {} {} 检测到时钟回拨 {} -> {}", usage.appName, usage.bizType, usage.prefix, c.last, now)
+		}
+		now = c.last.Add(time.Nanosecond)
+	}
+	c.last = now
+	return now
+}
@@ -0,0 +1,49 @@
+package generator
+
+import (
+	"testing"
+	"time"
+)
+
+// TestFallbackClockBackward 降级期间时钟回拨（跨过零点）时降级 id 不重复，日期也不回退到前一天
+func TestFallbackClockBackward(t *testing.T) {
+	caller := newMemCaller()
+	caller.setFail(true)
+	clock := newFakeClock(time.Date(2024, 1, 2, 0, 0, 1, 0, time.Local))
+	usage, err := NewWithOptions(caller.apply, testOptions(WithClock(clock), WithFallbackGenerator(SnowflakeFallback(1)))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	seen := make(map[string]bool)
+	generate := func(n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			id, err := usage.GenerateId("app")
+			if err != nil {
+				t.Fatal(err)
+			}
+			parts, err := usage.Parse(id)
+			if err != nil || !parts.Fallback {
+				t.Fatalf("id %s is not a fallback id (%v)", id, err)
+			}
+			if parts.Day != "20240102" {
+				t.Fatalf("fallback id %s day %s after the clock stepped back, want 20240102", id, parts.Day)
+			}
+			if seen[id] {
+				t.Fatalf("duplicate fallback id %s", id)
+			}
+			seen[id] = true
+			clock.Add(time.Millisecond)
+		}
+	}
+	generate(100)
+	if stats := usage.Stats(); stats.ClockBackwardEvents != 0 {
+		t.Fatalf("ClockBackwardEvents %d before the clock stepped back", stats.ClockBackwardEvents)
+	}
+	clock.Add(-2 * time.Second)
+	generate(100)
+	if stats := usage.Stats(); stats.ClockBackwardEvents == 0 {
+		t.Fatal("clock step back was not counted")
+	}
+}
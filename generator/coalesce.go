@@ -0,0 +1,119 @@
+package generator
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// rangeFlight 一次合并后的号段申请，窗口期内到达的申请方都等待同一个结果
+type rangeFlight struct {
+	day     string
+	waiters int32
+	done    chan struct{}
+	resp    *NewRangeResp
+	err     error
+}
+
+// coalescedNewIdRange 合并并发的号段申请：首个申请方等待一个很短的窗口，统计窗口内同时触发申请的数量，
+// 按 步长 * 申请方数量（不超过上限倍数）申请一个大号段，其它申请方等待并共用这个号段
+// 争用策略为 QueueAndWait 时窗口为 0、倍数为 1，即只让其它申请方等待进行中的申请
+// 合并后的申请使用不随首个申请方取消的 ctx，每个申请方（包括首个）只按自己的 ctx 放弃等待，不影响其它申请方
+func (usage *RangeUsageInfoStruct) coalescedNewIdRange(req *ApplyReq, window time.Duration, maxMultiple int) (*NewRangeResp, bool, error) {
+	ctx := req.Context()
+	usage.flightM.Lock()
+	if flight := usage.flight; flight != nil && flight.day == req.Day {
+		atomic.AddInt32(&flight.waiters, 1)
+		usage.flightM.Unlock()
+		atomic.AddInt64(&usage.counters.coalesced, 1)
+		select {
+		case <-flight.done:
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+		usage.logs.Debug("{} {} {} 共用合并申请的号段", usage.appName, usage.bizType, usage.prefix)
+		return flight.resp, false, flight.err
+	}
+	flight := &rangeFlight{day: req.Day, done: make(chan struct{})}
+	usage.flight = flight
+	usage.flightM.Unlock()
+
+	if window > 0 {
+		timer := time.NewTimer(window)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			//首个申请方放弃等待，已经在等待的申请方不受影响，提前发起申请
+			timer.Stop()
+		}
+	}
+	if ctx.Err() != nil {
+		usage.flightM.Lock()
+		if atomic.LoadInt32(&flight.waiters) == 0 {
+			//没有其它申请方在等待，不再申请号段
+			usage.clearFlight(flight)
+			usage.flightM.Unlock()
+			flight.err = ctx.Err()
+			close(flight.done)
+			return nil, false, ctx.Err()
+		}
+		usage.flightM.Unlock()
+	}
+	multiple := int(atomic.LoadInt32(&flight.waiters)) + 1
+	if multiple > maxMultiple {
+		multiple = maxMultiple
+	}
+	req.Step = req.Step * multiple
+	usage.logs.Debug("{} {} {} 合并号段申请 {} 倍步长 {}", usage.appName, usage.bizType, usage.prefix, multiple, req.Step)
+
+	batch := *req
+	batch.ctx = detachedContext{parent: ctx}
+	go usage.runFlight(flight, &batch)
+	select {
+	case <-flight.done:
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+	return flight.resp, false, flight.err
+}
+
+// runFlight 发起合并后的号段申请，完成后通知所有等待的申请方
+func (usage *RangeUsageInfoStruct) runFlight(flight *rangeFlight, req *ApplyReq) {
+	flight.resp, flight.err = usage.callNumbers(req)
+	usage.flightM.Lock()
+	usage.clearFlight(flight)
+	usage.flightM.Unlock()
+	close(flight.done)
+	if flight.err != nil {
+		usage.logs.Debug("号段申请失败 {}", flight.err.Error())
+	}
+}
+
+// clearFlight 清除进行中的申请，调用方需持有 flightM
+// 换日时新日期的申请会替换 usage.flight，旧日期的申请完成后不能清除新日期的申请
+func (usage *RangeUsageInfoStruct) clearFlight(flight *rangeFlight) {
+	if usage.flight == flight {
+		usage.flight = nil
+	}
+}
+
+// detachedContext 保留 parent 中的值（如链路追踪信息），但不随 parent 取消或超时
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key any) any {
+	return c.parent.Value(key)
+}
@@ -0,0 +1,224 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// gatedCaller 在 gate 关闭之前阻塞的号段申请函数，记录申请时 ctx 是否已经结束
+type gatedCaller struct {
+	gate      chan struct{}
+	calls     int32
+	ctxErrors int32
+	next      int64
+}
+
+func (c *gatedCaller) apply(req *ApplyReq) (*NewRangeResp, error) {
+	atomic.AddInt32(&c.calls, 1)
+	<-c.gate
+	if req.Context().Err() != nil {
+		atomic.AddInt32(&c.ctxErrors, 1)
+		return nil, req.Context().Err()
+	}
+	end := atomic.AddInt64(&c.next, int64(req.Step))
+	return &NewRangeResp{RangeStart: end - int64(req.Step) + 1, RangeEnd: end}, nil
+}
+
+func newCoalescingUsage(t *testing.T, caller NumbersReqFunc) *RangeUsageInfoStruct {
+	usage, err := NewWithOptions(caller, testOptions(WithStep(10), WithRequestCoalescing(50*time.Millisecond, 8))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return usage
+}
+
+type coalesceResult struct {
+	resp *NewRangeResp
+	err  error
+	took time.Duration
+}
+
+func coalesce(usage *RangeUsageInfoStruct, ctx context.Context) <-chan coalesceResult {
+	return coalesceDay(usage, ctx, "20240101")
+}
+
+func coalesceDay(usage *RangeUsageInfoStruct, ctx context.Context, day string) <-chan coalesceResult {
+	ch := make(chan coalesceResult, 1)
+	go func() {
+		start := time.Now()
+		resp, _, err := usage.coalescedNewIdRange(&ApplyReq{AppName: "app", BizType: "T", Day: day, Step: 10, ctx: ctx}, usage.cfg.coalesceWindow, usage.cfg.coalesceMaxMultiple)
+		ch <- coalesceResult{resp: resp, err: err, took: time.Since(start)}
+	}()
+	return ch
+}
+
+func TestCoalesceLeaderCancelled(t *testing.T) {
+	caller := &gatedCaller{gate: make(chan struct{})}
+	usage := newCoalescingUsage(t, caller.apply)
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leader := coalesce(usage, leaderCtx)
+	time.Sleep(10 * time.Millisecond)
+	waiter := coalesce(usage, context.Background())
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	res := <-leader
+	if !errors.Is(res.err, context.Canceled) {
+		t.Fatalf("leader: got %v, want context.Canceled", res.err)
+	}
+	if res.took >= 50*time.Millisecond {
+		t.Fatalf("leader waited %s after cancel, window not interrupted", res.took)
+	}
+	close(caller.gate)
+	res = <-waiter
+	if res.err != nil || res.resp == nil {
+		t.Fatalf("waiter: got %v, %v, want the coalesced range", res.resp, res.err)
+	}
+	if got := res.resp.RangeEnd - res.resp.RangeStart + 1; got != 20 {
+		t.Fatalf("coalesced range width %d, want 20", got)
+	}
+	if atomic.LoadInt32(&caller.ctxErrors) != 0 {
+		t.Fatal("coalesced request was cancelled with the leader")
+	}
+}
+
+func TestCoalesceLeaderCancelledAlone(t *testing.T) {
+	caller := &gatedCaller{gate: make(chan struct{})}
+	close(caller.gate)
+	usage := newCoalescingUsage(t, caller.apply)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := coalesce(usage, ctx)
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if res := <-leader; !errors.Is(res.err, context.Canceled) {
+		t.Fatalf("leader: got %v, want context.Canceled", res.err)
+	}
+	if calls := atomic.LoadInt32(&caller.calls); calls != 0 {
+		t.Fatalf("caller invoked %d times with no one waiting", calls)
+	}
+	//之后的申请重新发起，不复用已放弃的合并申请
+	if res := <-coalesce(usage, context.Background()); res.err != nil {
+		t.Fatal(res.err)
+	}
+}
+
+func TestCoalesceWaiterDeadline(t *testing.T) {
+	caller := &gatedCaller{gate: make(chan struct{})}
+	usage := newCoalescingUsage(t, caller.apply)
+
+	leader := coalesce(usage, context.Background())
+	time.Sleep(10 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 80*time.Millisecond)
+	defer cancel()
+	res := <-coalesce(usage, ctx)
+	if !errors.Is(res.err, context.DeadlineExceeded) {
+		t.Fatalf("waiter: got %v, want context.DeadlineExceeded", res.err)
+	}
+	close(caller.gate)
+	if res := <-leader; res.err != nil || res.resp == nil {
+		t.Fatalf("leader: got %v, %v", res.resp, res.err)
+	}
+}
+
+func TestGenerateIdCtxCoalesced(t *testing.T) {
+	caller := &gatedCaller{gate: make(chan struct{})}
+	usage := newCoalescingUsage(t, caller.apply)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := usage.GenerateIdCtx(ctx, "app"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+	close(caller.gate)
+	if _, err := usage.GenerateIdCtx(context.Background(), "app"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestCoalesceBurst 同时到达的一批申请只发起一次按人数放大步长的申请
+func TestCoalesceBurst(t *testing.T) {
+	const burst = 6
+	caller := &gatedCaller{gate: make(chan struct{})}
+	close(caller.gate)
+	usage := newCoalescingUsage(t, caller.apply)
+	defer usage.Close()
+
+	start := make(chan struct{})
+	var ready, done sync.WaitGroup
+	ids := make([]string, burst)
+	errs := make([]error, burst)
+	for i := 0; i < burst; i++ {
+		ready.Add(1)
+		done.Add(1)
+		go func(i int) {
+			defer done.Done()
+			ready.Done()
+			<-start
+			ids[i], errs[i] = usage.GenerateId("app")
+		}(i)
+	}
+	ready.Wait()
+	close(start)
+	done.Wait()
+	seen := make(map[string]bool, burst)
+	for i, id := range ids {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if seen[id] {
+			t.Fatalf("duplicate id %s", id)
+		}
+		seen[id] = true
+	}
+	if calls := atomic.LoadInt32(&caller.calls); calls != 1 {
+		t.Fatalf("caller invoked %d times for one burst, want 1", calls)
+	}
+	if got := atomic.LoadInt64(&caller.next); got != 10*burst {
+		t.Fatalf("allocated %d numbers, want one range of %d", got, 10*burst)
+	}
+}
+
+// TestCoalesceStaleFlight 换日时旧日期的申请完成后不能清除新日期进行中的申请，之后到达的新日期申请方仍然共用它
+func TestCoalesceStaleFlight(t *testing.T) {
+	gates := map[string]chan struct{}{"20240101": make(chan struct{}), "20240102": make(chan struct{})}
+	var calls [2]int32
+	var next int64
+	caller := func(req *ApplyReq) (*NewRangeResp, error) {
+		if req.Day == "20240102" {
+			atomic.AddInt32(&calls[1], 1)
+		} else {
+			atomic.AddInt32(&calls[0], 1)
+		}
+		<-gates[req.Day]
+		end := atomic.AddInt64(&next, int64(req.Step))
+		return &NewRangeResp{RangeStart: end - int64(req.Step) + 1, RangeEnd: end}, nil
+	}
+	usage := newCoalescingUsage(t, caller)
+
+	old := coalesceDay(usage, context.Background(), "20240101")
+	time.Sleep(70 * time.Millisecond)
+	leader := coalesceDay(usage, context.Background(), "20240102")
+	time.Sleep(70 * time.Millisecond)
+	close(gates["20240101"])
+	if res := <-old; res.err != nil {
+		t.Fatal(res.err)
+	}
+	waiter := coalesceDay(usage, context.Background(), "20240102")
+	time.Sleep(10 * time.Millisecond)
+	close(gates["20240102"])
+	first, second := <-leader, <-waiter
+	if first.err != nil || second.err != nil {
+		t.Fatalf("leader %v, waiter %v", first.err, second.err)
+	}
+	if first.resp != second.resp {
+		t.Fatalf("waiter got range %d-%d, want the leader's %d-%d", second.resp.RangeStart, second.resp.RangeEnd, first.resp.RangeStart, first.resp.RangeEnd)
+	}
+	if c := atomic.LoadInt32(&calls[1]); c != 1 {
+		t.Fatalf("day 20240102 requested %d times, want 1", c)
+	}
+}
@@ -0,0 +1,74 @@
+package generator
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// generateConcurrently 用 workers 个协程各生成 perWorker 个 id，返回所有 id，出错时标记测试失败
+func generateConcurrently(t *testing.T, usage *RangeUsageInfoStruct, workers, perWorker int) []string {
+	t.Helper()
+	var (
+		m   sync.Mutex
+		ids []string
+		wg  sync.WaitGroup
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			local := make([]string, 0, perWorker)
+			for i := 0; i < perWorker; i++ {
+				id, err := usage.GenerateId("app")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				local = append(local, id)
+			}
+			m.Lock()
+			ids = append(ids, local...)
+			m.Unlock()
+		}()
+	}
+	wg.Wait()
+	return ids
+}
+
+// TestRangeBoundaryConcurrency 号段即将用完时大量协程同时取号，越过号段末尾的协程应当等待刷新而不是生成降级 id
+func TestRangeBoundaryConcurrency(t *testing.T) {
+	cases := []struct {
+		name    string
+		step    int
+		workers int
+		delay   time.Duration
+	}{
+		{"tiny step", 3, 32, 0},
+		{"small step", 10, 16, 0},
+		{"slow service", 10, 16, time.Millisecond},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			usage, err := NewWithOptions(slowCaller(tc.delay), testOptions(WithStep(tc.step))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer usage.Close()
+			ids := generateConcurrently(t, usage, tc.workers, 100)
+			seen := make(map[string]bool, len(ids))
+			for _, id := range ids {
+				if seen[id] {
+					t.Fatalf("%s issued twice", id)
+				}
+				seen[id] = true
+				if parts, err := usage.Parse(id); err != nil || parts.Fallback {
+					t.Fatalf("%s is a fallback id (%v)", id, err)
+				}
+			}
+			if stats := usage.Stats(); stats.Fallbacks != 0 || stats.Generated != int64(tc.workers*100) {
+				t.Fatalf("generated %d, fallbacks %d, want %d, 0", stats.Generated, stats.Fallbacks, tc.workers*100)
+			}
+		})
+	}
+}
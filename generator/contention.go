@@ -0,0 +1,48 @@
+package generator
+
+import "sync/atomic"
+
+// ContentionStrategy 号段即将用完时，已经有其它协程在申请号段（并发争用）时的处理策略
+type ContentionStrategy int
+
+const (
+	DegradeToSingle  ContentionStrategy = iota //只申请单次使用的 1 个号码，默认，对号段服务压力小但争用时请求次数多
+	QueueAndWait                               //等待进行中的申请返回并共用它的号段，不额外请求号段服务，但需要等待
+	ProportionalStep                           //按争用的申请方数量申请一小段号码，自用一个，其余留给后续请求，减少争用期间的请求次数
+)
+
+const (
+	constProportionalIdsPerWaiter = 10   //按比例申请时每个争用的申请方对应的号码数
+	constMaxProportionalStep      = 1000 //按比例申请时的最大步长
+	constMaxQueueRetries          = 10   //排队等待时，等到的号段已被用完后重新取号的最多次数
+)
+
+func (s ContentionStrategy) valid() bool {
+	return s >= DegradeToSingle && s <= ProportionalStep
+}
+
+// contendedStep 争用时单次申请的步长
+func (usage *RangeUsageInfoStruct) contendedStep(contenders int32) int {
+	if usage.cfg.contention != ProportionalStep {
+		return 1
+	}
+	step := int(contenders) * constProportionalIdsPerWaiter
+	if step > constMaxProportionalStep {
+		step = constMaxProportionalStep
+	}
+	return step
+}
+
+// useOnce 使用争用时申请到的单次号段：返回起始号码作为本次的 id，按比例申请时其余号码交给后续请求优先发放
+func (usage *RangeUsageInfoStruct) useOnce(resp *NewRangeResp, rangeDay string) int64 {
+	if usage.cfg.contention == ProportionalStep && resp.RangeEnd > resp.RangeStart {
+		usage.usageM.Lock()
+		for seq := resp.RangeStart + 1; seq <= resp.RangeEnd; seq++ {
+			usage.released = append(usage.released, releasedSeq{seq: seq, day: rangeDay})
+		}
+		atomic.StoreInt32(&usage.releasedCount, int32(len(usage.released)))
+		usage.usageM.Unlock()
+		usage.logs.Debug("{} {} {} 争用时按比例申请的号码留给后续请求 {} {}", usage.appName, usage.bizType, usage.prefix, resp.RangeStart+1, resp.RangeEnd)
+	}
+	return resp.RangeStart
+}
@@ -0,0 +1,134 @@
+package generator
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stepRecorder 记录每次号段申请的步长，申请前等待 delay 让同时到达的申请方发生争用
+type stepRecorder struct {
+	*memCaller
+	delay time.Duration
+	m     sync.Mutex
+	steps []int
+}
+
+func (c *stepRecorder) apply(req *ApplyReq) (*NewRangeResp, error) {
+	c.m.Lock()
+	c.steps = append(c.steps, req.Step)
+	c.m.Unlock()
+	time.Sleep(c.delay)
+	return c.memCaller.apply(req)
+}
+
+func (c *stepRecorder) recorded() []int {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return append([]int(nil), c.steps...)
+}
+
+// burst 同时发起 n 次 GenerateId，返回生成的 id
+func burst(t *testing.T, usage *RangeUsageInfoStruct, n int) []string {
+	t.Helper()
+	start := make(chan struct{})
+	var ready, done sync.WaitGroup
+	ids := make([]string, n)
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		ready.Add(1)
+		done.Add(1)
+		go func(i int) {
+			defer done.Done()
+			ready.Done()
+			<-start
+			ids[i], errs[i] = usage.GenerateId("app")
+		}(i)
+	}
+	ready.Wait()
+	close(start)
+	done.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ids
+}
+
+// TestContentionStrategy 首次取号时同时到达的一批请求按各策略申请号段，生成的 id 都是不重复的正常 id
+func TestContentionStrategy(t *testing.T) {
+	const n = 8
+	cases := []struct {
+		name     string
+		strategy ContentionStrategy
+		check    func(t *testing.T, usage *RangeUsageInfoStruct, steps []int)
+	}{
+		{"degrade to single", DegradeToSingle, func(t *testing.T, usage *RangeUsageInfoStruct, steps []int) {
+			singles := 0
+			for _, step := range steps {
+				if step == 1 {
+					singles++
+				} else if step != 100 {
+					t.Fatalf("requested step %d, want 100 or 1", step)
+				}
+			}
+			if singles == 0 {
+				t.Fatalf("steps %v, want single-use requests while contended", steps)
+			}
+		}},
+		{"queue and wait", QueueAndWait, func(t *testing.T, usage *RangeUsageInfoStruct, steps []int) {
+			if len(steps) != 1 || steps[0] != 100 {
+				t.Fatalf("steps %v, want one shared request of 100", steps)
+			}
+		}},
+		{"proportional step", ProportionalStep, func(t *testing.T, usage *RangeUsageInfoStruct, steps []int) {
+			proportional, spare := 0, 0
+			for _, step := range steps {
+				if step == 100 {
+					continue
+				}
+				if step == 1 || step%constProportionalIdsPerWaiter != 0 || step > n*constProportionalIdsPerWaiter {
+					t.Fatalf("requested step %d, want 100 or a multiple of %d", step, constProportionalIdsPerWaiter)
+				}
+				proportional++
+				spare += step - 1
+			}
+			if proportional == 0 {
+				t.Fatalf("steps %v, want proportional requests while contended", steps)
+			}
+			//每个按比例申请的号段自用一个，其余留给后续请求
+			if got := int(atomic.LoadInt32(&usage.releasedCount)); got != spare {
+				t.Fatalf("%d numbers kept for later requests, want %d", got, spare)
+			}
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			caller := &stepRecorder{memCaller: newMemCaller(), delay: 30 * time.Millisecond}
+			usage, err := NewWithOptions(caller.apply, testOptions(WithStep(100), WithContentionStrategy(tc.strategy))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer usage.Close()
+			ids := burst(t, usage, n)
+			tc.check(t, usage, caller.recorded())
+			calls := caller.callCount()
+			ids = append(ids, burst(t, usage, n)...)
+			if caller.callCount() != calls {
+				t.Fatalf("second burst requested %d more ranges, want none", caller.callCount()-calls)
+			}
+			seen := make(map[string]bool, len(ids))
+			for _, id := range ids {
+				if seen[id] {
+					t.Fatalf("duplicate id %s", id)
+				}
+				seen[id] = true
+				if parts, err := usage.Parse(id); err != nil || parts.Fallback {
+					t.Fatalf("id %s parsed to %+v (%v), want a range id", id, parts, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package generator
+
+import (
+	"testing"
+	"time"
+)
+
+// TestCurrentDate 未持有号段、持有今天的号段、号段用完以及跨天时的返回值
+func TestCurrentDate(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local))
+	usage, err := NewWithOptions(newMemCaller().apply, testOptions(WithClock(clock), WithStep(100))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	if day, ok := usage.CurrentDate(); ok || day != "" {
+		t.Fatalf("CurrentDate() = %q, %v before any range, want \"\", false", day, ok)
+	}
+
+	if _, err := usage.GenerateId("app"); err != nil {
+		t.Fatal(err)
+	}
+	if day, ok := usage.CurrentDate(); !ok || day != "20240101" {
+		t.Fatalf("CurrentDate() = %q, %v, want 20240101, true", day, ok)
+	}
+
+	clock.Add(24 * time.Hour)
+	if day, ok := usage.CurrentDate(); ok {
+		t.Fatalf("CurrentDate() = %q, true with the previous day's range", day)
+	}
+	if _, err := usage.GenerateId("app"); err != nil {
+		t.Fatal(err)
+	}
+	if day, ok := usage.CurrentDate(); !ok || day != "20240102" {
+		t.Fatalf("CurrentDate() = %q, %v after the day change, want 20240102, true", day, ok)
+	}
+
+	usage.usageM.Lock()
+	usage.currentMaxId = usage.currentRangeEnd
+	usage.usageM.Unlock()
+	if day, ok := usage.CurrentDate(); ok {
+		t.Fatalf("CurrentDate() = %q, true with an exhausted range", day)
+	}
+}
+
+// TestCurrentDateBackfill 按日期回填使用单独的号段，不改变当前号段的日期
+func TestCurrentDateBackfill(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 5, 10, 0, 0, 0, time.Local))
+	usage, err := NewWithOptions(newMemCaller().apply, testOptions(WithClock(clock), WithStep(100))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	id, err := usage.GenerateIdForDate("app", time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts := mustParse(t, usage, id); parts.Day != "20240102" {
+		t.Fatalf("backfilled id %s parsed to day %s, want 20240102", id, parts.Day)
+	}
+	if day, ok := usage.CurrentDate(); ok {
+		t.Fatalf("CurrentDate() = %q, true after only backfilling", day)
+	}
+
+	if _, err := usage.GenerateId("app"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := usage.GenerateIdForDate("app", time.Date(2024, 1, 3, 0, 0, 0, 0, time.Local)); err != nil {
+		t.Fatal(err)
+	}
+	if day, ok := usage.CurrentDate(); !ok || day != "20240105" {
+		t.Fatalf("CurrentDate() = %q, %v after backfilling, want 20240105, true", day, ok)
+	}
+}
+
+// TestCurrentDateServerDay 信任服务端日期时返回号段所属的服务端日期
+func TestCurrentDateServerDay(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 1, 23, 59, 0, 0, time.Local))
+	usage, err := NewWithOptions(skewedCaller("20240102"), testOptions(WithClock(clock), WithStep(10), WithTrustServerDay(true))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	if _, err := usage.GenerateId("app"); err != nil {
+		t.Fatal(err)
+	}
+	if day, ok := usage.CurrentDate(); !ok || day != "20240102" {
+		t.Fatalf("CurrentDate() = %q, %v, want the server day 20240102, true", day, ok)
+	}
+}
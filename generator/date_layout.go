@@ -0,0 +1,109 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateLayoutJulian 儒略日格式的日期：2 位年份 + 3 位年内天数，如 2024-02-01 为 24032
+const DateLayoutJulian = "julian"
+
+// dateLayoutSamples 校验日期格式时使用的样例时间，覆盖闰年、年末和不同位数的月、日、时
+var dateLayoutSamples = []time.Time{
+	time.Date(2024, 2, 29, 13, 0, 0, 0, time.UTC),
+	time.Date(2026, 12, 31, 23, 0, 0, 0, time.UTC),
+	time.Date(2025, 1, 5, 8, 0, 0, 0, time.UTC),
+}
+
+// dateCodec id 中的日期与周期标识的相互转换，layout 为空时 id 中直接使用周期标识
+type dateCodec struct {
+	layout string
+	period Period
+}
+
+func (c *config) dates() dateCodec {
+	return dateCodec{layout: c.dateLayout, period: c.period}
+}
+
+// width id 中日期部分的长度
+func (d dateCodec) width() int {
+	if d.layout == "" {
+		return d.period.keyLen()
+	}
+	return len(d.format(d.period.key(dateLayoutSamples[0])))
+}
+
+// format 把周期标识转换为 id 中的日期
+func (d dateCodec) format(key string) string {
+	if d.layout == "" {
+		return key
+	}
+	t, err := d.period.start(key, time.UTC)
+	if err != nil {
+		return key
+	}
+	if d.layout == DateLayoutJulian {
+		return fmt.Sprintf("%s%03d", t.Format("06"), t.YearDay())
+	}
+	return t.Format(d.layout)
+}
+
+// parse 把 id 中的日期还原为周期标识，要求重新格式化后与 s 完全相同
+func (d dateCodec) parse(s string) (string, bool) {
+	if d.layout == "" {
+		return s, d.period.isKey(s)
+	}
+	var t time.Time
+	if d.layout == DateLayoutJulian {
+		if len(s) != 5 {
+			return "", false
+		}
+		year, err := time.Parse("06", s[:2])
+		if err != nil {
+			return "", false
+		}
+		day, err := strconv.Atoi(s[2:])
+		if err != nil || day < 1 || day > 366 {
+			return "", false
+		}
+		t = year.AddDate(0, 0, day-1)
+	} else {
+		var err error
+		if t, err = time.ParseInLocation(d.layout, s, time.UTC); err != nil {
+			return "", false
+		}
+	}
+	key := d.period.key(t)
+	return key, d.format(key) == s
+}
+
+// validateDateLayout 日期格式只能输出数字和 -、_、.，长度固定，且能还原出周期标识，
+// 如按天隔离时不能使用只精确到月的格式，否则不同日期的 id 会重复
+func (c *config) validateDateLayout() error {
+	if c.dateLayout == "" {
+		return nil
+	}
+	if c.dateless {
+		return fmt.Errorf("date layout %q conflicts with dateless mode", c.dateLayout)
+	}
+	d := c.dates()
+	width := d.width()
+	for _, sample := range dateLayoutSamples {
+		key := c.period.key(sample)
+		s := d.format(key)
+		if len(s) != width {
+			return fmt.Errorf("date layout %q is not fixed width", c.dateLayout)
+		}
+		for i := 0; i < len(s); i++ {
+			if !(s[i] >= '0' && s[i] <= '9') && !strings.ContainsRune(constDateSeparators, rune(s[i])) {
+				return fmt.Errorf("date layout %q outputs %q, only digits and %q are allowed", c.dateLayout, s[i], constDateSeparators)
+			}
+		}
+		if back, ok := d.parse(s); !ok || back != key {
+			return fmt.Errorf("date layout %q cannot tell %s periods apart", c.dateLayout, c.period)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package generator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDateSeparatorRoundTrip(t *testing.T) {
+	cases := []struct {
+		name string
+		sep  string
+	}{
+		{"glued", ""},
+		{"dash", "-"},
+		{"underscore", "_"},
+		{"dot", "."},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			caller := newMemCaller()
+			clock := newFakeClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local))
+			usage, err := NewWithOptions(caller.apply, testOptions(WithClock(clock), WithStep(1), WithDateSeparator(tc.sep))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer usage.Close()
+			id, err := usage.GenerateId("app")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(id, "T-20240101"+tc.sep) {
+				t.Fatalf("id %s, want the date followed by %q", id, tc.sep)
+			}
+			parts, err := usage.Parse(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parts.Prefix != "T" || parts.Day != "20240101" || parts.Sequence != 1 {
+				t.Fatalf("Parse(%s) = %+v", id, parts)
+			}
+
+			//降级 id 使用同样的格式
+			caller.setFail(true)
+			fallback, err := usage.GenerateId("app")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(fallback, "T-20240101"+tc.sep+"Y") {
+				t.Fatalf("fallback id %s, want the same separator", fallback)
+			}
+			if parts, err := usage.Parse(fallback); err != nil || !parts.Fallback || parts.Day != "20240101" {
+				t.Fatalf("Parse(%s) = %+v, %v", fallback, parts, err)
+			}
+		})
+	}
+}
+
+// TestDateSeparatorParsesGluedIds 配置分隔符之后仍能解析之前生成的日期与序号直接相连的 id
+func TestDateSeparatorParsesGluedIds(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local))
+	glued, err := NewWithOptions(newMemCaller().apply, testOptions(WithClock(clock))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer glued.Close()
+	separated, err := NewWithOptions(newMemCaller().apply, testOptions(WithClock(clock), WithDateSeparator("-"))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer separated.Close()
+	id, err := glued.GenerateId("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts, err := separated.Parse(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts.Day != "20240101" || parts.Sequence != 1 {
+		t.Fatalf("Parse(%s) with a separator configured = %+v", id, parts)
+	}
+}
+
+func TestDateSeparatorInvalid(t *testing.T) {
+	for _, opts := range [][]Option{
+		{WithDateSeparator("/")},
+		{WithDateSeparator("--")},
+		{WithDateSeparator("-"), WithDateless(true)},
+	} {
+		if _, err := NewWithOptions(newMemCaller().apply, testOptions(opts...)...); !errors.Is(err, ErrInvalidOption) {
+			t.Fatalf("error %v, want ErrInvalidOption", err)
+		}
+	}
+}
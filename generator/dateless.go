@@ -0,0 +1,57 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+const (
+	constDatelessKeyLen = 13 //无日期 id 的序号位数
+	constDatelessMaxSeq = 10000000000000
+	//constDatelessNamespace 无日期模式下申请、归还号段使用的日期，所有日期共用这一个序号空间，
+	//序号跨天继续递增，id 中的序号与日期无关
+	constDatelessNamespace = "dateless"
+)
+
+var errDatelessOutOfRange = errors.New("dateless id out of range")
+
+// datelessKey 将序号补齐为定长的数字串，序号来自与日期无关的序号空间，不包含日期信息
+func datelessKey(seq int64) (string, error) {
+	if seq < 0 || seq >= constDatelessMaxSeq {
+		return "", fmt.Errorf("%w: sequence %d", errDatelessOutOfRange, seq)
+	}
+	return fmt.Sprintf("%0*d", constDatelessKeyLen, seq), nil
+}
+
+// splitDatelessKey 从定长数字串中还原序号
+func splitDatelessKey(digits string) (int64, error) {
+	if len(digits) != constDatelessKeyLen {
+		return 0, fmt.Errorf("%w: key length %d", errDatelessOutOfRange, len(digits))
+	}
+	return strconv.ParseInt(digits, 10, 64)
+}
+
+// datelessInvoke 无日期模式下申请号段时把日期替换为 constDatelessNamespace，
+// 服务端返回该命名空间作为号段日期时视为与申请日期一致
+func datelessInvoke(req *ApplyReq, invoke NumbersReqFunc) (*ApplyReq, NumbersReqFunc) {
+	wire := *req
+	wire.Day = constDatelessNamespace
+	return &wire, func(req *ApplyReq) (*NewRangeResp, error) {
+		resp, err := invoke(req)
+		if err == nil && resp != nil && resp.Day == constDatelessNamespace {
+			copied := *resp
+			copied.Day = ""
+			resp = &copied
+		}
+		return resp, err
+	}
+}
+
+// padDatelessFallback 将降级随机后缀补齐到无日期 id 的定长长度
+func padDatelessFallback(suffix string) string {
+	for len(suffix) < constDatelessKeyLen {
+		suffix += "A"
+	}
+	return suffix
+}
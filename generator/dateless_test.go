@@ -0,0 +1,87 @@
+package generator
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// dayRecorder 记录申请号段时使用的日期
+type dayRecorder struct {
+	*memCaller
+	m    sync.Mutex
+	days map[string]bool
+}
+
+func (c *dayRecorder) apply(req *ApplyReq) (*NewRangeResp, error) {
+	c.m.Lock()
+	c.days[req.Day] = true
+	c.m.Unlock()
+	return c.memCaller.apply(req)
+}
+
+func TestDatelessIds(t *testing.T) {
+	cases := []struct {
+		name string
+		opts []Option
+	}{
+		{"plain", nil},
+		{"check char", []Option{WithCheckChar(true)}},
+		{"scramble", []Option{WithScramble(true)}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			caller := &dayRecorder{memCaller: newMemCaller(), days: make(map[string]bool)}
+			clock := newFakeClock(time.Date(2024, 1, 1, 23, 0, 0, 0, time.Local))
+			usage, err := NewWithOptions(caller.apply, testOptions(append(tc.opts, WithDateless(true), WithClock(clock), WithStep(50))...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer usage.Close()
+
+			seen := make(map[string]bool)
+			length := 0
+			var last int64
+			for i := 0; i < 300; i++ {
+				if i == 150 {
+					clock.Add(2 * time.Hour)
+				}
+				id, err := usage.GenerateId("app")
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, day := range []string{"20240101", "20240102", "240101", "240102"} {
+					if strings.Contains(id, day) {
+						t.Fatalf("%s contains date %s", id, day)
+					}
+				}
+				if length == 0 {
+					length = len(id)
+				} else if len(id) != length {
+					t.Fatalf("%s has length %d, want %d", id, len(id), length)
+				}
+				if seen[id] {
+					t.Fatalf("%s issued twice", id)
+				}
+				seen[id] = true
+
+				parts, err := usage.Parse(id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if parts.Day != "" {
+					t.Fatalf("%s parsed day %q, want empty", id, parts.Day)
+				}
+				if parts.Sequence <= last {
+					t.Fatalf("%s sequence %d not above %d across days", id, parts.Sequence, last)
+				}
+				last = parts.Sequence
+			}
+			//跨天后序号继续递增，不按日期重新开始
+			if len(caller.days) != 1 || !caller.days[constDatelessNamespace] {
+				t.Fatalf("requested days %v, want only %s", caller.days, constDatelessNamespace)
+			}
+		})
+	}
+}
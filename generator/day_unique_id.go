@@ -1,10 +1,12 @@
 package generator
 
 import (
-	"errors"
+	"context"
 	"fmt"
+	"math"
 	"math/rand"
 	"net"
+	"os"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -12,37 +14,92 @@ import (
 )
 
 const (
-	constIdFormat       = "%s-%s%s"
 	constIncrementStep  = 10000
 	LeastAvailableIdNum = 50 //当剩余可用id数小于这个数时，申请新号段，建议小于步长较多
+
+	seqOverflow int64 = -1 //序号到达 int64 上限，不能继续递增
 )
 
 type ApplyReq struct {
 	AppName string `json:"appName"` //"申请应用名"
 	BizType string `json:"bizType"` //应用内使用号段的业务类型，业务方需要确保appName + bizType 不与其它申请者重复
-	Day     string `json:"day"`     //"日期格式: 20060102" 号段应用日期，获得的号段会确保该日期内独占（在appName+bizType范围内独点）
+	Day     string `json:"day"`     //"日期格式: 20060102" 号段应用日期，获得的号段会确保该日期内独占（在appName+bizType范围内独点）；WithPeriod 设置了其它周期时为对应的周期标识
 	Step    int    `json:"step"`    //"号段步长" 申请号段的步长, 建议申请步长为1000，或不超过100000
+
+	tags *requestTags    //GenerateIdWithTags 传入的标签，只用于日志和回调，不发送给号段服务；用指针保持 ApplyReq 可比较
+	ctx  context.Context //GenerateIdCtx 等传入的 ctx，号段申请函数通过 Context 获取
+}
+
+// Context 返回发起本次号段申请的调用方传入的 ctx，号段申请函数应在 ctx 取消或超时后尽快返回；
+// 后台预取、跨天提前申请等不属于某次调用的申请返回 context.Background()
+func (req *ApplyReq) Context() context.Context {
+	if req.ctx == nil {
+		return context.Background()
+	}
+	return req.ctx
+}
+
+// ApplyReqWithContext 返回带有 ctx 的 req 副本，号段申请函数通过 Context 获取 ctx；
+// 用于在生成器之外构造号段申请，如 gRPC 服务端把请求的 ctx 转发给 NumbersReqFunc
+func ApplyReqWithContext(ctx context.Context, req ApplyReq) *ApplyReq {
+	req.ctx = ctx
+	return &req
 }
 
 type NewRangeResp struct {
-	RangeStart int64 `json:"rangeStart"`
-	RangeEnd   int64 `json:"rangeEnd"`
+	RangeStart int64  `json:"rangeStart"`
+	RangeEnd   int64  `json:"rangeEnd"`
+	Day        string `json:"day,omitempty"` //可选，服务端实际分配号段所属的日期，为空时视为与申请日期一致
+	//可选，服务端实际分配的步长，服务端压力大时可能小于申请的步长，为 0 时按号段宽度推算
+	GrantedStep int64 `json:"grantedStep,omitempty"`
 }
 
 type RangeUsageInfoStruct struct {
-	//CurrentRangeStart int
+	currentRangeStart     int64
 	currentMaxId          int64
 	currentRangeEnd       int64
 	applyDate             time.Time
+	rangeDay              string     //当前号段所属日期（id 中的日期），通常与 applyDate 同一天，信任服务端日期时以服务端为准
 	usageM                sync.Mutex //控制共享变量更新
 	gettingIdRangeCounter int32      //控制并发请求号段
 	reqNumbersCaller      NumbersReqFunc
 	logs                  LogInterface
-	prefix                string
+	prefix                string //构造时的前缀，用于日志标识实例
+	idPrefix              string //id 中使用的前缀，可通过 SetPrefix 修改，受 prefixM 保护
+	prefixM               sync.RWMutex
 	bizType               string
 	appName               string
 	hostKey               string //用来区别服务不同实例，降级随机生成方案避免不同实例重复
+	fallbackTag           string //降级 id 中的实例标识，由 hostKey、进程号和启动时间计算，见 newFallbackTag
 	rander                *rand.Rand
+	randM                 sync.Mutex //rand.Rand 不是并发安全的
+	cfg                   config
+	rangeQueue            []idRange //预取的待用号段，受 usageM 保护
+	prefetching           int32     //控制同一时刻只有一个后台预取协程
+	prefetchAt            int64     //当前号段触发后台预取的号码，受 usageM 保护
+	flightM               sync.Mutex
+	flight                *rangeFlight //进行中的合并号段申请，受 flightM 保护
+	health                healthState
+	counters              statsCounters
+	fallbackWindow        minuteWindow
+	fallbackClock         fallbackClock
+	released              []releasedSeq //ReserveID 回滚归还的号码，受 usageM 保护
+	releasedCount         int32         //released 的长度，用于不加锁判断是否有归还的号码
+	closed                int32
+	stopCh                chan struct{} //通知后台协程退出，Close 时关闭
+	overflowFrom          string        //溢出到后一天时的本地日期，受 usageM 保护
+	overflowDay           string        //溢出后申请号段使用的日期，受 usageM 保护
+	lastRange             lastRange
+	dayLife               dayLifecycle           //受 usageM 保护
+	pendingDayEvents      int32                  //dayLife.pending 的长度，用于不加锁判断是否有待回调的事件
+	live                  atomic.Pointer[config] //可热更新的配置，见 Reconfigure
+	reconfigM             sync.Mutex             //串行化并发的 Reconfigure
+	selfCheck             selfCheckState
+	inflightSem           chan struct{}   //WithMaxInflight 的并发名额，未配置时为 nil
+	inflight              int64           //进行中的生成调用数
+	dupGuard              *duplicateGuard //WithDuplicateGuard 的布隆过滤器，未配置时为 nil
+	prewarmed             *idRange        //WithNextDayPrewarm 提前申请的下一周期号段，受 usageM 保护
+	dated                 datedRanges     //GenerateIdForDate 按日期缓存的号段
 }
 
 type LogInterface interface {
@@ -52,6 +109,8 @@ type LogInterface interface {
 	Error(format string, v ...any)
 }
 
+// NumbersReqFunc 旧的号段申请函数形式，为兼容已有调用方保留，只能通过 req.Context 获取 ctx，不能归还号段或关闭连接；
+// 新代码请实现 NumbersClient 并使用 NewWithClient
 type NumbersReqFunc func(req *ApplyReq) (*NewRangeResp, error)
 
 var keyMap = map[byte]byte{
@@ -67,122 +126,465 @@ var keyMap = map[byte]byte{
 	'9': 'U',
 }
 
-func New(caller NumbersReqFunc, logs LogInterface, prefix string) *RangeUsageInfoStruct {
-	source := rand.NewSource(time.Now().UnixNano())
+// New 按 opts 创建生成器，应用名通过 WithAppName、前缀通过 WithPrefix、日志通过 WithLogger 设置（不设置时丢弃日志）；
+// 没有设置应用名时不会以第一次生成调用传入的应用名为准，每次生成都返回 ErrNotInitialized；
+// 不校验选项，不合法的配置修正为默认值并记录日志，保证不会失败，需要在配置有问题时返回错误的使用 NewWithOptions；
+// caller 为兼容保留的函数形式，Close 时不会归还未用完的号码，新代码请使用 NewWithClient
+func New(caller NumbersReqFunc, opts ...Option) *RangeUsageInfoStruct {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	nodeErr := cfg.resolveNodeID()
+	cfg.normalize()
+	if nodeErr != nil {
+		cfg.logs.Warn("{} 确定节点序号失败，使用配置的节点序号 {}", cfg.prefix, nodeErr.Error())
+	}
+	usage := newRangeUsage(caller, cfg)
+	if err := usage.restoreState(); err != nil {
+		usage.logs.Warn("{} {} 恢复号段状态失败 {}", usage.bizType, usage.prefix, err.Error())
+	}
+	usage.startDayWatch()
+	usage.startPrewarm()
+	return usage
+}
+
+// NewWithOptions 与 New 相同，但在构造时校验调用函数、前缀和各个选项，配置有问题时返回错误而不是静默接受
+// 应用名必须通过 WithAppName 设置，使用 WithRawCaller 时 caller 可以为 nil；caller 同样是兼容保留的函数形式，见 NewWithClient
+func NewWithOptions(caller NumbersReqFunc, opts ...Option) (*RangeUsageInfoStruct, error) {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if caller == nil && cfg.rawCaller == nil && cfg.staticNodes == 0 {
+		return nil, fmt.Errorf("%w: caller is nil", ErrInvalidOption)
+	}
+	if cfg.prefix == "" && cfg.bizType == "" {
+		//前缀为空时 bizType 无法默认取前缀
+		return nil, fmt.Errorf("%w: biz type is required when prefix is empty", ErrInvalidOption)
+	}
+	if cfg.appName == "" {
+		return nil, fmt.Errorf("%w: app name is required, see WithAppName", ErrInvalidOption)
+	}
+	if err := cfg.resolveNodeID(); err != nil {
+		return nil, err
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	cfg.normalize()
+	usage := newRangeUsage(caller, cfg)
+	if err := usage.restoreState(); err != nil {
+		return nil, err
+	}
+	usage.startDayWatch()
+	usage.startPrewarm()
+	return usage, nil
+}
+
+func newRangeUsage(caller NumbersReqFunc, cfg config) *RangeUsageInfoStruct {
+	source := rand.NewSource(time.Now().UnixNano() ^ int64(os.Getpid())<<32) //混入进程号，同一台机器上同时启动的实例随机序列也不同
 	rander := rand.New(source)
+	if cfg.rander != nil {
+		rander = cfg.rander
+	}
 	hostKey := GetHostKey()
-	return &RangeUsageInfoStruct{
+	if cfg.hostKey != "" {
+		hostKey = cfg.hostKey
+	}
+	if cfg.staticNodes > 0 {
+		caller = (&staticNodeCaller{clock: cfg.clock, period: cfg.period}).apply
+	}
+	usage := &RangeUsageInfoStruct{
 		reqNumbersCaller: caller,
-		logs:             logs,
-		prefix:           prefix,
-		bizType:          prefix,
+		logs:             cfg.logs,
+		prefix:           cfg.prefix,
+		idPrefix:         cfg.prefix,
+		bizType:          cfg.bizType,
+		appName:          cfg.appName,
 		rander:           rander,
 		hostKey:          hostKey,
+		fallbackTag:      newFallbackTag(hostKey, os.Getpid(), time.Now()),
+		cfg:              cfg,
 	}
+	live := cfg
+	usage.live.Store(&live)
+	if cfg.maxInflight > 0 {
+		usage.inflightSem = make(chan struct{}, cfg.maxInflight)
+	}
+	if cfg.duplicateGuard > 0 {
+		usage.dupGuard = newDuplicateGuard(cfg.duplicateGuard)
+	}
+	if cfg.expvarName != "" {
+		usage.publishExpvar(cfg.expvarName)
+	}
+	return usage
 }
 
 func (usage *RangeUsageInfoStruct) GenerateIdWithAppendPrefix(applicationName string, appendPrefix string) (string, error) {
+	return usage.generateIdOfType(context.Background(), applicationName, appendPrefix, usage.cfg.typeFlag, nil)
+}
+
+// GenerateIdWithAppendPrefixCtx 与 GenerateIdWithAppendPrefix 相同，ctx 取消或超时后不再等待号段申请，直接返回 ctx 的错误
+func (usage *RangeUsageInfoStruct) GenerateIdWithAppendPrefixCtx(ctx context.Context, applicationName string, appendPrefix string) (string, error) {
+	return usage.generateIdOfType(ctx, applicationName, appendPrefix, usage.cfg.typeFlag, nil)
+}
+
+func (usage *RangeUsageInfoStruct) generateIdOfType(ctx context.Context, applicationName string, appendPrefix string, flag byte, tags map[string]string) (string, error) {
+	release, err := usage.acquireInflight(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	var before time.Time
+	if usage.cfg.selfCheck {
+		//自检模式下串行生成，保证按发放顺序检查
+		usage.selfCheck.m.Lock()
+		defer usage.selfCheck.m.Unlock()
+		before = usage.cfg.clock.Now()
+	}
+	id, err := usage.nextValidId(ctx, applicationName, appendPrefix, flag, tags, before)
+	if err != nil {
+		return "", err
+	}
+	if err := usage.acceptId(id); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// acceptId 返回 id 之前的最后检查：长度限制、重复检测，通过后计入生成数
+func (usage *RangeUsageInfoStruct) acceptId(id string) error {
+	if usage.cfg.maxIdLen > 0 && len(id) > usage.cfg.maxIdLen {
+		usage.logs.Error("{} {} {} id 超过最大长度 {} {}", usage.appName, usage.bizType, usage.prefix, id, usage.cfg.maxIdLen)
+		return fmt.Errorf("%w: %s longer than %d", ErrIdTooLong, id, usage.cfg.maxIdLen)
+	}
+	usage.checkDuplicate(id)
+	atomic.AddInt64(&usage.counters.generated, 1)
+	return nil
+}
+
+func (usage *RangeUsageInfoStruct) generateId(ctx context.Context, applicationName string, appendPrefix string, flag byte, tags map[string]string) (string, error) {
+	taken, err := usage.nextSeq(ctx, applicationName, tags, 0, time.Time{})
+	if err != nil {
+		return "", err
+	}
+
+	var finalPrefix = usage.currentPrefix()
+	if appendPrefix != "" {
+		finalPrefix = joinPrefix(finalPrefix, appendPrefix)
+	}
+	if usage.cfg.bizCode != "" {
+		finalPrefix = joinPrefix(finalPrefix, usage.cfg.bizCode)
+	}
 
-	if usage.appName == "" {
-		//首次调用设置，后面不再变更，避免同一个实例被应用在不同业务场景中
-		usage.appName = applicationName
+	if taken.reissued && usage.cfg.selfCheck {
+		usage.selfCheck.reissued = true
+	}
+	if taken.seq != 0 {
+		return usage.generateKey(taken.seq, finalPrefix, taken.day, flag)
+	}
+
+	//号段获取失败
+	//当前号段资源已用完且还未请求到新号段（高并发下低概率），降级到随机生成方案
+	logs := usage.callLogs(tags)
+	if _, ok := usage.cfg.fallbackGen.(errorFallback); ok {
+		logs.Error("{} {} {} 获取号段失败，配置了不降级，返回错误", usage.appName, usage.bizType, usage.prefix)
+		return "", taken.unavailable()
+	}
+	currentTime := usage.cfg.clock.Now()
+	if !usage.allowFallback(currentTime) {
+		logs.Error("{} {} {} 最近一分钟降级 id 数已达上限 {}，不再降级", usage.appName, usage.bizType, usage.prefix, usage.tunables().maxFallbackPerMinute)
+		return "", ErrFallbackRateExceeded
+	}
+	usage.recordFallback(currentTime)
+	logs.Warn("{} {} {} 获取号段失败或等待请求号段中，先降级到随机生成业务编号方案", usage.appName, usage.bizType, usage.prefix)
+	fallbackTime := usage.fallbackTime(currentTime)
+	randSuffix := usage.fallbackSuffix()
+	if usage.cfg.dateless {
+		randSuffix = padDatelessFallback(randSuffix)
+	}
+	if flag != 0 {
+		randSuffix = string(flag) + randSuffix
+	}
+	if usage.cfg.shardFunc != nil {
+		ch, err := usage.fallbackShardChar()
+		if err != nil {
+			return "", err
+		}
+		randSuffix += string(ch)
+	}
+	randOrderId := usage.withCheckChar(usage.formatId(finalPrefix, usage.periodKey(fallbackTime), randSuffix))
+	usage.emitFallback(randOrderId, tags)
+	return randOrderId, nil
+
+}
+
+// takenSeq nextSeq 取到的号码，seq 为 0 时没有可用号码，由调用方决定降级还是返回错误
+type takenSeq struct {
+	seq      int64
+	day      string //id 中的日期，信任服务端日期时可能与本地日期不同
+	reissued bool   //重新发放的是事务回滚归还的号码
+	rangeErr error  //seq 为 0 时申请号段失败的原因
+}
+
+// unavailable 没有可用号码且不降级时返回的错误
+func (t takenSeq) unavailable() error {
+	if t.rangeErr != nil {
+		return wrapError(ErrSegmentUnavailable, t.rangeErr)
+	}
+	return ErrSegmentUnavailable
+}
+
+// nextSeq 取下一个号码及其所属日期，GenerateId 与 GenerateInt64 共用：优先发放归还的号码，其次从当前号段取号，
+// 需要时申请新号段，处理溢出到后一天、排队重试、熔断恢复和降级宽限期，不做降级
+func (usage *RangeUsageInfoStruct) nextSeq(ctx context.Context, applicationName string, tags map[string]string, attempt int, graceUntil time.Time) (takenSeq, error) {
+
+	if atomic.LoadInt32(&usage.closed) != 0 {
+		return takenSeq{}, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return takenSeq{}, err
+	}
+
+	if _, err := usage.bindAppName(applicationName); err != nil {
+		return takenSeq{}, err
 	}
 
-	currentTime := time.Now()
+	logs := usage.callLogs(tags)
+	currentTime := usage.cfg.clock.Now()
+	recoveries := atomic.LoadInt64(&usage.health.recoveries)
 	var currentId int64
 	//根据当前号段资源，构建订单号
-	todayFormat := currentTime.Format("20060102")
+	todayFormat := usage.periodKey(currentTime)
 	req := ApplyReq{
 		AppName: usage.appName,
 		BizType: usage.bizType,
-		Day:     todayFormat,
-		Step:    constIncrementStep,
+		Day:     usage.requestDay(todayFormat),
+		Step:    usage.step(),
+		tags:    newRequestTags(tags),
+		ctx:     ctx,
 	}
 
-	var finalPrefix = usage.prefix
-	if appendPrefix != "" {
-		finalPrefix = usage.prefix + "-" + appendPrefix
+	logs.Debug("{} {} {} 请求新的id, 当前号段: {} {}", usage.appName, usage.bizType, usage.prefix, atomic.LoadInt64(&usage.currentMaxId), atomic.LoadInt64(&usage.currentRangeEnd))
+
+	if seq, day, ok := usage.takeReleasedSeq(todayFormat); ok {
+		//优先重新发放事务回滚归还的号码
+		return takenSeq{seq: seq, day: day, reissued: true}, nil
 	}
 
-	usage.logs.Debug("{} {} {} 请求新的id, 当前号段: {} {} {}", usage.appName, usage.bizType, usage.prefix, usage.applyDate, usage.currentMaxId, usage.currentRangeEnd)
+	//在锁内判断并取号，保证不会越过当前号段的结束号码
+	taken := usage.takePrewarmed(currentTime, usage.takeId(currentTime))
+	usage.flushDayEvents()
+	if taken.err != nil {
+		return takenSeq{}, taken.err
+	}
+	currentId, idDay := taken.id, taken.day //id 中的日期，信任服务端日期时可能与本地日期不同
+	if taken.prefetch {
+		usage.triggerPrefetch(req)
+	}
 
-	if currentTime.Day() != usage.applyDate.Day() { //新的一天或服务重启了，获取新的号段
-		usage.logs.Debug("{} {} {} 新的一天，取新号段", usage.appName, usage.bizType, usage.prefix)
-		resp, bUseOnce, err := usage.getNewIdRange(&req)
-		if err != nil {
-			usage.logs.Debug("{} {} {} 请求号段失败 {}", usage.appName, usage.bizType, usage.prefix, err.Error())
-			//return "", errcode.IdGenFailed.Error()
-		} else {
-			if bUseOnce {
-				currentId = resp.RangeStart
-				return usage.GenerateKey(currentId, finalPrefix, todayFormat)
-			} else {
-				currentId = usage.replaceRange(resp.RangeStart, resp.RangeEnd, currentTime)
-				usage.logs.Debug("{} {} {} 号段更替，新号段 {} {} {}", usage.appName, usage.bizType, usage.prefix, usage.currentMaxId, usage.currentRangeEnd, usage.applyDate)
-			}
+	var rangeErr error //申请号段失败的原因，不降级时随错误返回
+	if taken.refresh != refreshNone {
+		if taken.refresh == refreshNewDay { //新的一天或服务重启了，获取新的号段
+			logs.Debug("{} {} {} 新的一天，取新号段", usage.appName, usage.bizType, usage.prefix)
+		} else { //号段即将用完，获取新号段
+			logs.Debug("{} {} {} 当天号段用完了，重新申请", usage.appName, usage.bizType, usage.prefix)
 		}
-	} else if usage.currentMaxId+LeastAvailableIdNum > usage.currentRangeEnd {
-		//号段即将用完，获取新号段
-		usage.logs.Debug("{} {} {} 当天号段用完了，重新申请", usage.appName, usage.bizType, usage.prefix)
 		resp, bUseOnce, err := usage.getNewIdRange(&req)
+		var rangeDay string
+		if err == nil {
+			rangeDay, err = usage.checkRangeDay(&req, resp)
+		}
 		if err != nil {
-			usage.logs.Error("{} {} {} 请求号段出错 {}", usage.appName, usage.bizType, usage.prefix, err.Error())
+			logs.Error("{} {} {} 请求号段出错 {}", usage.appName, usage.bizType, usage.prefix, err.Error())
 			//return "", errcode.IdGenFailed.Error()
+			rangeErr = err
+			if ctx.Err() != nil {
+				//调用方取消或超时，不再降级
+				return takenSeq{}, ctx.Err()
+			}
 		} else {
 			if bUseOnce {
-				currentId = resp.RangeStart
-				return usage.GenerateKey(currentId, finalPrefix, todayFormat)
+				return takenSeq{seq: usage.useOnce(resp, rangeDay), day: rangeDay}, nil
 			} else {
-				currentId = usage.replaceRange(resp.RangeStart, resp.RangeEnd, currentTime)
-				usage.logs.Debug("{} {} {} 号段更替，新号段 {} {} {}", usage.appName, usage.bizType, usage.prefix, usage.currentMaxId, usage.currentRangeEnd, usage.applyDate)
+				currentId, idDay = usage.replaceRange(resp.RangeStart, resp.RangeEnd, currentTime, rangeDay)
+				logs.Debug("{} {} {} 号段更替，新号段 {} {}", usage.appName, usage.bizType, usage.prefix, currentId, resp.RangeEnd)
+				if currentId == 0 && usage.cfg.contention == QueueAndWait && attempt < constMaxQueueRetries {
+					//等到的号段已被其它申请方用完，重新取号或申请，而不是降级
+					return usage.nextSeq(ctx, applicationName, tags, attempt+1, graceUntil)
+				}
+				if currentId == 0 {
+					rangeErr = ErrRangeExhausted
+				}
 			}
 		}
 	} else {
-		//号段内递增
-		currentId = usage.incrementAndGet()
-		usage.logs.Debug("{} {} {} 使用已有号段获得的号码 {}", usage.appName, usage.bizType, usage.prefix, currentId)
+		logs.Debug("{} {} {} 使用已有号段获得的号码 {}", usage.appName, usage.bizType, usage.prefix, currentId)
+	}
+
+	if usage.cfg.overflowToNextDay && currentId != 0 && usage.exceedsDailyCap(currentId) {
+		currentId, idDay = usage.overflowNextDay(currentTime, idDay)
 	}
 
-	if (currentId == 0) || (currentId > usage.currentRangeEnd) {
-		//号段获取失败
-		//当前号段资源已用完且还未请求到新号段（高并发下低概率），降级到随机生成方案
-		usage.logs.Warn("{} {} {} 获取号段失败或等待请求号段中，先降级到随机生成业务编号方案", usage.appName, usage.bizType, usage.prefix)
-		randSuffix := usage.randId(usage.hostKey)
-		randOrderId := fmt.Sprintf(constIdFormat, finalPrefix, todayFormat, randSuffix)
-		return randOrderId, nil
+	if currentId == seqOverflow {
+		logs.Error("{} {} {} 序号达到 int64 上限 {} {}", usage.appName, usage.bizType, usage.prefix, usage.currentMaxId, usage.currentRangeEnd)
+		return takenSeq{}, ErrSequenceOverflow
 	}
 
-	return usage.GenerateKey(currentId, finalPrefix, todayFormat)
+	if currentId == 0 {
+		if attempt < constMaxQueueRetries && usage.awaitRecovery(recoveries) {
+			//决定降级期间号段服务已经恢复（如熔断半开探测成功），直接重新取号或申请号段
+			return usage.nextSeq(ctx, applicationName, tags, attempt+1, graceUntil)
+		}
+		if usage.cfg.fallbackGrace > 0 {
+			//设置了宽限期时先等待进行中的号段申请或重试，宽限期过后才降级
+			if graceUntil.IsZero() {
+				graceUntil = time.Now().Add(usage.cfg.fallbackGrace)
+			}
+			if usage.waitFallbackGrace(ctx, graceUntil) {
+				return usage.nextSeq(ctx, applicationName, tags, attempt, graceUntil)
+			}
+			if ctx.Err() != nil {
+				return takenSeq{}, ctx.Err()
+			}
+		}
+	}
 
+	return takenSeq{seq: currentId, day: idDay, rangeErr: rangeErr}, nil
+}
+
+// SetPrefix 运行时修改 id 前缀，校验规则与构造时相同，只影响之后生成的 id，已生成的 id 不会改变
+// 当前号段继续使用，申请号段的 bizType 也不会随之改变
+func (usage *RangeUsageInfoStruct) SetPrefix(prefix string) error {
+	if err := validatePrefix(prefix); err != nil {
+		return err
+	}
+	if err := usage.cfg.checkMaxIdLength(prefix); err != nil {
+		return err
+	}
+	usage.prefixM.Lock()
+	defer usage.prefixM.Unlock()
+	usage.logs.Info("{} {} {} 修改前缀 {} -> {}", usage.appName, usage.bizType, usage.prefix, usage.idPrefix, prefix)
+	usage.idPrefix = prefix
+	return nil
+}
+
+func (usage *RangeUsageInfoStruct) currentPrefix() string {
+	usage.prefixM.RLock()
+	defer usage.prefixM.RUnlock()
+	return usage.idPrefix
+}
+
+// formatId 拼接 id，配置了 WithFormat 时按模板拼接，否则配置了日期分隔符时在日期与序号之间加入分隔符，无日期模式下 day 不使用
+func (usage *RangeUsageInfoStruct) formatId(finalPrefix string, day string, suffix string) string {
+	if usage.cfg.template != nil {
+		return usage.cfg.template.render(finalPrefix, usage.cfg.dates().format(day), suffix)
+	}
+	if usage.cfg.dateless {
+		return joinPrefix(finalPrefix, suffix)
+	}
+	return joinPrefix(finalPrefix, day+usage.cfg.dateSeparator+suffix)
+}
+
+// joinPrefix 用 - 连接前缀与其后的部分，前缀为空时不输出开头的分隔符
+func joinPrefix(prefix string, rest string) string {
+	if prefix == "" {
+		return rest
+	}
+	return prefix + "-" + rest
 }
 
 func (usage *RangeUsageInfoStruct) GenerateKey(currentId int64, finalPrefix string, todayFormat string) (string, error) {
-	uniqueKey := fmt.Sprintf("%06d", currentId)
+	return usage.generateKey(currentId, finalPrefix, todayFormat, usage.cfg.typeFlag)
+}
+
+// generateKey flag 不为 0 时作为类型标识放在序号之前
+func (usage *RangeUsageInfoStruct) generateKey(currentId int64, finalPrefix string, todayFormat string, flag byte) (string, error) {
+	if currentId < 0 {
+		usage.logs.Error("{} {} {} 序号为负数 {}", usage.appName, usage.bizType, usage.prefix, currentId)
+		return "", ErrSequenceOverflow
+	}
+	seq := usage.nodeSeq(currentId)
+	if usage.cfg.scramble {
+		//打散节点映射后的序号，不同节点的序号互不相同，打散后仍互不相同
+		seq = scrambleSeq(seq)
+	}
+	suffix := make([]byte, 0)
+	if flag != 0 {
+		suffix = append(suffix, flag)
+	}
+	if usage.cfg.seqRadix != 0 {
+		encoded, err := encodeRadix(seq, usage.cfg.seqAlphabet, usage.cfg.seqWidth)
+		if err != nil {
+			usage.logs.Error("{} {} {} 序号超出每天可用的数量 {}", usage.appName, usage.bizType, usage.prefix, seq)
+			return "", err
+		}
+		return usage.appendShard(append(suffix, encoded...), currentId, finalPrefix, todayFormat)
+	}
+
+	uniqueKey := fmt.Sprintf("%0*d", usage.cfg.seqPadWidth(), seq)
+	if usage.cfg.dateless {
+		var err error
+		uniqueKey, err = datelessKey(seq)
+		if err != nil {
+			usage.logs.Error("{} {} {} 生成无日期id出错 {} {}", usage.appName, usage.bizType, usage.prefix, currentId, err.Error())
+			return "", err
+		}
+	}
 
 	uniqueKeyLen := len(uniqueKey)
 
-	suffix := make([]byte, 0)
 	for i := 0; i < uniqueKeyLen; i++ {
 		ch := uniqueKey[i]
-		newCh, ok := keyMap[ch]
+		newCh, ok := usage.cfg.keyMap[ch]
 		if !ok {
 			usage.logs.Error("{} {} {} 生成id映射出错 {} {} {}", usage.appName, usage.bizType, usage.prefix, uniqueKey, i, ch)
-			return "", errors.New("id map error")
+			return "", fmt.Errorf("%w: %q at %d", ErrIdMapFailed, ch, i)
 		}
 		//newCh := uniqueKey[i] + 'A'
 		suffix = append(suffix, newCh)
 	}
+	return usage.appendShard(suffix, currentId, finalPrefix, todayFormat)
+}
+
+// appendShard 追加分片字符并拼接出完整的 id
+func (usage *RangeUsageInfoStruct) appendShard(suffix []byte, currentId int64, finalPrefix string, todayFormat string) (string, error) {
+	if usage.cfg.instanceTag {
+		suffix = append(suffix, usage.fallbackTag...)
+	}
+	if usage.cfg.shardFunc != nil {
+		ch, err := usage.shardChar(currentId)
+		if err != nil {
+			return "", err
+		}
+		suffix = append(suffix, ch)
+	}
 
-	orderId := fmt.Sprintf(constIdFormat, finalPrefix, todayFormat, suffix)
+	orderId := usage.formatId(finalPrefix, todayFormat, string(suffix))
 
 	//usage.logs.Debug("生成的业务编号 {}", orderId)
-	return orderId, nil
+	return usage.withCheckChar(orderId), nil
 }
 
 func (usage *RangeUsageInfoStruct) GenerateId(applicationName string) (string, error) {
 	return usage.GenerateIdWithAppendPrefix(applicationName, "")
 }
 
+// GenerateIdCtx 与 GenerateId 相同，ctx 会通过 ApplyReq.Context 传给号段申请函数；
+// ctx 取消或超时后不再等待号段申请，也不降级，直接返回 ctx 的错误，号段申请函数不响应 ctx 时仍会在后台执行完
+func (usage *RangeUsageInfoStruct) GenerateIdCtx(ctx context.Context, applicationName string) (string, error) {
+	return usage.GenerateIdWithAppendPrefixCtx(ctx, applicationName, "")
+}
+
+// GenerateIdTimed 与 GenerateId 相同，额外返回本次调用的耗时（包含同步申请号段的时间），
+// 便于调用方接入自己的监控指标；耗时按系统单调时钟计算，不受 WithClock 影响
+func (usage *RangeUsageInfoStruct) GenerateIdTimed(applicationName string) (string, time.Duration, error) {
+	start := time.Now()
+	id, err := usage.GenerateId(applicationName)
+	return id, time.Since(start), err
+}
+
 //
 //func (usage *RangeUsageInfoStruct) UniqueIdByTime(port int, calcTime time.Time) string {
 //
@@ -216,23 +618,13 @@ func (usage *RangeUsageInfoStruct) GenerateId(applicationName string) (string, e
 //	return string(suffix)
 //}
 
-func (usage *RangeUsageInfoStruct) randId(hostKey string) string {
+func (usage *RangeUsageInfoStruct) randId() string {
+	usage.randM.Lock()
 	num := usage.rander.Intn(10000000000)
+	usage.randM.Unlock()
 	suffix := make([]byte, 0)
 	suffix = append(suffix, 'Y')
-
-	for k, ch := range hostKey {
-		if k == 3 {
-			break
-		}
-		pos := ch + 17
-		suffix = append(suffix, uint8(pos))
-	}
-
-	lenHostKey := len(hostKey)
-	for i := 0; i < 3-lenHostKey; i++ {
-		suffix = append(suffix, 'A')
-	}
+	suffix = append(suffix, usage.fallbackTag...)
 
 	randSuffix := make([]byte, 0)
 	for ; num > 0; num = num / 26 {
@@ -262,31 +654,210 @@ func (usage *RangeUsageInfoStruct) randId(hostKey string) string {
 //	return string(suffix)
 //}
 
-func (usage *RangeUsageInfoStruct) replaceRange(rangeStart, rangeEnd int64, usageDay time.Time) int64 {
+// rangeSwitch replaceRange 在锁内的处理结果，日志在锁外输出，缩短持有 usageM 的时间
+type rangeSwitch struct {
+	kind                 rangeSwitchKind
+	appName              string
+	oldMaxId, oldEnd     int64
+	oldDate              time.Time
+	newMaxId, newEnd     int64
+	newDate              time.Time
+	newDay               string
+	rangeStart, rangeEnd int64
+}
+
+type rangeSwitchKind int
+
+const (
+	switchReplaced  rangeSwitchKind = iota //切换到新号段
+	switchOverlap                          //新号段与已用号码部分重叠，只使用未用过的部分
+	switchKeep                             //新号段不大于当前号段，继续使用当前号段
+	switchExhausted                        //当前号段已用完且新号段无法保证递增
+)
+
+func (usage *RangeUsageInfoStruct) replaceRange(rangeStart, rangeEnd int64, usageDay time.Time, rangeDay string) (int64, string) {
+	defer usage.flushDayEvents()
 	usage.usageM.Lock()
-	defer usage.usageM.Unlock()
-	if usage.applyDate.Day() == usageDay.Day() && usage.currentRangeEnd >= rangeEnd {
-		usage.logs.Debug("不能用小的号段代替大的号段，直接递增")
-		usage.currentMaxId++
-		return usage.currentMaxId
+	id, day, sw := usage.replaceRangeLocked(rangeStart, rangeEnd, usageDay, rangeDay)
+	usage.usageM.Unlock()
+	usage.logRangeSwitch(&sw)
+	return id, day
+}
+
+// replaceRangeLocked 只做号段状态的切换，调用方需持有 usageM
+func (usage *RangeUsageInfoStruct) replaceRangeLocked(rangeStart, rangeEnd int64, usageDay time.Time, rangeDay string) (int64, string, rangeSwitch) {
+	sw := rangeSwitch{
+		appName:    usage.appName,
+		oldMaxId:   usage.currentMaxId,
+		oldEnd:     usage.currentRangeEnd,
+		oldDate:    usage.applyDate,
+		rangeStart: rangeStart,
+		rangeEnd:   rangeEnd,
+	}
+	if usage.samePeriod(usage.applyDate, usageDay) && usage.rangeDay == rangeDay && rangeStart <= usage.currentMaxId {
+		//同一天的号段不能让序号回退，否则会生成重复 id
+		if rangeEnd > usage.currentMaxId {
+			//与已用号码部分重叠，只使用未用过的部分
+			sw.kind = switchOverlap
+			if rangeEnd > usage.currentRangeEnd {
+				atomic.StoreInt64(&usage.currentRangeEnd, rangeEnd)
+				usage.resetPrefetchPointLocked()
+			}
+			return usage.nextIdLocked(), usage.rangeDay, sw
+		}
+		if usage.currentMaxId < usage.currentRangeEnd {
+			sw.kind = switchKeep
+			return usage.nextIdLocked(), usage.rangeDay, sw
+		}
+		//当前号段已用完，新号段又无法保证递增，按号段申请失败处理
+		sw.kind = switchExhausted
+		return 0, usage.rangeDay, sw
+	}
+	if !usage.samePeriod(usage.applyDate, usageDay) {
+		usage.rangeQueue = nil //跨天后旧日期的预取号段不再可用
 	}
-	usage.logs.Debug("号段更替，原号段 {} {} {}", usage.currentMaxId, usage.currentRangeEnd, usage.applyDate)
-	usage.currentMaxId = rangeStart
-	usage.currentRangeEnd = rangeEnd
+	for len(usage.rangeQueue) > 0 && usage.rangeQueue[0].start <= rangeEnd {
+		usage.rangeQueue = usage.rangeQueue[1:]
+	}
+	usage.setRangeLocked(rangeStart, rangeEnd)
 	usage.applyDate = usageDay
-	usage.logs.Debug("号段更替，新号段 {} {} {}", usage.currentMaxId, usage.currentRangeEnd, usage.applyDate)
-	return usage.currentMaxId
+	usage.rangeDay = rangeDay
+	usage.recordRangeSwitchLocked()
+	sw.kind = switchReplaced
+	sw.newMaxId, sw.newEnd, sw.newDate, sw.newDay = usage.currentMaxId, usage.currentRangeEnd, usage.applyDate, usage.rangeDay
+	return usage.currentMaxId, usage.rangeDay, sw
+}
+
+// logRangeSwitch 输出 replaceRangeLocked 的处理结果，不能持有 usageM
+func (usage *RangeUsageInfoStruct) logRangeSwitch(sw *rangeSwitch) {
+	switch sw.kind {
+	case switchOverlap:
+		usage.logs.Debug("新号段与已用号码重叠，从当前号码继续递增 {} {} {}", sw.rangeStart, sw.rangeEnd, sw.oldMaxId)
+	case switchKeep:
+		usage.logs.Debug("不能用小的号段代替大的号段，直接递增")
+	case switchExhausted:
+		usage.logs.Warn("{} {} {} 当前号段已用完且新号段 {} {} 不大于已用号码 {}，按申请失败处理", sw.appName, usage.bizType, usage.prefix, sw.rangeStart, sw.rangeEnd, sw.oldMaxId)
+	default:
+		usage.logs.Debug("号段更替，原号段 {} {} {}", sw.oldMaxId, sw.oldEnd, sw.oldDate)
+		usage.logs.Debug("号段更替，新号段 {} {} {} {}", sw.newMaxId, sw.newEnd, sw.newDate, sw.newDay)
+	}
+}
+
+type rangeRefresh int
+
+const (
+	refreshNone    rangeRefresh = iota
+	refreshNewDay               //跨天或尚未申请过号段
+	refreshNearEnd              //号段即将用完
+)
+
+// takenId 一次在锁内取号的结果
+type takenId struct {
+	id       int64
+	day      string
+	prefetch bool         //需要触发后台预取
+	refresh  rangeRefresh //不为 refreshNone 时需要申请新号段，id 无效
+	err      error        //不为 nil 时不能生成 id
 }
 
-func (usage *RangeUsageInfoStruct) incrementAndGet() int64 {
+// takeId 在锁内根据当前号段状态取号，取到的号码不会超过当前号段的结束号码
+// 号段即将用完时：开启预取则先用完剩余号码再切换到预取号段；其它协程正在申请新号段时先用完剩余号码；
+// 否则返回需要申请新号段
+func (usage *RangeUsageInfoStruct) takeId(now time.Time) takenId {
 	usage.usageM.Lock()
 	defer usage.usageM.Unlock()
-	usage.currentMaxId++
-	return usage.currentMaxId
+	if err := usage.checkFutureDateLocked(now); err != nil {
+		return takenId{err: err}
+	}
+	if !usage.samePeriod(now, usage.applyDate) {
+		return takenId{refresh: refreshNewDay}
+	}
+	remaining := usage.currentRangeEnd - usage.currentMaxId
+	if remaining < usage.refreshThreshold() {
+		if usage.cfg.rangeQueueDepth > 0 {
+			if id, ok := usage.nextQueuedIdLocked(); ok {
+				return takenId{id: id, day: usage.rangeDay, prefetch: usage.prefetchNeededLocked()}
+			}
+			return takenId{refresh: refreshNearEnd}
+		}
+		if remaining > 0 && atomic.LoadInt32(&usage.gettingIdRangeCounter) > 0 {
+			//其它协程正在申请新号段，先用完当前号段剩余的号码
+			return takenId{id: usage.nextIdLocked(), day: usage.rangeDay}
+		}
+		return takenId{refresh: refreshNearEnd}
+	}
+	return takenId{id: usage.nextIdLocked(), day: usage.rangeDay, prefetch: usage.prefetchNeededLocked()}
+}
+
+// WillRefresh 按当前号段状态判断下一次 GenerateId 是否会同步请求号段服务（跨天、号段即将用完且没有可用的预取号段），
+// 判断规则与 takeId 相同；并发调用时状态随时可能变化，结果只能作为提示
+func (usage *RangeUsageInfoStruct) WillRefresh() bool {
+	now := usage.cfg.clock.Now()
+	usage.usageM.Lock()
+	defer usage.usageM.Unlock()
+	if !usage.samePeriod(now, usage.applyDate) {
+		return true
+	}
+	if len(usage.released) > 0 {
+		return false //优先发放归还的号码
+	}
+	remaining := usage.currentRangeEnd - usage.currentMaxId
+	if remaining >= usage.refreshThreshold() {
+		return false
+	}
+	if usage.cfg.rangeQueueDepth > 0 {
+		if remaining > 0 {
+			return false
+		}
+		applyDay := usage.periodKey(usage.applyDate)
+		for _, next := range usage.rangeQueue {
+			if next.day == applyDay && next.rangeDay == usage.rangeDay && next.start > usage.currentRangeEnd {
+				return false
+			}
+		}
+		return true
+	}
+	return remaining <= 0 || atomic.LoadInt32(&usage.gettingIdRangeCounter) == 0
+}
+
+// CurrentDate 返回当前持有的号段所属日期（即下一个 id 中的日期）以及是否持有未用完的号段
+// 跨天后旧日期的号段不再使用，返回 false；并发调用时状态随时可能变化，结果只能作为提示
+func (usage *RangeUsageInfoStruct) CurrentDate() (string, bool) {
+	now := usage.cfg.clock.Now()
+	usage.usageM.Lock()
+	defer usage.usageM.Unlock()
+	if usage.rangeDay == "" || !usage.samePeriod(now, usage.applyDate) || usage.currentMaxId >= usage.currentRangeEnd {
+		return "", false
+	}
+	return usage.rangeDay, true
+}
+
+// nextIdLocked 号段内取下一个号码，到达 int64 上限时返回 seqOverflow 而不是回绕成负数，调用方需持有 usageM
+func (usage *RangeUsageInfoStruct) nextIdLocked() int64 {
+	if usage.currentMaxId >= math.MaxInt64 {
+		return seqOverflow
+	}
+	return atomic.AddInt64(&usage.currentMaxId, 1)
+}
+
+// setRangeLocked 切换到新号段，并以新号段的起始号码作为下一个已分配号码，调用方需持有 usageM
+// 号段相关字段在锁内写，写入使用 atomic，以便 expvar 等观测方不加锁读取
+func (usage *RangeUsageInfoStruct) setRangeLocked(start, end int64) {
+	atomic.StoreInt64(&usage.currentRangeStart, start)
+	atomic.StoreInt64(&usage.currentMaxId, start)
+	atomic.StoreInt64(&usage.currentRangeEnd, end)
+	usage.resetPrefetchPointLocked()
 }
 
 func (usage *RangeUsageInfoStruct) getNewIdRange(req *ApplyReq) (*NewRangeResp, bool, error) {
 
+	if usage.cfg.coalesceWindow > 0 {
+		return usage.coalescedNewIdRange(req, usage.cfg.coalesceWindow, usage.cfg.coalesceMaxMultiple)
+	}
+	if usage.cfg.contention == QueueAndWait {
+		return usage.coalescedNewIdRange(req, 0, 1)
+	}
+
 	bUseOnce := false
 	var curCounter int32
 	curCounter = atomic.AddInt32(&(usage.gettingIdRangeCounter), 1)
@@ -294,11 +865,11 @@ func (usage *RangeUsageInfoStruct) getNewIdRange(req *ApplyReq) (*NewRangeResp,
 	if curCounter > 1 { //已经有请求在进行了，只申请自用号码即可
 		usage.logs.Debug("只申请单次使用号段 {}", curCounter)
 		bUseOnce = true
-		req.Step = 1
+		req.Step = usage.contendedStep(curCounter)
 	}
 
 	//logs.Debug("执行号段申请 {}", curCounter)
-	resp, err := usage.reqNumbersCaller(req)
+	resp, err := usage.callNumbers(req)
 	if err != nil {
 		usage.logs.Debug("号段申请失败 {} {}", err.Error(), curCounter)
 		return nil, bUseOnce, err
@@ -0,0 +1,73 @@
+package generator
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// startDayWatch 配置了 WithDayChangeCheck 时启动后台协程，每个实例最多一个，Close 时退出
+func (usage *RangeUsageInfoStruct) startDayWatch() {
+	if usage.cfg.dayCheckInterval <= 0 {
+		return
+	}
+	usage.stopCh = make(chan struct{})
+	go usage.watchDay(usage.cfg.dayCheckInterval)
+}
+
+func (usage *RangeUsageInfoStruct) watchDay(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-usage.stopCh:
+			return
+		case <-ticker.C:
+			usage.reconcileDay(usage.cfg.clock.Now())
+		}
+	}
+}
+
+// reconcileDay 检测到跨天且没有其它号段申请在进行时，提前申请新一天的号段，
+// 使休眠或长时间空闲后跨天的实例不必在第一次生成 id 时同步申请号段
+func (usage *RangeUsageInfoStruct) reconcileDay(now time.Time) {
+	if atomic.LoadInt32(&usage.closed) != 0 || atomic.LoadInt32(&usage.gettingIdRangeCounter) > 0 {
+		return
+	}
+	usage.usageM.Lock()
+	//applyDate 在锁内写入，非零说明已有请求设置过 appName
+	stale := !usage.applyDate.IsZero() && !usage.samePeriod(now, usage.applyDate)
+	appName := usage.appName
+	usage.usageM.Unlock()
+	if !stale {
+		return
+	}
+
+	req := ApplyReq{
+		AppName: appName,
+		BizType: usage.bizType,
+		Day:     usage.periodKey(now),
+		Step:    usage.step(),
+	}
+	usage.logs.Info("{} {} {} 检测到跨天，提前申请新号段 {}", appName, usage.bizType, usage.prefix, req.Day)
+	resp, bUseOnce, err := usage.getNewIdRange(&req)
+	if err != nil {
+		usage.logs.Warn("{} {} {} 跨天提前申请号段失败 {}", appName, usage.bizType, usage.prefix, err.Error())
+		return
+	}
+	if bUseOnce {
+		//请求路径上已经在申请新号段，单次号码不再使用
+		return
+	}
+	rangeDay, err := usage.checkRangeDay(&req, resp)
+	if err != nil {
+		return
+	}
+	id, _ := usage.replaceRange(resp.RangeStart, resp.RangeEnd, now, rangeDay)
+
+	usage.usageM.Lock()
+	defer usage.usageM.Unlock()
+	if id > 0 && id == usage.currentRangeStart && id == usage.currentMaxId {
+		//replaceRange 把起始号码当作已分配，这里没有生成 id，退回给后续请求使用
+		atomic.StoreInt64(&usage.currentMaxId, id-1)
+	}
+}
@@ -0,0 +1,56 @@
+package generator
+
+import (
+	"testing"
+	"time"
+)
+
+// TestDayChangeCheckRefreshes 跨过零点后没有生成调用时后台也会提前申请新一天的号段，之后的第一次生成不再同步申请
+func TestDayChangeCheckRefreshes(t *testing.T) {
+	caller := &dayRecorder{memCaller: newMemCaller(), days: make(map[string]bool)}
+	clock := newFakeClock(time.Date(2024, 1, 1, 23, 59, 59, 0, time.Local))
+	usage, err := NewWithOptions(caller.apply, testOptions(WithClock(clock), WithStep(100), WithDayChangeCheck(time.Millisecond))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := usage.GenerateId("app"); err != nil {
+		t.Fatal(err)
+	}
+	clock.Add(2 * time.Second)
+	deadline := time.Now().Add(time.Second)
+	for {
+		caller.m.Lock()
+		refreshed := caller.days["20240102"]
+		caller.m.Unlock()
+		//号段申请返回后还要切换到新号段
+		if refreshed && !usage.WillRefresh() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no range requested for 20240102 after midnight")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	calls := caller.callCount()
+	id, err := usage.GenerateId("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts, err := usage.Parse(id); err != nil || parts.Day != "20240102" || parts.Sequence != 1 {
+		t.Fatalf("first id after midnight %s (%v), want sequence 1 on 20240102", id, err)
+	}
+	if caller.callCount() != calls {
+		t.Fatal("first GenerateId after the proactive refresh requested a range")
+	}
+
+	//Close 之后后台协程退出，不再申请号段
+	if err := usage.Close(); err != nil {
+		t.Fatal(err)
+	}
+	calls = caller.callCount()
+	clock.Add(24 * time.Hour)
+	time.Sleep(20 * time.Millisecond)
+	if caller.callCount() != calls {
+		t.Fatal("range requested after Close")
+	}
+}
@@ -0,0 +1,41 @@
+package generator
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// defaultUsage Init 创建的进程级生成器
+var defaultUsage atomic.Pointer[RangeUsageInfoStruct]
+
+// Init 按 NewWithOptions 创建进程级的默认生成器，供包级的 GenerateId 使用，小型服务不必在各层之间传递生成器；
+// opts 必须包含 WithAppName，与 NewWithOptions 相同；重复调用时替换默认生成器并关闭之前的生成器，选项不合法时保留之前的生成器并返回错误
+func Init(caller NumbersReqFunc, opts ...Option) error {
+	usage, err := NewWithOptions(caller, opts...)
+	if err != nil {
+		return err
+	}
+	if old := defaultUsage.Swap(usage); old != nil {
+		_ = old.Close()
+	}
+	return nil
+}
+
+// Default 返回 Init 创建的默认生成器，没有调用 Init 时返回 nil
+func Default() *RangeUsageInfoStruct {
+	return defaultUsage.Load()
+}
+
+// GenerateId 使用默认生成器和 Init 时设置的应用名生成 id，没有调用 Init 时返回 ErrNotInitialized
+func GenerateId() (string, error) {
+	return GenerateIdCtx(context.Background())
+}
+
+// GenerateIdCtx 与 GenerateId 相同，ctx 的处理见 RangeUsageInfoStruct.GenerateIdCtx
+func GenerateIdCtx(ctx context.Context) (string, error) {
+	usage := defaultUsage.Load()
+	if usage == nil {
+		return "", ErrNotInitialized
+	}
+	return usage.GenerateIdCtx(ctx, usage.appName)
+}
@@ -0,0 +1,100 @@
+package generator
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// DegradedReasonCode 降级原因
+type DegradedReasonCode string
+
+const (
+	ReasonCircuitOpen          DegradedReasonCode = "circuit_open"           //熔断器打开或半开
+	ReasonDailyCapReached      DegradedReasonCode = "daily_cap_reached"      //当天序号已用完
+	ReasonFallbackRateExceeded DegradedReasonCode = "fallback_rate_exceeded" //最近一分钟降级 id 数达到上限
+	ReasonConsecutiveFailures  DegradedReasonCode = "consecutive_failures"   //连续号段申请失败次数达到阈值
+)
+
+// DegradedInfo 生成器的降级状态，Degraded 为 false 时其余字段为零值
+type DegradedInfo struct {
+	Degraded            bool               `json:"degraded"`
+	Reason              DegradedReasonCode `json:"reason,omitempty"`
+	Since               *time.Time         `json:"since,omitempty"` //进入该降级状态的时间
+	ConsecutiveFailures int                `json:"consecutiveFailures"`
+}
+
+// DegradedReason 返回当前的降级原因，同时满足多个条件时按
+// 熔断、当天序号用完、降级 id 数超限、连续申请失败 的顺序返回第一个
+func (usage *RangeUsageInfoStruct) DegradedReason() DegradedInfo {
+	now := usage.cfg.clock.Now()
+	limited := usage.tunables().maxFallbackPerMinute > 0 && !usage.allowFallback(now)
+
+	h := &usage.health
+	h.m.Lock()
+	defer h.m.Unlock()
+	info := DegradedInfo{ConsecutiveFailures: h.consecutiveFailures}
+	var since time.Time
+	switch {
+	case h.breaker != breakerClosed:
+		info.Reason, since = ReasonCircuitOpen, h.breakerSince
+	case h.capDay != "" && h.capDay == usage.periodKey(now):
+		info.Reason, since = ReasonDailyCapReached, h.capSince
+	case limited && !h.fallbackLimitedSince.IsZero():
+		info.Reason, since = ReasonFallbackRateExceeded, h.fallbackLimitedSince
+	case h.consecutiveFailures >= usage.tunables().unhealthyThreshold:
+		info.Reason, since = ReasonConsecutiveFailures, h.unhealthySince
+	default:
+		return info
+	}
+	info.Degraded = true
+	info.Since = &since
+	return info
+}
+
+// recordGenerateError 记录会导致降级的生成错误，供 DegradedReason 使用
+func (usage *RangeUsageInfoStruct) recordGenerateError(err error) {
+	now := usage.cfg.clock.Now()
+	h := &usage.health
+	h.m.Lock()
+	defer h.m.Unlock()
+	switch {
+	case errors.Is(err, ErrSequenceOverflow) || errors.Is(err, errDatelessOutOfRange):
+		if day := usage.periodKey(now); h.capDay != day {
+			h.capDay, h.capSince = day, now
+		}
+	case errors.Is(err, ErrFallbackRateExceeded):
+		if h.fallbackLimitedSince.IsZero() {
+			h.fallbackLimitedSince = now
+		}
+	}
+}
+
+// clearFallbackLimited 再次允许降级时清除降级 id 数超限的状态
+func (usage *RangeUsageInfoStruct) clearFallbackLimited() {
+	h := &usage.health
+	h.m.Lock()
+	defer h.m.Unlock()
+	h.fallbackLimitedSince = time.Time{}
+}
+
+// HealthHandler 返回健康检查的 http.Handler，一般挂载在 /healthz：
+// GET 请求返回 DegradedReason 的 json，正常时状态码 200，降级时 503；其它方法返回 405
+func (usage *RangeUsageInfoStruct) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		info := usage.DegradedReason()
+		w.Header().Set("Content-Type", "application/json")
+		if info.Degraded {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+		_ = json.NewEncoder(w).Encode(info)
+	})
+}
@@ -0,0 +1,81 @@
+package generator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestDegradedReason 每种降级原因都能通过 DegradedReason 和 HealthHandler 报告出来
+func TestDegradedReason(t *testing.T) {
+	cases := []struct {
+		name   string
+		opts   []Option
+		caller func() NumbersReqFunc
+		calls  int
+		want   DegradedReasonCode
+	}{
+		{"consecutive failures", []Option{WithHealthThreshold(2)}, nil, 2, ReasonConsecutiveFailures},
+		{"circuit open", []Option{WithHealthThreshold(100), WithCircuitBreaker(1, time.Minute)}, nil, 1, ReasonCircuitOpen},
+		{"fallback rate exceeded", []Option{WithHealthThreshold(100), WithMaxFallbackRate(1)}, nil, 2, ReasonFallbackRateExceeded},
+		{"daily cap reached", []Option{WithSequenceRadix(16, 2)}, func() NumbersReqFunc {
+			return (&scriptedCaller{ranges: []NewRangeResp{{RangeStart: 239, RangeEnd: 300}}}).apply
+		}, 2, ReasonDailyCapReached},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := newFakeClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local))
+			failing := newMemCaller()
+			failing.setFail(true)
+			caller := NumbersReqFunc(failing.apply)
+			if tc.caller != nil {
+				caller = tc.caller()
+			}
+			usage, err := NewWithOptions(caller, testOptions(append(tc.opts, WithClock(clock), WithStep(1))...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer usage.Close()
+			handler := usage.HealthHandler()
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("healthz status %d before degrading, want 200", rec.Code)
+			}
+
+			for i := 0; i < tc.calls; i++ {
+				clock.Add(time.Second)
+				_, _ = usage.GenerateId("app")
+			}
+			info := usage.DegradedReason()
+			if !info.Degraded || info.Reason != tc.want || info.Since == nil || info.Since.IsZero() || info.Since.After(clock.Now()) {
+				t.Fatalf("DegradedReason %+v, want %s", info, tc.want)
+			}
+
+			rec = httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			var got DegradedInfo
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != http.StatusServiceUnavailable || got.Reason != tc.want || got.Since == nil {
+				t.Fatalf("healthz status %d body %s, want 503 with reason %s", rec.Code, rec.Body.String(), tc.want)
+			}
+		})
+	}
+}
+
+func TestHealthHandlerMethod(t *testing.T) {
+	usage, err := NewWithOptions(newMemCaller().apply, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	rec := httptest.NewRecorder()
+	usage.HealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/healthz", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST status %d, want 405", rec.Code)
+	}
+}
@@ -0,0 +1,69 @@
+package generator
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DiagnosticKind 诊断事件类型
+type DiagnosticKind string
+
+const (
+	DiagnosticDuplicateRange DiagnosticKind = "duplicate_range" //号段服务连续两次返回了完全相同的号段
+	DiagnosticSelfCheck      DiagnosticKind = "self_check"      //WithSelfCheck 自检发现生成的 id 不满足约束
+	DiagnosticDuplicateId    DiagnosticKind = "duplicate_id"    //WithDuplicateGuard 发现生成的 id 可能与最近的 id 重复
+)
+
+// Diagnostic 诊断事件，用于暴露号段服务的异常行为，生成器本身会按安全的方式继续处理
+type Diagnostic struct {
+	Kind       DiagnosticKind
+	Time       time.Time
+	AppName    string
+	BizType    string
+	Day        string //申请号段的日期，自检事件为 id 中的日期
+	RangeStart int64
+	RangeEnd   int64
+	Message    string
+}
+
+// lastRange 号段服务最近一次返回的号段，用于发现重复号段
+type lastRange struct {
+	m     sync.Mutex
+	day   string
+	start int64
+	end   int64
+}
+
+// emitDiagnostic 调用 WithDiagnostic 设置的回调，调用方不能持有 usageM
+func (usage *RangeUsageInfoStruct) emitDiagnostic(d Diagnostic) {
+	if usage.cfg.diagnostic == nil {
+		return
+	}
+	d.Time = usage.cfg.clock.Now()
+	d.AppName = usage.appName
+	d.BizType = usage.bizType
+	usage.cfg.diagnostic(d)
+}
+
+// detectDuplicateRange 号段服务返回与上一次完全相同的号段时计数并上报诊断事件
+// 重复的号段不会导致重复 id：replaceRange 不允许序号回退，只会继续使用当前号段剩余的号码
+func (usage *RangeUsageInfoStruct) detectDuplicateRange(req *ApplyReq, resp *NewRangeResp) {
+	last := &usage.lastRange
+	last.m.Lock()
+	dup := last.day == req.Day && last.start == resp.RangeStart && last.end == resp.RangeEnd
+	last.day, last.start, last.end = req.Day, resp.RangeStart, resp.RangeEnd
+	last.m.Unlock()
+	if !dup {
+		return
+	}
+	atomic.AddInt64(&usage.counters.duplicateRanges, 1)
+	usage.logs.Error("{} {} {} 号段服务连续返回了相同的号段 {} {} {}", usage.appName, usage.bizType, usage.prefix, req.Day, resp.RangeStart, resp.RangeEnd)
+	usage.emitDiagnostic(Diagnostic{
+		Kind:       DiagnosticDuplicateRange,
+		Day:        req.Day,
+		RangeStart: resp.RangeStart,
+		RangeEnd:   resp.RangeEnd,
+		Message:    "numbers service returned the same range twice",
+	})
+}
@@ -0,0 +1,55 @@
+package generator
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestDuplicateRangeReported 号段服务连续返回相同号段时计数并上报诊断事件，生成的 id 仍然不重复
+func TestDuplicateRangeReported(t *testing.T) {
+	caller := &scriptedCaller{ranges: []NewRangeResp{
+		{RangeStart: 1, RangeEnd: 10},
+		{RangeStart: 1, RangeEnd: 10},
+		{RangeStart: 11, RangeEnd: 20},
+		{RangeStart: 21, RangeEnd: 30},
+		{RangeStart: 31, RangeEnd: 40},
+	}}
+	var m sync.Mutex
+	var events []Diagnostic
+	usage, err := NewWithOptions(caller.apply, testOptions(WithStep(10), WithThreshold(2), WithNoFallback(), WithDiagnostic(func(d Diagnostic) {
+		m.Lock()
+		events = append(events, d)
+		m.Unlock()
+	}))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+
+	var last int64
+	for i := 0; i < 25; i++ {
+		id, err := usage.GenerateId("app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts, err := usage.Parse(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parts.Sequence <= last {
+			t.Fatalf("id %s reused sequence %d after %d", id, parts.Sequence, last)
+		}
+		last = parts.Sequence
+	}
+	if got := usage.Stats().DuplicateRanges; got != 1 {
+		t.Fatalf("DuplicateRanges %d, want 1", got)
+	}
+	m.Lock()
+	defer m.Unlock()
+	if len(events) != 1 {
+		t.Fatalf("got %d diagnostic events, want 1: %+v", len(events), events)
+	}
+	if d := events[0]; d.Kind != DiagnosticDuplicateRange || d.RangeStart != 1 || d.RangeEnd != 10 || d.AppName != "app" || d.Day == "" {
+		t.Fatalf("diagnostic %+v, want duplicate range 1-10", d)
+	}
+}
@@ -0,0 +1,82 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+)
+
+// DryRun 用于切换配置前的检查：用 applicationName 以步长 1 申请一个号段，按当前配置以号段的起始号码生成一个样例 id，
+// 并校验长度（WithMaxIDLength）、安全字符集合（WithSafeCharset）以及能否被 Parse 还原出相同的日期和序号；
+// 申请到的号段直接丢弃，不会改变当前号段、Stats 计数、熔断器和号段日志等任何运行状态，号段服务端仍会消耗这 1 个号码。
+// ctx 只控制等待号段申请返回的时间，超时后申请函数仍会在后台执行完
+func (usage *RangeUsageInfoStruct) DryRun(ctx context.Context, applicationName string) (sampleID string, err error) {
+	if usage.reqNumbersCaller == nil && usage.cfg.rawCaller == nil {
+		return "", fmt.Errorf("%w: dry run needs a numbers caller", ErrInvalidOption)
+	}
+	req := ApplyReq{
+		AppName: applicationName,
+		BizType: usage.bizType,
+		Day:     usage.periodKey(usage.cfg.clock.Now()),
+		Step:    1,
+	}
+	type result struct {
+		resp *NewRangeResp
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := usage.invokeLocked(&req, usage.invokeCaller)
+		done <- result{resp: resp, err: err}
+	}()
+	var resp *NewRangeResp
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case r := <-done:
+		if r.err != nil {
+			return "", fmt.Errorf("dry run apply range: %w", r.err)
+		}
+		resp = r.resp
+	}
+	if resp.RangeStart <= 0 || resp.RangeEnd < resp.RangeStart {
+		return "", fmt.Errorf("%w: range %d-%d", ErrInvalidResponse, resp.RangeStart, resp.RangeEnd)
+	}
+	rangeDay, err := usage.checkRangeDay(&req, resp)
+	if err != nil {
+		return "", err
+	}
+
+	finalPrefix := usage.currentPrefix()
+	if usage.cfg.bizCode != "" {
+		finalPrefix = joinPrefix(finalPrefix, usage.cfg.bizCode)
+	}
+	id, err := usage.generateKey(resp.RangeStart, finalPrefix, rangeDay, usage.cfg.typeFlag)
+	if err != nil {
+		return "", err
+	}
+	if err := usage.checkSample(id, finalPrefix, rangeDay, usage.nodeSeq(resp.RangeStart)); err != nil {
+		return id, err
+	}
+	if usage.cfg.uuidNamespace != nil {
+		id = usage.toUUID(id)
+	}
+	if usage.cfg.maxIdLen > 0 && len(id) > usage.cfg.maxIdLen {
+		return id, fmt.Errorf("%w: %s longer than %d", ErrIdTooLong, id, usage.cfg.maxIdLen)
+	}
+	return id, nil
+}
+
+// checkSample 校验 DryRun 生成的样例 id 的字符集合，并确认能还原出日期和序号；UUID 输出模式在转换前按原始 id 校验
+func (usage *RangeUsageInfoStruct) checkSample(id string, finalPrefix string, day string, seq int64) error {
+	if err := usage.cfg.safeCharset.check("sample id", id[len(finalPrefix):]); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidId, err.Error())
+	}
+	parts, err := parseFormat(id, usage.currentFormat(), usage.cfg.keyMap, usage.cfg.keyInverse)
+	if err != nil {
+		return err
+	}
+	if parts.Sequence != seq || parts.Day != day {
+		return fmt.Errorf("%w: %s parsed as %s %d, want %s %d", ErrInvalidId, id, parts.Day, parts.Sequence, day, seq)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestDryRunLeavesStats DryRun 返回能解析的样例 id，当前号段和 Stats 计数都不变
+func TestDryRunLeavesStats(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local))
+	caller := newMemCaller()
+	usage, err := NewWithOptions(caller.apply, testOptions(WithClock(clock), WithStep(100), WithCheckChar(true))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	var last int64
+	for i := 0; i < 5; i++ {
+		id, err := usage.GenerateId("app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		last = mustParse(t, usage, id).Sequence
+	}
+	before := usage.Stats()
+	calls := caller.callCount()
+
+	sample, err := usage.DryRun(context.Background(), "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts, err := usage.Parse(sample)
+	if err != nil || parts.Fallback || parts.Day != "20240101" || parts.Sequence != 101 {
+		t.Fatalf("sample %s parsed to %+v (%v), want the start of the dry-run range", sample, parts, err)
+	}
+	if caller.callCount() != calls+1 {
+		t.Fatalf("DryRun made %d range requests, want 1", caller.callCount()-calls)
+	}
+	if after := usage.Stats(); !reflect.DeepEqual(before, after) {
+		t.Fatalf("Stats changed by DryRun:\nbefore %+v\nafter  %+v", before, after)
+	}
+	id, err := usage.GenerateId("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq := mustParse(t, usage, id).Sequence; seq != last+1 {
+		t.Fatalf("GenerateId after DryRun got sequence %d, want %d from the live range", seq, last+1)
+	}
+}
+
+func TestDryRunCallerError(t *testing.T) {
+	caller := newMemCaller()
+	caller.setFail(true)
+	usage, err := NewWithOptions(caller.apply, testOptions(WithHealthThreshold(1))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	before := usage.Stats()
+	if _, err := usage.DryRun(context.Background(), "app"); !errors.Is(err, errDown) {
+		t.Fatalf("got %v, want the caller error", err)
+	}
+	if after := usage.Stats(); !reflect.DeepEqual(before, after) {
+		t.Fatalf("Stats changed by a failed DryRun:\nbefore %+v\nafter  %+v", before, after)
+	}
+	if info := usage.DegradedReason(); info.Degraded {
+		t.Fatalf("failed DryRun degraded the generator: %+v", info)
+	}
+
+	gated := &gatedCaller{gate: make(chan struct{})}
+	defer close(gated.gate)
+	slow, err := NewWithOptions(gated.apply, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer slow.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := slow.DryRun(ctx, "app"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+}
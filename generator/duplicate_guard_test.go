@@ -0,0 +1,69 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestDuplicateGuardReports 号段服务在时钟回拨后重新分配了已用过的号段，生成的重复 id 被检测到并上报
+func TestDuplicateGuardReports(t *testing.T) {
+	caller := &scriptedCaller{ranges: []NewRangeResp{{RangeStart: 1, RangeEnd: 100}}}
+	clock := newFakeClock(time.Date(2024, 1, 1, 23, 59, 0, 0, time.Local))
+	rec := &diagnosticRecorder{}
+	usage, err := NewWithOptions(caller.apply, testOptions(WithClock(clock), WithDuplicateGuard(1000), WithDiagnostic(rec.record))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	first, err := usage.GenerateId("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.Add(2 * time.Minute)
+	if _, err := usage.GenerateId("app"); err != nil {
+		t.Fatal(err)
+	}
+	clock.Add(-2 * time.Minute)
+	again, err := usage.GenerateId("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != first {
+		t.Fatalf("misbehaving caller produced %s then %s, want a repeated id", first, again)
+	}
+	if s := usage.Stats(); s.DuplicateIds != 1 {
+		t.Fatalf("DuplicateIds %d, want 1", s.DuplicateIds)
+	}
+	var found []Diagnostic
+	rec.m.Lock()
+	for _, d := range rec.events {
+		if d.Kind == DiagnosticDuplicateId {
+			found = append(found, d)
+		}
+	}
+	rec.m.Unlock()
+	if len(found) != 1 || !strings.Contains(found[0].Message, first) {
+		t.Fatalf("diagnostics %+v, want one duplicate id %s", found, first)
+	}
+}
+
+// TestDuplicateGuardFalsePositives 写入多代不重复的 id 时误判率在文档说明的范围内
+func TestDuplicateGuardFalsePositives(t *testing.T) {
+	const capacity = 10000
+	g := newDuplicateGuard(capacity)
+	falsePositives := 0
+	for i := 0; i < 5*capacity; i++ {
+		if g.seen(fmt.Sprintf("T-20240101%012d", i)) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / (5 * capacity); rate > 0.004 {
+		t.Fatalf("false positive rate %.4f, want at most about 0.2%%", rate)
+	}
+	//最近写入的 id 一定能发现
+	if !g.seen(fmt.Sprintf("T-20240101%012d", 5*capacity-1)) {
+		t.Fatal("most recent id not detected")
+	}
+}
@@ -0,0 +1,79 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestEmptyPrefixRoundTrip 前缀为空时 id 不以分隔符开头，正常 id 和降级 id 都能按原样解析
+func TestEmptyPrefixRoundTrip(t *testing.T) {
+	cases := []struct {
+		name string
+		opts []Option
+	}{
+		{"plain", nil},
+		{"date separator", []Option{WithDateSeparator("_")}},
+		{"dateless", []Option{WithDateless(true)}},
+		{"check char", []Option{WithCheckChar(true)}},
+		{"radix", []Option{WithSequenceRadix(36, 6)}},
+		{"type flag and shard", []Option{WithTypeFlag('P', nil), WithShardFunc(shardByMod)}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			caller := newMemCaller()
+			usage, err := NewWithOptions(caller.apply, testOptions(append(tc.opts, WithPrefix(""), WithBizType("ORD"))...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer usage.Close()
+			check := func(id string, wantFallback bool) *IdParts {
+				t.Helper()
+				if strings.HasPrefix(id, "-") || strings.Contains(id, "--") {
+					t.Fatalf("id %s has an empty prefix separator", id)
+				}
+				parts, err := usage.Parse(id)
+				if err != nil || parts.Prefix != "" || parts.Fallback != wantFallback {
+					t.Fatalf("Parse(%s) = %+v, %v, want empty prefix, fallback %v", id, parts, err, wantFallback)
+				}
+				return parts
+			}
+			var last int64
+			for i := 0; i < 20; i++ {
+				id, err := usage.GenerateId("app")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if strings.Contains(id, "-") {
+					t.Fatalf("id %s without prefix contains a separator", id)
+				}
+				parts := check(id, false)
+				if parts.Sequence <= last {
+					t.Fatalf("%s decoded to sequence %d after %d", id, parts.Sequence, last)
+				}
+				last = parts.Sequence
+			}
+
+			//追加前缀时追加的前缀就是 id 的前缀
+			id, err := usage.GenerateIdWithAppendPrefix("app", "PAY")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(id, "PAY-") {
+				t.Fatalf("id %s with append prefix, want PAY- at the start", id)
+			}
+			if parts, err := usage.Parse(id); err != nil || parts.Prefix != "PAY" || parts.AppendPrefix != "PAY" {
+				t.Fatalf("Parse(%s) = %+v, %v, want prefix PAY", id, parts, err)
+			}
+
+			caller.setFail(true)
+			usage.usageM.Lock()
+			usage.currentMaxId = usage.currentRangeEnd
+			usage.usageM.Unlock()
+			id, err = usage.GenerateId("app")
+			if err != nil {
+				t.Fatal(err)
+			}
+			check(id, true)
+		})
+	}
+}
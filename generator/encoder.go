@@ -0,0 +1,62 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// inverseKeyMap keyMap 的逆映射，以映射结果字符为下标，值为对应的数字字符，0 表示该字符不是映射结果
+type inverseKeyMap [256]byte
+
+// defaultInverseKeyMap 默认 keyMap 的逆映射，包初始化时计算一次
+var defaultInverseKeyMap = mustInvertKeyMap(keyMap)
+
+// invertKeyMap 计算 0-9 映射的逆映射，映射不是一一对应（缺少数字或两个数字映射到同一个字符）时返回错误
+func invertKeyMap(m map[byte]byte) (*inverseKeyMap, error) {
+	inv := &inverseKeyMap{}
+	for d := byte('0'); d <= '9'; d++ {
+		ch, ok := m[d]
+		if !ok {
+			return nil, fmt.Errorf("%w: key map missing digit %q", ErrInvalidOption, d)
+		}
+		if prev := inv[ch]; prev != 0 {
+			return nil, fmt.Errorf("%w: key map is not a bijection, digits %q and %q both map to %q", ErrInvalidOption, prev, d, ch)
+		}
+		inv[ch] = d
+	}
+	return inv, nil
+}
+
+func mustInvertKeyMap(m map[byte]byte) *inverseKeyMap {
+	inv, err := invertKeyMap(m)
+	if err != nil {
+		panic(err)
+	}
+	return inv
+}
+
+// validateKeyMap 校验自定义的数字映射，保证 Parse 能无歧义地拆分 id：
+// 0-9 都要有映射且一一对应；映射结果不能是 id 的分隔符 -、separators 中的日期分隔符或模板固定字符、降级标记 Y；
+// 日期与序号直接相连（glued）时映射结果不能是数字，否则无法区分日期和序号
+func validateKeyMap(m map[byte]byte, separators string, glued bool) error {
+	if _, err := invertKeyMap(m); err != nil {
+		return err
+	}
+	for d := byte('0'); d <= '9'; d++ {
+		ch, ok := m[d]
+		if !ok {
+			return fmt.Errorf("%w: key map missing digit %q", ErrInvalidOption, d)
+		}
+		switch {
+		case ch == '-':
+			return fmt.Errorf("%w: key map digit %q maps to id separator %q", ErrInvalidOption, d, ch)
+		case strings.IndexByte(separators, ch) >= 0:
+			return fmt.Errorf("%w: key map digit %q maps to date separator %q", ErrInvalidOption, d, ch)
+		case ch == constFallbackMarker:
+			return fmt.Errorf("%w: key map digit %q maps to fallback marker %q", ErrInvalidOption, d, ch)
+		case glued && ch >= '0' && ch <= '9':
+			return fmt.Errorf("%w: key map digit %q maps to digit %q, which is ambiguous when the date is glued to the suffix", ErrInvalidOption, d, ch)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package generator
+
+import (
+	"errors"
+	"testing"
+)
+
+// keyMapWith 返回默认映射的副本，digit 改为映射到 ch
+func keyMapWith(digit, ch byte) map[byte]byte {
+	m := make(map[byte]byte, len(keyMap))
+	for k, v := range keyMap {
+		m[k] = v
+	}
+	m[digit] = ch
+	return m
+}
+
+func TestKeyMapAlphabetRejected(t *testing.T) {
+	cases := []struct {
+		name string
+		opts []Option
+	}{
+		{"id separator", []Option{WithKeyMap(keyMapWith('3', '-'))}},
+		{"date separator", []Option{WithDateSeparator("_"), WithKeyMap(keyMapWith('3', '_'))}},
+		{"fallback marker", []Option{WithKeyMap(keyMapWith('3', constFallbackMarker))}},
+		{"digit glued to date", []Option{WithKeyMap(keyMapWith('3', '7'))}},
+		{"duplicate char", []Option{WithKeyMap(keyMapWith('3', keyMap['4']))}},
+		{"missing digit", []Option{WithKeyMap(map[byte]byte{'0': 'A'})}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewWithOptions(newMemCaller().apply, testOptions(tc.opts...)...); !errors.Is(err, ErrInvalidOption) {
+				t.Fatalf("error %v, want ErrInvalidOption", err)
+			}
+		})
+	}
+}
+
+// TestKeyMapDigitsWithSeparator 日期与序号之间有分隔符时映射为数字没有歧义，可以使用
+func TestKeyMapDigitsWithSeparator(t *testing.T) {
+	digits := make(map[byte]byte, 10)
+	for d := byte('0'); d <= '9'; d++ {
+		digits[d] = d
+	}
+	usage, err := NewWithOptions(newMemCaller().apply, testOptions(WithDateSeparator("-"), WithKeyMap(digits))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	id, err := usage.GenerateId("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts, err := usage.Parse(id); err != nil || parts.Sequence != 1 {
+		t.Fatalf("Parse(%s) = %+v, %v", id, parts, err)
+	}
+}
+
+// TestInverseKeyMap 默认映射和自定义映射的逆映射正确，不是一一对应的映射被拒绝
+func TestInverseKeyMap(t *testing.T) {
+	custom := map[byte]byte{'0': 'Z', '1': 'X', '2': 'W', '3': 'V', '4': 'T', '5': 'P', '6': 'M', '7': 'L', '8': 'K', '9': 'J'}
+	for name, m := range map[string]map[byte]byte{"default": keyMap, "custom": custom} {
+		inv, err := invertKeyMap(m)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		mapped := 0
+		for ch := 0; ch < len(inv); ch++ {
+			if digit := inv[ch]; digit != 0 {
+				mapped++
+				if m[digit] != byte(ch) {
+					t.Fatalf("%s: inverse maps %q to %q, forward maps %q to %q", name, ch, digit, digit, m[digit])
+				}
+			}
+		}
+		if mapped != 10 {
+			t.Fatalf("%s: inverse has %d entries, want 10", name, mapped)
+		}
+	}
+	if *defaultInverseKeyMap != *mustInvertKeyMap(keyMap) {
+		t.Fatal("package-level inverse differs from the default key map")
+	}
+
+	for name, m := range map[string]map[byte]byte{
+		"duplicate char": keyMapWith('3', keyMap['0']),
+		"missing digit":  {'0': 'A', '1': 'C'},
+	} {
+		if _, err := invertKeyMap(m); !errors.Is(err, ErrInvalidOption) {
+			t.Fatalf("%s: got %v, want ErrInvalidOption", name, err)
+		}
+	}
+
+	//自定义映射的实例使用自己的逆映射解析 id
+	usage, err := NewWithOptions(newMemCaller().apply, testOptions(WithKeyMap(custom))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	for i := int64(1); i <= 12; i++ {
+		id, err := usage.GenerateId("app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parts, err := usage.Parse(id); err != nil || parts.Sequence != i {
+			t.Fatalf("Parse(%s) = %+v, %v, want sequence %d", id, parts, err, i)
+		}
+	}
+}
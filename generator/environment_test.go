@@ -0,0 +1,45 @@
+package generator
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestEnvironmentIsolation staging 和 prod 共用同一个号段服务时申请的隔离键不同，拿到的号段互不影响，id 的格式不变
+func TestEnvironmentIsolation(t *testing.T) {
+	caller := newMemCaller()
+	var m sync.Mutex
+	bizTypes := make(map[string]string)
+	record := func(env string) NumbersReqFunc {
+		return func(req *ApplyReq) (*NewRangeResp, error) {
+			m.Lock()
+			bizTypes[env] = req.BizType
+			m.Unlock()
+			return caller.apply(req)
+		}
+	}
+	ids := make(map[string]string)
+	for _, env := range []string{"staging", "prod"} {
+		usage, err := NewWithOptions(record(env), testOptions(WithBizType("ORD"), WithEnvironment(env))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, err := usage.GenerateId("app")
+		usage.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[env] = id
+	}
+	if bizTypes["staging"] != "ORD@staging" || bizTypes["prod"] != "ORD@prod" {
+		t.Fatalf("requested biz types %v, want ORD@staging and ORD@prod", bizTypes)
+	}
+	//两个环境各自从 1 开始发放，id 相同说明 id 中不带环境，只是号段相互隔离
+	if ids["staging"] != ids["prod"] {
+		t.Fatalf("staging id %s and prod id %s differ, want the same first id from isolated ranges", ids["staging"], ids["prod"])
+	}
+
+	if _, err := NewWithOptions(caller.apply, testOptions(WithEnvironment("pr od"))...); err == nil {
+		t.Fatal("environment with a space was accepted")
+	}
+}
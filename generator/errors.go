@@ -0,0 +1,86 @@
+package generator
+
+import "errors"
+
+// Error 带错误码的哨兵错误：用 errors.Is 判断具体错误，用 ErrorCode 或 errors.As 取出错误码用于接口响应，不需要匹配错误文本
+type Error struct {
+	Code    string //稳定的错误码，如 RANGE_DAY_MISMATCH
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func codedError(code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+var (
+	ErrRangeDayMismatch = codedError("RANGE_DAY_MISMATCH", "range day mismatch") //服务端返回的号段日期与申请日期不一致
+	ErrInvalidOption    = codedError("INVALID_OPTION", "invalid option")         //构造参数或选项不合法
+	ErrCircuitOpen      = codedError("CIRCUIT_OPEN", "circuit open")             //熔断器打开，暂停申请号段
+	ErrInvalidResponse  = codedError("INVALID_RESPONSE", "invalid response")     //号段服务返回的响应无法转换为号段
+	ErrSequenceOverflow = codedError("SEQUENCE_OVERFLOW", "sequence overflow")   //序号到达 int64 上限
+	ErrClosed           = codedError("CLOSED", "generator closed")               //生成器已关闭
+	ErrNotDecodable     = codedError("NOT_DECODABLE", "id not decodable")        //UUID 输出模式下 id 是单向生成的，无法解析
+	ErrInvalidShard     = codedError("INVALID_SHARD", "invalid shard char")      //分片函数返回的字符不在可用范围内
+	ErrInvalidTypeFlag  = codedError("INVALID_TYPE_FLAG", "invalid type flag")   //类型标识不合法或未开启
+	ErrIdTooLong        = codedError("ID_TOO_LONG", "id too long")               //id 超过 WithMaxIDLength 设置的长度
+	ErrInvalidHandoff   = codedError("INVALID_HANDOFF", "invalid handoff")       //交接的号段与本实例不匹配或不能保证递增
+	ErrBusy             = codedError("BUSY", "generator busy")                   //进行中的生成调用数达到 WithMaxInflight 的上限
+	ErrFutureApplyDate  = codedError("FUTURE_APPLY_DATE", "future apply date")   //号段的申请时间晚于当前时钟，见 RejectFutureDate
+	ErrIdRejected       = codedError("ID_REJECTED", "id rejected")               //WithIDValidator 连续拒绝了生成的 id
+	ErrInvalidState     = codedError("INVALID_STATE", "invalid state")           //二进制号段状态不完整或格式不对
+	ErrStateVersion     = codedError("STATE_VERSION", "state version")           //二进制号段状态的格式版本不受支持
+	ErrCallerPanic      = codedError("CALLER_PANIC", "caller panic")             //号段申请函数 panic，已恢复
+	ErrIdMapFailed      = codedError("ID_MAP_FAILED", "id map failed")           //序号中的字符不在数字映射中
+	ErrRangeExhausted   = codedError("RANGE_EXHAUSTED", "range exhausted")       //申请到的号段已被用完或不能保证递增，无法使用
+	ErrCallerFailed     = codedError("CALLER_FAILED", "numbers caller failed")   //号段申请函数返回了错误，原始错误可以通过 errors.Is、errors.As 取得
+	ErrNotInitialized   = codedError("NOT_INITIALIZED", "not initialized")       //生成器没有设置号段申请函数，或调用包级函数之前没有 Init
+	ErrChecksumMismatch = codedError("CHECKSUM_MISMATCH", "checksum mismatch")   //id 末尾的校验字符不一致，id 可能输错了
+	ErrAppNameMismatch  = codedError("APP_NAME_MISMATCH", "app name mismatch")   //生成调用传入的应用名与实例确定的应用名不一致，见 WithAppName
+
+	ErrSegmentUnavailable = codedError("SEGMENT_UNAVAILABLE", "segment unavailable") //没有可用号段且配置了不降级，见 ErrorFallback
+
+	ErrFallbackRateExceeded = codedError("FALLBACK_RATE_EXCEEDED", "fallback rate exceeded") //最近一分钟降级 id 数超过上限
+)
+
+// ErrorCode 返回 err 链上最外层的错误码，不是本包的错误时返回空字符串
+func ErrorCode(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ""
+}
+
+// wrappedError 以 kind 作为错误类型包装原始错误：errors.Is(err, kind) 成立，同时 cause 仍可以通过 errors.Is、errors.As 取得
+type wrappedError struct {
+	kind  *Error
+	cause error
+}
+
+func wrapError(kind *Error, cause error) error {
+	return &wrappedError{kind: kind, cause: cause}
+}
+
+func (w *wrappedError) Error() string {
+	return w.kind.Message + ": " + w.cause.Error()
+}
+
+func (w *wrappedError) Unwrap() error {
+	return w.cause
+}
+
+func (w *wrappedError) Is(target error) bool {
+	return target == w.kind
+}
+
+func (w *wrappedError) As(target any) bool {
+	if t, ok := target.(**Error); ok {
+		*t = w.kind
+		return true
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package generator
+
+import (
+	"expvar"
+	"sync/atomic"
+)
+
+// publishExpvar 将运行计数发布到 expvar，可通过 /debug/vars 查看，读取时不加锁
+func (usage *RangeUsageInfoStruct) publishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(usage.expvarSnapshot))
+}
+
+func (usage *RangeUsageInfoStruct) expvarSnapshot() any {
+	start := atomic.LoadInt64(&usage.currentRangeStart)
+	maxId := atomic.LoadInt64(&usage.currentMaxId)
+	end := atomic.LoadInt64(&usage.currentRangeEnd)
+	var utilization float64
+	if end >= start && start > 0 {
+		utilization = float64(maxId-start+1) / float64(end-start+1)
+	}
+	return map[string]any{
+		"generated":     atomic.LoadInt64(&usage.counters.generated),
+		"fallbacks":     atomic.LoadInt64(&usage.counters.fallbacks),
+		"rangeRequests": atomic.LoadInt64(&usage.counters.rangeRequests),
+		"rangeErrors":   atomic.LoadInt64(&usage.counters.rangeErrors),
+		"utilization":   utilization,
+	}
+}
@@ -0,0 +1,59 @@
+package generator
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+)
+
+func TestExpvarCounters(t *testing.T) {
+	caller := newMemCaller()
+	usage, err := NewWithOptions(caller.apply, testOptions(WithStep(10), WithExpvar("generator_test_counters"))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	read := func() map[string]float64 {
+		t.Helper()
+		v := expvar.Get("generator_test_counters")
+		if v == nil {
+			t.Fatal("counters are not published")
+		}
+		var counters map[string]float64
+		if err := json.Unmarshal([]byte(v.String()), &counters); err != nil {
+			t.Fatal(err)
+		}
+		return counters
+	}
+	if c := read(); c["generated"] != 0 || c["rangeRequests"] != 0 {
+		t.Fatalf("counters before generating %v", c)
+	}
+	for i := 0; i < 4; i++ {
+		if _, err := usage.GenerateId("app"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := read()
+	if c["generated"] != 4 || c["rangeRequests"] != 1 || c["fallbacks"] != 0 {
+		t.Fatalf("counters after 4 ids %v", c)
+	}
+	if c["utilization"] != 0.4 {
+		t.Fatalf("utilization %v after 4 of 10 ids, want 0.4", c["utilization"])
+	}
+
+	//号段服务故障后用完当前号段，之后的 id 为降级 id
+	caller.setFail(true)
+	for i := 0; i < 10; i++ {
+		if _, err := usage.GenerateId("app"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats := usage.Stats()
+	if stats.Fallbacks == 0 {
+		t.Fatal("no fallback ids after the range ran out")
+	}
+	c = read()
+	if c["generated"] != 14 || c["fallbacks"] != float64(stats.Fallbacks) || c["rangeErrors"] != float64(stats.RangeErrors) || c["rangeErrors"] == 0 {
+		t.Fatalf("counters after fallbacks %v, stats %+v", c, stats)
+	}
+}
@@ -0,0 +1,123 @@
+package generator
+
+import (
+	crand "crypto/rand"
+	"sync"
+	"time"
+)
+
+const (
+	constSnowflakeWorkerBits = 10
+	constSnowflakeSeqBits    = 12
+	constSnowflakeMaxSeq     = 1<<constSnowflakeSeqBits - 1
+	constSnowflakeWidth      = 14 //63 位的雪花号按 26 进制定长编码的位数，26^14 > 2^63，定长保证按字符串排序即按时间排序
+
+	constCrockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
+
+// snowflakeEpoch 雪花降级后缀的时间起点
+var snowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// RandomFallback 默认的降级后缀：Y + 3 位实例标识 + 8 位随机大写字母，与不设置 WithFallbackGenerator 相同
+func RandomFallback() FallbackGenerator {
+	return randFallbackGenerator{}
+}
+
+// ErrorFallback 不生成降级 id：号段用完且申请失败时生成调用返回 ErrSegmentUnavailable，
+// 适用于不能接受 id 格式变化、宁可失败重试的业务
+func ErrorFallback() FallbackGenerator {
+	return errorFallback{}
+}
+
+// errorFallback 只用于标记不降级，生成器在降级之前识别并返回错误，不会调用 Generate
+type errorFallback struct{}
+
+func (errorFallback) Generate(string, Clock) string {
+	return ""
+}
+
+// SnowflakeFallback 按雪花算法生成可排序的降级后缀：Y + 14 位大写字母，编码 毫秒时间戳（自 2020-01-01）、
+// workerID 的低 10 位和 12 位毫秒内序号；同一实例内严格递增，不同实例之间按时间排序，
+// 多实例同时降级时需要为每个实例分配不同的 workerID（0-1023）才能保证不重复
+func SnowflakeFallback(workerID int) FallbackGenerator {
+	return &snowflakeFallback{worker: int64(workerID) & (1<<constSnowflakeWorkerBits - 1)}
+}
+
+type snowflakeFallback struct {
+	m      sync.Mutex
+	worker int64
+	lastMs int64
+	seq    int64
+}
+
+func (g *snowflakeFallback) Generate(_ string, clock Clock) string {
+	g.m.Lock()
+	ms := clock.Now().Sub(snowflakeEpoch).Milliseconds()
+	if ms < 0 {
+		ms = 0
+	}
+	if ms < g.lastMs {
+		ms = g.lastMs //时钟回拨时沿用上次的时间，保持递增
+	}
+	if ms == g.lastMs {
+		g.seq++
+		if g.seq > constSnowflakeMaxSeq {
+			//同一毫秒内序号用完，借用下一毫秒
+			ms++
+			g.seq = 0
+		}
+	} else {
+		g.seq = 0
+	}
+	g.lastMs = ms
+	n := ms<<(constSnowflakeWorkerBits+constSnowflakeSeqBits) | g.worker<<constSnowflakeSeqBits | g.seq
+	g.m.Unlock()
+
+	suffix := make([]byte, constSnowflakeWidth+1)
+	suffix[0] = constFallbackMarker
+	for i := constSnowflakeWidth; i > 0; i-- {
+		suffix[i] = byte('A' + n%26)
+		n /= 26
+	}
+	return string(suffix)
+}
+
+// ULIDFallback 按 ULID 生成可排序的降级后缀：Y + 26 位 Crockford base32，前 10 位为毫秒时间戳、后 16 位为 80 位随机数，
+// 不依赖实例标识，不同实例同时降级时也可以认为不会重复；同一毫秒内的后缀之间不保证顺序
+func ULIDFallback() FallbackGenerator {
+	return ulidFallback{}
+}
+
+type ulidFallback struct{}
+
+func (ulidFallback) Generate(_ string, clock Clock) string {
+	var entropy [10]byte
+	if _, err := crand.Read(entropy[:]); err != nil {
+		//系统随机数不可用时退回时间做种子，唯一性降低但不影响生成
+		ns := clock.Now().UnixNano()
+		for i := range entropy {
+			entropy[i] = byte(ns >> (8 * (i % 8)))
+		}
+	}
+	ms := uint64(clock.Now().UnixMilli())
+	suffix := make([]byte, 27)
+	suffix[0] = constFallbackMarker
+	for i := 10; i > 0; i-- {
+		suffix[i] = constCrockfordAlphabet[ms&31]
+		ms >>= 5
+	}
+	//80 位随机数按 5 位一组编码为 16 个字符
+	var bits uint64
+	var nbits uint
+	pos := 11
+	for _, b := range entropy {
+		bits = bits<<8 | uint64(b)
+		nbits += 8
+		for nbits >= 5 {
+			nbits -= 5
+			suffix[pos] = constCrockfordAlphabet[(bits>>nbits)&31]
+			pos++
+		}
+	}
+	return string(suffix)
+}
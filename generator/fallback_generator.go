@@ -0,0 +1,36 @@
+package generator
+
+import "strings"
+
+// FallbackGenerator 生成降级 id 中代替序号的唯一后缀，hostKey 为当前实例的主机标识，clock 为 WithClock 设置的时钟；
+// 何时降级、降级限流和拼接前缀日期仍由生成器处理，实现需要自行保证多实例同时降级时后缀不重复，并且可以被并发调用
+type FallbackGenerator interface {
+	Generate(hostKey string, clock Clock) string
+}
+
+// randFallbackGenerator 默认的降级后缀：Y + 3 位实例标识 + 8 位随机大写字母，见 randId
+type randFallbackGenerator struct {
+	usage *RangeUsageInfoStruct
+}
+
+func (g randFallbackGenerator) Generate(string, Clock) string {
+	return g.usage.randId()
+}
+
+// fallbackSuffix 按 WithFallbackGenerator 设置的算法生成降级后缀，后缀不以降级标记 Y 开头时补上，使 Parse 能识别降级 id、也不会与正常 id 重复；
+// 后缀为空或含有分隔符 - 时改用默认算法
+func (usage *RangeUsageInfoStruct) fallbackSuffix() string {
+	gen := usage.cfg.fallbackGen
+	if r, ok := gen.(randFallbackGenerator); gen == nil || (ok && r.usage == nil) {
+		gen = randFallbackGenerator{usage: usage}
+	}
+	suffix := gen.Generate(usage.hostKey, usage.cfg.clock)
+	if suffix == "" || strings.IndexByte(suffix, '-') >= 0 {
+		usage.logs.Error("{} {} {} 自定义降级后缀不合法 {}，改用默认算法", usage.appName, usage.bizType, usage.prefix, suffix)
+		return usage.randId()
+	}
+	if suffix[0] != constFallbackMarker {
+		suffix = string(constFallbackMarker) + suffix
+	}
+	return suffix
+}
@@ -0,0 +1,91 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sequentialFallback 确定性的降级后缀：主机标识 + 时钟的时分秒 + 递增计数
+type sequentialFallback struct {
+	m sync.Mutex
+	n int
+}
+
+func (g *sequentialFallback) Generate(hostKey string, clock Clock) string {
+	g.m.Lock()
+	defer g.m.Unlock()
+	g.n++
+	return fmt.Sprintf("%s%s%04d", hostKey, clock.Now().Format("150405"), g.n)
+}
+
+// dashFallback 返回带分隔符的不合法后缀
+type dashFallback struct{}
+
+func (dashFallback) Generate(string, Clock) string {
+	return "BAD-SUFFIX"
+}
+
+func newFallbackUsage(t *testing.T, gen FallbackGenerator) (*RangeUsageInfoStruct, *fakeClock) {
+	t.Helper()
+	caller := newMemCaller()
+	caller.setFail(true)
+	clock := newFakeClock(time.Date(2024, 1, 1, 10, 30, 0, 0, time.Local))
+	usage, err := NewWithOptions(caller.apply, testOptions(WithClock(clock), WithHostKey("H7"), WithFallbackGenerator(gen))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return usage, clock
+}
+
+// TestCustomFallbackGenerator 号段服务不可用时使用自定义算法生成降级后缀，补上降级标记后 Parse 仍能识别
+func TestCustomFallbackGenerator(t *testing.T) {
+	gen := &sequentialFallback{}
+	usage, clock := newFallbackUsage(t, gen)
+	defer usage.Close()
+	for i, want := range []string{"T-20240101YH71030000001", "T-20240101YH71030000002"} {
+		id, err := usage.GenerateId("app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != want {
+			t.Fatalf("fallback %d = %s, want %s", i, id, want)
+		}
+		if parts, err := usage.Parse(id); err != nil || !parts.Fallback {
+			t.Fatalf("Parse(%s) = %+v, %v, want a fallback id", id, parts, err)
+		}
+	}
+	clock.Add(time.Minute)
+	if id, err := usage.GenerateId("app"); err != nil || id != "T-20240101YH71031000003" {
+		t.Fatalf("fallback after a minute = %s, %v, want the configured clock", id, err)
+	}
+	if s := usage.Stats(); s.Fallbacks != 3 {
+		t.Fatalf("Fallbacks %d, want 3", s.Fallbacks)
+	}
+}
+
+func TestCustomFallbackGeneratorInvalid(t *testing.T) {
+	usage, _ := newFallbackUsage(t, dashFallback{})
+	defer usage.Close()
+	id, err := usage.GenerateId("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(id, "BAD") {
+		t.Fatalf("invalid custom suffix used in %s", id)
+	}
+	if parts, err := usage.Parse(id); err != nil || !parts.Fallback || parts.InstanceTag != usage.fallbackTag {
+		t.Fatalf("Parse(%s) = %+v, %v, want the default fallback", id, parts, err)
+	}
+}
+
+func TestErrorFallback(t *testing.T) {
+	usage, _ := newFallbackUsage(t, ErrorFallback())
+	defer usage.Close()
+	if id, err := usage.GenerateId("app"); !errors.Is(err, ErrSegmentUnavailable) {
+		t.Fatalf("got %s, %v, want ErrSegmentUnavailable", id, err)
+	}
+}
@@ -0,0 +1,33 @@
+package generator
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+const constFallbackGracePoll = 5 * time.Millisecond //降级宽限期内检查号段申请是否完成的间隔
+
+// waitFallbackGrace 降级之前在宽限期内等待：有号段申请在进行时等它完成，没有时等待一个检查间隔后重新取号或申请，
+// 返回 false 表示宽限期已过，需要降级；调用方的 ctx 取消或超时时也返回 false，由调用方返回 ctx 的错误
+func (usage *RangeUsageInfoStruct) waitFallbackGrace(ctx context.Context, deadline time.Time) bool {
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return false
+		}
+		if remaining > constFallbackGracePoll {
+			remaining = constFallbackGracePoll
+		}
+		timer := time.NewTimer(remaining)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+		if atomic.LoadInt32(&usage.gettingIdRangeCounter) == 0 {
+			return time.Now().Before(deadline)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package generator
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// blipCaller 在第一次申请之后的 outage 时间内返回错误，模拟号段服务短暂不可用
+type blipCaller struct {
+	*memCaller
+	outage time.Duration
+	m      sync.Mutex
+	first  time.Time
+}
+
+func (c *blipCaller) apply(req *ApplyReq) (*NewRangeResp, error) {
+	c.m.Lock()
+	if c.first.IsZero() {
+		c.first = time.Now()
+	}
+	down := time.Since(c.first) < c.outage
+	c.m.Unlock()
+	if down {
+		return nil, errDown
+	}
+	return c.memCaller.apply(req)
+}
+
+func TestFallbackGrace(t *testing.T) {
+	cases := []struct {
+		name         string
+		outage       time.Duration
+		grace        time.Duration
+		wantFallback bool
+	}{
+		{"recovers within grace", 30 * time.Millisecond, 300 * time.Millisecond, false},
+		{"outage outlasts grace", time.Hour, 40 * time.Millisecond, true},
+		{"no grace", 30 * time.Millisecond, 0, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			caller := &blipCaller{memCaller: newMemCaller(), outage: tc.outage}
+			usage, err := NewWithOptions(caller.apply, testOptions(WithFallbackGrace(tc.grace))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer usage.Close()
+			start := time.Now()
+			id, err := usage.GenerateId("app")
+			if err != nil {
+				t.Fatal(err)
+			}
+			took := time.Since(start)
+			parts, err := usage.Parse(id)
+			if err != nil || parts.Fallback != tc.wantFallback {
+				t.Fatalf("id %s parsed to %+v (%v), want fallback %v", id, parts, err, tc.wantFallback)
+			}
+			if !tc.wantFallback && (took < tc.outage || took >= tc.grace) {
+				t.Fatalf("real id after %s, want after the %s outage and within the %s grace", took, tc.outage, tc.grace)
+			}
+			if tc.wantFallback && took < tc.grace {
+				t.Fatalf("fallback after %s, before the %s grace expired", took, tc.grace)
+			}
+		})
+	}
+}
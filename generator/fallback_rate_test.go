@@ -0,0 +1,41 @@
+package generator
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMaxFallbackRate(t *testing.T) {
+	const limit = 5
+	caller := newMemCaller()
+	caller.setFail(true)
+	clock := newFakeClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local))
+	usage, err := NewWithOptions(caller.apply, testOptions(WithClock(clock), WithStep(10), WithMaxFallbackRate(limit))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	for i := 0; i < limit; i++ {
+		if _, err := usage.GenerateId("app"); err != nil {
+			t.Fatalf("fallback %d within the limit: %v", i+1, err)
+		}
+	}
+	if stats := usage.Stats(); stats.FallbackRate != limit {
+		t.Fatalf("FallbackRate %d, want %d", stats.FallbackRate, limit)
+	}
+	for i := 0; i < 3; i++ {
+		if id, err := usage.GenerateId("app"); !errors.Is(err, ErrFallbackRateExceeded) {
+			t.Fatalf("GenerateId past the fallback limit %q, %v, want ErrFallbackRateExceeded", id, err)
+		}
+	}
+	if stats := usage.Stats(); stats.Fallbacks != limit {
+		t.Fatalf("Fallbacks %d after rejections, want %d", stats.Fallbacks, limit)
+	}
+
+	//一分钟之后窗口内的降级 id 过期，重新允许降级
+	clock.Add(time.Minute + time.Second)
+	if _, err := usage.GenerateId("app"); err != nil {
+		t.Fatalf("fallback after the window passed: %v", err)
+	}
+}
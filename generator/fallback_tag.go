@@ -0,0 +1,29 @@
+package generator
+
+import (
+	"hash/fnv"
+	"strconv"
+	"time"
+)
+
+const constFallbackTagLen = 3 //降级 id 中实例标识的长度
+
+// newFallbackTag 计算降级 id 中 3 位大写字母的实例标识
+// 原来直接用 hostKey（IP 最后一段）的前 3 个字符，同一台机器上的多个实例标识完全相同，不同机器也容易重复，
+// 现在对 hostKey、进程号和启动时间做哈希，同一台机器上的不同进程、重启前后的同一进程都会得到不同的标识（26^3 种），
+// 与 8 位随机后缀一起降低多实例同时降级时的碰撞概率，id 的长度不变
+func newFallbackTag(hostKey string, pid int, start time.Time) string {
+	h := fnv.New32a()
+	h.Write([]byte(hostKey))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.Itoa(pid)))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.FormatInt(start.UnixNano(), 10)))
+	sum := h.Sum32()
+	tag := make([]byte, constFallbackTagLen)
+	for i := range tag {
+		tag[i] = byte('A' + sum%26)
+		sum /= 26
+	}
+	return string(tag)
+}
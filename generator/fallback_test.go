@@ -0,0 +1,65 @@
+package generator
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// 同一台机器上的两个实例降级时生成的随机 id 不应相互冲突：
+// 即使随机数种子相同（同一纳秒启动），进程号和启动时间计算出的实例标识也应把两者隔开
+func TestFallbackCollisionSameHost(t *testing.T) {
+	const perInstance = 50000
+	const maxRate = 1e-5
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.Local)
+	cases := []struct {
+		name  string
+		seeds [2]int64
+	}{
+		{"same seed", [2]int64{1, 1}},
+		{"different seeds", [2]int64{1, 2}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			caller := newMemCaller()
+			caller.setFail(true)
+			var usages [2]*RangeUsageInfoStruct
+			for i := range usages {
+				usage, err := NewWithOptions(caller.apply, testOptions(
+					WithHostKey("same-host"),
+					WithRand(rand.New(rand.NewSource(tc.seeds[i]))),
+				)...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer usage.Close()
+				usage.fallbackTag = newFallbackTag("same-host", 4000+i, start.Add(time.Duration(i)*time.Millisecond))
+				usages[i] = usage
+			}
+			if usages[0].fallbackTag == usages[1].fallbackTag {
+				t.Fatalf("instances with different pid and start time share fallback tag %s", usages[0].fallbackTag)
+			}
+
+			seen := make(map[string]int, 2*perInstance)
+			collisions := 0
+			for n := 0; n < perInstance; n++ {
+				for i, usage := range usages {
+					id, err := usage.GenerateId("app")
+					if err != nil {
+						t.Fatal(err)
+					}
+					if owner, ok := seen[id]; ok && owner != i {
+						collisions++
+					}
+					seen[id] = i
+				}
+			}
+			if stats := usages[0].Stats(); stats.Fallbacks != perInstance {
+				t.Fatalf("Fallbacks %d, want %d", stats.Fallbacks, perInstance)
+			}
+			if rate := float64(collisions) / float64(2*perInstance); rate > maxRate {
+				t.Fatalf("%d cross-instance collisions in %d fallback ids, rate %g above %g", collisions, 2*perInstance, rate, maxRate)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package generator
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var errDown = errors.New("numbers service down")
+
+// memCaller 测试用的进程内号段分配器，按 appName + bizType + day 独立递增
+type memCaller struct {
+	m      sync.Mutex
+	maxIds map[string]int64
+	calls  int
+	fail   bool
+}
+
+func newMemCaller() *memCaller {
+	return &memCaller{maxIds: make(map[string]int64)}
+}
+
+func (c *memCaller) apply(req *ApplyReq) (*NewRangeResp, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.calls++
+	if c.fail {
+		return nil, errDown
+	}
+	key := req.AppName + "|" + req.BizType + "|" + req.Day
+	start := c.maxIds[key] + 1
+	c.maxIds[key] += int64(req.Step)
+	return &NewRangeResp{RangeStart: start, RangeEnd: c.maxIds[key]}, nil
+}
+
+func (c *memCaller) setFail(fail bool) {
+	c.m.Lock()
+	c.fail = fail
+	c.m.Unlock()
+}
+
+func (c *memCaller) callCount() int {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.calls
+}
+
+// fakeClock 测试用的可调时钟
+type fakeClock struct {
+	m   sync.Mutex
+	now time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.m.Lock()
+	c.now = c.now.Add(d)
+	c.m.Unlock()
+}
+
+// testOptions 测试公用的选项，opts 追加在后面可以覆盖
+func testOptions(opts ...Option) []Option {
+	return append([]Option{WithAppName("app"), WithPrefix("T")}, opts...)
+}
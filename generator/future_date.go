@@ -0,0 +1,40 @@
+package generator
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// FutureDatePolicy 号段的申请时间（applyDate）晚于当前时钟超过容忍范围时的处理策略，
+// 通常由时钟回拨、其它实例交接（ImportRange）或恢复（StateStore）了时钟偏快时申请的号段导致
+type FutureDatePolicy int
+
+const (
+	RefreshFutureDate FutureDatePolicy = iota //默认，不再信任该号段：跨周期时按当前时钟申请新号段，同一周期内把申请时间校正为当前时间
+	RejectFutureDate                          //返回 ErrFutureApplyDate，直到时钟追上申请时间或号段被替换
+)
+
+const constFutureDateTolerance = time.Minute //默认的容忍范围，避免并发取号时先取到时间的协程误判
+
+func (p FutureDatePolicy) valid() bool {
+	return p == RefreshFutureDate || p == RejectFutureDate
+}
+
+// checkFutureDateLocked 检查申请时间是否晚于 now 超过容忍范围，按策略校正申请时间或返回错误，调用方需持有 usageM
+func (usage *RangeUsageInfoStruct) checkFutureDateLocked(now time.Time) error {
+	ahead := usage.applyDate.Sub(now)
+	if ahead <= usage.cfg.futureDateTolerance {
+		return nil
+	}
+	atomic.AddInt64(&usage.counters.futureDates, 1)
+	if usage.cfg.futureDatePolicy == RejectFutureDate {
+		usage.logs.Error("{} {} {} 号段申请时间 {} 晚于当前时间 {}，拒绝生成 id", usage.appName, usage.bizType, usage.prefix, usage.applyDate, now)
+		return fmt.Errorf("%w: apply date %s is %v ahead of clock", ErrFutureApplyDate, usage.applyDate.Format(time.RFC3339), ahead)
+	}
+	usage.logs.Warn("{} {} {} 号段申请时间 {} 晚于当前时间 {}，不再信任该号段", usage.appName, usage.bizType, usage.prefix, usage.applyDate, now)
+	if usage.samePeriod(now, usage.applyDate) {
+		usage.applyDate = now
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package generator
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newFutureDateUsage(t *testing.T, opts ...Option) (*RangeUsageInfoStruct, *memCaller, *fakeClock) {
+	t.Helper()
+	caller := newMemCaller()
+	clock := newFakeClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local))
+	usage, err := NewWithOptions(caller.apply, testOptions(append(opts, WithClock(clock), WithStep(100))...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := usage.GenerateId("app"); err != nil {
+		t.Fatal(err)
+	}
+	return usage, caller, clock
+}
+
+func setApplyDate(usage *RangeUsageInfoStruct, at time.Time) {
+	usage.usageM.Lock()
+	usage.applyDate = at
+	usage.usageM.Unlock()
+}
+
+// TestFutureApplyDateRefresh 申请时间比时钟晚一天时按当前时钟重新申请号段，id 使用当前日期
+func TestFutureApplyDateRefresh(t *testing.T) {
+	usage, caller, clock := newFutureDateUsage(t)
+	defer usage.Close()
+	setApplyDate(usage, clock.Now().Add(24*time.Hour))
+	calls := caller.callCount()
+	id, err := usage.GenerateId("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts := mustParse(t, usage, id); parts.Fallback || parts.Day != "20240101" || parts.Sequence != 101 {
+		t.Fatalf("id %s after a future apply date parsed to %+v, want the first number of a new 20240101 range", id, parts)
+	}
+	if caller.callCount() != calls+1 {
+		t.Fatalf("%d range requests, want a refresh", caller.callCount()-calls)
+	}
+	if s := usage.Stats(); s.FutureApplyDates != 1 {
+		t.Fatalf("FutureApplyDates %d, want 1", s.FutureApplyDates)
+	}
+}
+
+// TestFutureApplyDateSamePeriod 同一周期内的未来申请时间校正为当前时间，继续使用当前号段
+func TestFutureApplyDateSamePeriod(t *testing.T) {
+	usage, caller, clock := newFutureDateUsage(t)
+	defer usage.Close()
+	setApplyDate(usage, clock.Now().Add(2*time.Hour))
+	calls := caller.callCount()
+	id, err := usage.GenerateId("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts := mustParse(t, usage, id); parts.Sequence != 2 {
+		t.Fatalf("id %s parsed to %+v, want sequence 2 from the current range", id, parts)
+	}
+	usage.usageM.Lock()
+	corrected := usage.applyDate.Equal(clock.Now())
+	usage.usageM.Unlock()
+	if !corrected || caller.callCount() != calls {
+		t.Fatalf("apply date corrected %v, %d range requests, want corrected with none", corrected, caller.callCount()-calls)
+	}
+	if s := usage.Stats(); s.FutureApplyDates != 1 {
+		t.Fatalf("FutureApplyDates %d, want 1", s.FutureApplyDates)
+	}
+
+	//容忍范围内不处理
+	setApplyDate(usage, clock.Now().Add(30*time.Second))
+	if _, err := usage.GenerateId("app"); err != nil {
+		t.Fatal(err)
+	}
+	if s := usage.Stats(); s.FutureApplyDates != 1 {
+		t.Fatalf("FutureApplyDates %d within tolerance, want 1", s.FutureApplyDates)
+	}
+}
+
+func TestFutureApplyDateReject(t *testing.T) {
+	usage, _, clock := newFutureDateUsage(t, WithFutureDatePolicy(RejectFutureDate, time.Minute))
+	defer usage.Close()
+	setApplyDate(usage, clock.Now().Add(24*time.Hour))
+	if _, err := usage.GenerateId("app"); !errors.Is(err, ErrFutureApplyDate) {
+		t.Fatalf("got %v, want ErrFutureApplyDate", err)
+	}
+	if s := usage.Stats(); s.FutureApplyDates != 1 {
+		t.Fatalf("FutureApplyDates %d, want 1", s.FutureApplyDates)
+	}
+	clock.Add(24 * time.Hour)
+	if _, err := usage.GenerateId("app"); err != nil {
+		t.Fatalf("after the clock caught up: %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package generator
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// gapsAcrossRestarts 依次启动 restarts 个实例，每个生成 perRun 个 id 后关闭，返回最大序号与已发放 id 数之差，即空洞的号码数
+func gapsAcrossRestarts(t *testing.T, restarts, perRun int, opts ...Option) int64 {
+	t.Helper()
+	caller := newMemCaller()
+	clock := newFakeClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local))
+	opts = testOptions(append([]Option{WithClock(clock), WithStep(100)}, opts...)...)
+	seen := make(map[int64]bool)
+	var maxSeq int64
+	for run := 0; run < restarts; run++ {
+		usage, err := NewWithOptions(caller.apply, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < perRun; i++ {
+			id, err := usage.GenerateId("app")
+			if err != nil {
+				t.Fatal(err)
+			}
+			parts, err := usage.Parse(id)
+			if err != nil || parts.Fallback {
+				t.Fatalf("id %s is not a range id (%v)", id, err)
+			}
+			if seen[parts.Sequence] {
+				t.Fatalf("sequence %d issued twice across restarts", parts.Sequence)
+			}
+			seen[parts.Sequence] = true
+			if parts.Sequence > maxSeq {
+				maxSeq = parts.Sequence
+			}
+		}
+		if err := usage.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return maxSeq - int64(len(seen))
+}
+
+func TestGapPolicyAcrossRestarts(t *testing.T) {
+	const restarts, perRun = 3, 10
+	//默认策略每次重启都丢弃当前号段未用完的部分
+	if gaps := gapsAcrossRestarts(t, restarts, perRun); gaps != (restarts-1)*(100-perRun) {
+		t.Fatalf("AllowGaps left %d gaps, want %d", gaps, (restarts-1)*(100-perRun))
+	}
+	store := NewFileStateStore(filepath.Join(t.TempDir(), "state.json"))
+	if gaps := gapsAcrossRestarts(t, restarts, perRun, WithGapPolicy(MinimizeGaps), WithStateStore(store)); gaps != 0 {
+		t.Fatalf("MinimizeGaps left %d gaps, want 0", gaps)
+	}
+}
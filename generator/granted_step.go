@@ -0,0 +1,56 @@
+package generator
+
+import "sync/atomic"
+
+// grantedStep 服务端实际分配的步长，没有返回 GrantedStep 或返回值与号段不符时按号段宽度推算
+func grantedStep(resp *NewRangeResp) int64 {
+	width := resp.RangeEnd - resp.RangeStart + 1
+	if resp.GrantedStep <= 0 || resp.GrantedStep > width {
+		return width
+	}
+	return resp.GrantedStep
+}
+
+// recordGrantedStep 记录正常申请号段时服务端实际分配的步长，并据此调整之后申请的步长，见 adaptStep；
+// 争用时单次申请的号段不代表服务端的分配能力，不记录
+func (usage *RangeUsageInfoStruct) recordGrantedStep(req *ApplyReq, resp *NewRangeResp) {
+	if req.Step != usage.step() {
+		return
+	}
+	granted := grantedStep(resp)
+	if granted < int64(req.Step) {
+		usage.logs.Debug("{} {} {} 服务端分配的步长 {} 小于申请的步长 {}", req.AppName, usage.bizType, usage.prefix, granted, req.Step)
+	}
+	atomic.StoreInt64(&usage.counters.grantedStep, granted)
+	if usage.tunables().clientBlockSize == 0 {
+		atomic.StoreInt64(&usage.counters.adaptiveStep, adaptStep(int64(req.Step), granted, int64(usage.tunables().rangeStep())))
+	}
+}
+
+// adaptStep 按服务端实际分配的步长计算下一次申请的步长：分配不足时下次只申请实际分配的数量，
+// 避免服务端压力大时反复申请大号段；足额分配时步长翻倍，逐步恢复到配置的步长 configured
+func adaptStep(requested, granted, configured int64) int64 {
+	next := granted
+	if granted >= requested {
+		next = requested * 2
+	}
+	if next > configured {
+		next = configured
+	}
+	if next < 1 {
+		next = 1
+	}
+	return next
+}
+
+// grantedThreshold 按服务端实际分配的步长计算申请新号段的阈值，不超过配置的阈值 base，至少为 1
+func grantedThreshold(granted int64, base int64) int64 {
+	threshold := base
+	if granted > 0 && granted/2 < threshold {
+		threshold = granted / 2
+	}
+	if threshold < 1 {
+		threshold = 1
+	}
+	return threshold
+}
@@ -0,0 +1,131 @@
+package generator
+
+import (
+	"sync"
+	"testing"
+)
+
+// cappedCaller 每次最多分配 limit 个号码，limit 为 0 时足额分配，记录每次申请的步长
+type cappedCaller struct {
+	*memCaller
+	m     sync.Mutex
+	limit int
+	steps []int
+}
+
+func (c *cappedCaller) apply(req *ApplyReq) (*NewRangeResp, error) {
+	c.m.Lock()
+	c.steps = append(c.steps, req.Step)
+	limit := c.limit
+	c.m.Unlock()
+	granted := *req
+	if limit > 0 && granted.Step > limit {
+		granted.Step = limit
+	}
+	return c.memCaller.apply(&granted)
+}
+
+func (c *cappedCaller) setLimit(limit int) {
+	c.m.Lock()
+	c.limit = limit
+	c.m.Unlock()
+}
+
+func (c *cappedCaller) requestedSteps() []int {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return append([]int(nil), c.steps...)
+}
+
+func (c *cappedCaller) lastStep() int {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.steps[len(c.steps)-1]
+}
+
+func TestAdaptStep(t *testing.T) {
+	tests := []struct {
+		name                           string
+		requested, granted, configured int64
+		want                           int64
+	}{
+		{"granted less", 100, 30, 100, 30},
+		{"granted in full doubles", 30, 30, 100, 60},
+		{"capped at configured", 60, 60, 100, 100},
+		{"configured", 100, 100, 100, 100},
+		{"at least one", 100, 0, 100, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := adaptStep(tt.requested, tt.granted, tt.configured); got != tt.want {
+				t.Fatalf("adaptStep(%d, %d, %d) = %d, want %d", tt.requested, tt.granted, tt.configured, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestedStepFollowsGrantedStep(t *testing.T) {
+	caller := &cappedCaller{memCaller: newMemCaller(), limit: 10}
+	usage, err := NewWithOptions(caller.apply, testOptions(WithStep(40))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+
+	for i := 0; i < 50; i++ {
+		if _, err := usage.GenerateId("app"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	//第一次按配置的步长申请，之后按实际分配的步长申请，足额分配时翻倍试探
+	steps := caller.requestedSteps()
+	if steps[0] != 40 {
+		t.Fatalf("first requested step = %d, want 40", steps[0])
+	}
+	for i, step := range steps[1:] {
+		if step != 10 && step != 20 {
+			t.Fatalf("requested step %d under a capped service = %d, want 10 or 20", i+1, step)
+		}
+	}
+	if stats := usage.Stats(); stats.LastGrantedStep != 10 || stats.NextStep > 20 {
+		t.Fatalf("LastGrantedStep = %d, NextStep = %d, want 10, at most 20", stats.LastGrantedStep, stats.NextStep)
+	}
+
+	//服务端恢复足额分配后逐步恢复到配置的步长
+	caller.setLimit(0)
+	for i := 0; i < 200 && usage.Stats().NextStep < 40; i++ {
+		if _, err := usage.GenerateId("app"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stats := usage.Stats(); stats.NextStep != 40 {
+		t.Fatalf("NextStep after recovery = %d, want 40", stats.NextStep)
+	}
+	for i := 0; i < 100 && caller.lastStep() != 40; i++ {
+		if _, err := usage.GenerateId("app"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := caller.lastStep(); got != 40 {
+		t.Fatalf("requested step after recovery = %d, want 40", got)
+	}
+}
+
+func TestGrantedStepInferredFromWidth(t *testing.T) {
+	tests := []struct {
+		name string
+		resp NewRangeResp
+		want int64
+	}{
+		{"granted step set", NewRangeResp{RangeStart: 1, RangeEnd: 100, GrantedStep: 100}, 100},
+		{"zero uses width", NewRangeResp{RangeStart: 11, RangeEnd: 30}, 20},
+		{"larger than width uses width", NewRangeResp{RangeStart: 1, RangeEnd: 10, GrantedStep: 50}, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := grantedStep(&tt.resp); got != tt.want {
+				t.Fatalf("grantedStep = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
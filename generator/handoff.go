@@ -0,0 +1,85 @@
+package generator
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// RangeHandoff 蓝绿发布时从下线实例交接给上线实例的未用完号段，号码范围为 [RangeStart, RangeEnd]
+type RangeHandoff struct {
+	AppName    string `json:"appName"`
+	BizType    string `json:"bizType"`
+	ApplyDay   string `json:"applyDay"` //申请号段时的本地日期（周期标识）
+	RangeDay   string `json:"rangeDay"` //号段所属日期，即 id 中的日期
+	RangeStart int64  `json:"rangeStart"`
+	RangeEnd   int64  `json:"rangeEnd"`
+}
+
+// ExportRange 导出当前号段中尚未使用的号码并立即从本实例移除，本实例之后需要时会重新申请号段；
+// 没有可导出的号码（或开启了静态节点模式）时返回 false
+// 交接需要调用方协调，保证同一份号段只被使用一次：
+// 1. 导出后的号段只能交给一个实例 ImportRange，导入失败时直接丢弃，不要再导入其它实例或重试多次；
+// 2. 导出与导入应在同一天内完成，跨天后导入会被拒绝；
+// 3. 下线实例导出后仍可以继续生成 id，但会使用新申请的号段，不会再使用导出的号码
+func (usage *RangeUsageInfoStruct) ExportRange() (RangeHandoff, bool) {
+	if usage.cfg.staticNodes > 0 {
+		return RangeHandoff{}, false
+	}
+	usage.usageM.Lock()
+	defer usage.usageM.Unlock()
+	if usage.currentMaxId >= usage.currentRangeEnd {
+		return RangeHandoff{}, false
+	}
+	h := RangeHandoff{
+		AppName:    usage.appName,
+		BizType:    usage.bizType,
+		ApplyDay:   usage.periodKey(usage.applyDate),
+		RangeDay:   usage.rangeDay,
+		RangeStart: usage.currentMaxId + 1,
+		RangeEnd:   usage.currentRangeEnd,
+	}
+	atomic.StoreInt64(&usage.currentRangeEnd, usage.currentMaxId)
+	usage.logs.Info("{} {} {} 导出未用完的号段 {} {} {}", usage.appName, usage.bizType, usage.prefix, h.RangeStart, h.RangeEnd, h.RangeDay)
+	return h, true
+}
+
+// ImportRange 接收其它实例通过 ExportRange 导出的号段并作为当前号段使用，校验 bizType、appName、日期，
+// 同一天内号段必须大于本实例已用的号码；本实例当前号段还有剩余号码时拒绝导入，避免丢弃已有的号段
+func (usage *RangeUsageInfoStruct) ImportRange(h RangeHandoff) error {
+	if usage.cfg.staticNodes > 0 {
+		return fmt.Errorf("%w: static node mode does not use ranges", ErrInvalidHandoff)
+	}
+	if h.RangeStart <= 0 || h.RangeEnd < h.RangeStart {
+		return fmt.Errorf("%w: range %d %d", ErrInvalidHandoff, h.RangeStart, h.RangeEnd)
+	}
+	if h.BizType != usage.bizType {
+		return fmt.Errorf("%w: biz type %s, want %s", ErrInvalidHandoff, h.BizType, usage.bizType)
+	}
+	now := usage.cfg.clock.Now()
+	if h.ApplyDay != usage.periodKey(now) {
+		return fmt.Errorf("%w: handoff day %s, today %s", ErrInvalidHandoff, h.ApplyDay, usage.periodKey(now))
+	}
+	defer usage.flushDayEvents()
+	usage.usageM.Lock()
+	defer usage.usageM.Unlock()
+	if h.AppName != usage.appName {
+		return fmt.Errorf("%w: app name %s, want %s", ErrInvalidHandoff, h.AppName, usage.appName)
+	}
+	sameDay := usage.samePeriod(usage.applyDate, now) && usage.rangeDay == h.RangeDay
+	if sameDay && usage.currentMaxId < usage.currentRangeEnd {
+		return fmt.Errorf("%w: current range still has %d ids", ErrInvalidHandoff, usage.currentRangeEnd-usage.currentMaxId)
+	}
+	if sameDay && h.RangeStart <= usage.currentMaxId {
+		return fmt.Errorf("%w: range start %d not greater than used id %d", ErrInvalidHandoff, h.RangeStart, usage.currentMaxId)
+	}
+	if !usage.samePeriod(usage.applyDate, now) {
+		usage.rangeQueue = nil
+	}
+	usage.setRangeLocked(h.RangeStart, h.RangeEnd)
+	atomic.StoreInt64(&usage.currentMaxId, h.RangeStart-1)
+	usage.applyDate = now
+	usage.rangeDay = h.RangeDay
+	usage.recordRangeSwitchLocked()
+	usage.logs.Info("{} {} {} 导入交接的号段 {} {} {}", usage.appName, usage.bizType, usage.prefix, h.RangeStart, h.RangeEnd, h.RangeDay)
+	return nil
+}
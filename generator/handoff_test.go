@@ -0,0 +1,110 @@
+package generator
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRangeHandoff(t *testing.T) {
+	caller := newMemCaller()
+	clock := newFakeClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local))
+	opts := testOptions(WithClock(clock), WithStep(100))
+	outgoing, err := NewWithOptions(caller.apply, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer outgoing.Close()
+	incoming, err := NewWithOptions(caller.apply, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer incoming.Close()
+
+	seen := make(map[string]bool)
+	generate := func(usage *RangeUsageInfoStruct, n int) []int64 {
+		seqs := make([]int64, 0, n)
+		for i := 0; i < n; i++ {
+			id, err := usage.GenerateId("app")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if seen[id] {
+				t.Fatalf("%s issued twice", id)
+			}
+			seen[id] = true
+			parts, err := usage.Parse(id)
+			if err != nil || parts.Fallback {
+				t.Fatalf("%s is a fallback id (%v)", id, err)
+			}
+			seqs = append(seqs, parts.Sequence)
+		}
+		return seqs
+	}
+
+	generate(outgoing, 10)
+	h, ok := outgoing.ExportRange()
+	if !ok || h.RangeStart != 11 || h.RangeEnd != 100 {
+		t.Fatalf("exported %+v %v, want 11-100", h, ok)
+	}
+	if _, ok := outgoing.ExportRange(); ok {
+		t.Fatal("exported the same range twice")
+	}
+	if err := incoming.ImportRange(h); err != nil {
+		t.Fatal(err)
+	}
+	if seqs := generate(incoming, 50); seqs[0] != 11 {
+		t.Fatalf("first imported sequence %d, want 11", seqs[0])
+	}
+	//导出后下线实例改用新申请的号段，与交接出去的号段不重叠
+	if seqs := generate(outgoing, 50); seqs[0] <= h.RangeEnd {
+		t.Fatalf("outgoing reused sequence %d of the exported range", seqs[0])
+	}
+	if callers := caller.callCount(); callers < 2 {
+		t.Fatalf("%d range requests, want the outgoing instance to fetch a new range", callers)
+	}
+}
+
+func TestImportRangeRejects(t *testing.T) {
+	valid := RangeHandoff{AppName: "app", BizType: "T", ApplyDay: "20240101", RangeDay: "20240101", RangeStart: 11, RangeEnd: 100}
+	cases := []struct {
+		name   string
+		modify func(h *RangeHandoff)
+	}{
+		{"empty range", func(h *RangeHandoff) { h.RangeEnd = h.RangeStart - 1 }},
+		{"other biz type", func(h *RangeHandoff) { h.BizType = "OTHER" }},
+		{"other app", func(h *RangeHandoff) { h.AppName = "other" }},
+		{"other day", func(h *RangeHandoff) { h.ApplyDay = "20231231" }},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := newFakeClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local))
+			usage, err := NewWithOptions(newMemCaller().apply, testOptions(WithClock(clock))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer usage.Close()
+			h := valid
+			tc.modify(&h)
+			if err := usage.ImportRange(h); !errors.Is(err, ErrInvalidHandoff) {
+				t.Fatalf("ImportRange error %v, want ErrInvalidHandoff", err)
+			}
+		})
+	}
+}
+
+func TestImportRangeKeepsCurrentRange(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local))
+	usage, err := NewWithOptions(newMemCaller().apply, testOptions(WithClock(clock), WithStep(100))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	if _, err := usage.GenerateId("app"); err != nil {
+		t.Fatal(err)
+	}
+	h := RangeHandoff{AppName: "app", BizType: "T", ApplyDay: "20240101", RangeDay: "20240101", RangeStart: 500, RangeEnd: 600}
+	if err := usage.ImportRange(h); !errors.Is(err, ErrInvalidHandoff) {
+		t.Fatalf("ImportRange with ids left error %v, want ErrInvalidHandoff", err)
+	}
+}
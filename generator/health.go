@@ -0,0 +1,169 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const constUnhealthyFailures = 3 //默认连续号段申请失败多少次后进入降级状态
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// healthState 号段申请的健康状态与熔断器
+type healthState struct {
+	m                   sync.Mutex
+	consecutiveFailures int
+	breaker             breakerState
+	breakerOpenedAt     time.Time
+
+	unhealthySince       time.Time //连续失败次数达到阈值的时间
+	breakerSince         time.Time //熔断器从关闭进入打开的时间，半开探测失败不会更新
+	fallbackLimitedSince time.Time //降级 id 数超过上限开始拒绝的时间，再次允许降级时清零
+	capDay               string    //当天序号用完的日期
+	capSince             time.Time
+
+	probeDone  chan struct{} //半开探测进行中时不为 nil，探测结束时关闭
+	recoveries int64         //号段申请从失败恢复正常的次数，atomic 读写，用于判断降级前是否已经恢复
+}
+
+// callNumbers 调用号段申请函数，主号段服务失败且配置了备用申请函数时改用备用服务，都失败才返回错误
+func (usage *RangeUsageInfoStruct) callNumbers(req *ApplyReq) (*NewRangeResp, error) {
+	resp, err := usage.callPrimary(req)
+	if err != nil && usage.cfg.backupCaller != nil && req.Context().Err() == nil {
+		resp, err = usage.callBackup(req, err)
+	}
+	if err == nil {
+		usage.recordGrantedStep(req, resp)
+		usage.journalRange(req, resp)
+		usage.emitNewRange(req, resp)
+	}
+	return resp, err
+}
+
+// callPrimary 调用主号段申请函数，统一记录成功失败，熔断器打开时不再请求号段服务
+func (usage *RangeUsageInfoStruct) callPrimary(req *ApplyReq) (*NewRangeResp, error) {
+	if !usage.allowRangeRequest() {
+		usage.logs.Debug("{} {} {} 熔断中，跳过号段申请", usage.appName, usage.bizType, usage.prefix)
+		return nil, ErrCircuitOpen
+	}
+	atomic.AddInt64(&usage.counters.rangeRequests, 1)
+	resp, err := usage.invokeLocked(req, usage.invokeCaller)
+	if err != nil && callerAborted(req, err) {
+		//调用方自己的 ctx 取消或超时，不能说明号段服务有问题，不计入失败次数和熔断
+		usage.abandonProbe()
+		return nil, err
+	}
+	if err != nil {
+		atomic.AddInt64(&usage.counters.rangeErrors, 1)
+	} else {
+		usage.detectDuplicateRange(req, resp)
+	}
+	if usage.recordRangeResult(err) {
+		usage.emitRecovered(req, resp)
+	}
+	return resp, err
+}
+
+func (usage *RangeUsageInfoStruct) allowRangeRequest() bool {
+	if usage.tunables().breakerThreshold <= 0 {
+		return true
+	}
+	h := &usage.health
+	h.m.Lock()
+	defer h.m.Unlock()
+	switch h.breaker {
+	case breakerOpen:
+		if usage.cfg.clock.Now().Sub(h.breakerOpenedAt) < usage.tunables().breakerCooldown {
+			return false
+		}
+		//冷却结束，放行一个探测请求
+		h.breaker = breakerHalfOpen
+		h.probeDone = make(chan struct{})
+		return true
+	case breakerHalfOpen:
+		return false //探测请求进行中
+	}
+	return true
+}
+
+// recordRangeResult 记录号段申请结果，返回是否为半开探测成功、熔断器由此关闭
+func (usage *RangeUsageInfoStruct) recordRangeResult(err error) bool {
+	h := &usage.health
+	h.m.Lock()
+	defer h.m.Unlock()
+	probed := h.breaker == breakerHalfOpen
+	if h.probeDone != nil {
+		close(h.probeDone)
+		h.probeDone = nil
+	}
+	if err == nil {
+		if h.breaker != breakerClosed || h.consecutiveFailures > 0 {
+			usage.logs.Info("{} {} {} 号段申请恢复正常", usage.appName, usage.bizType, usage.prefix)
+			atomic.AddInt64(&h.recoveries, 1)
+		}
+		h.consecutiveFailures = 0
+		h.breaker = breakerClosed
+		h.unhealthySince = time.Time{}
+		h.breakerSince = time.Time{}
+		return probed
+	}
+	h.consecutiveFailures++
+	if h.consecutiveFailures == usage.tunables().unhealthyThreshold {
+		h.unhealthySince = usage.cfg.clock.Now()
+	}
+	if usage.tunables().breakerThreshold > 0 && (h.breaker == breakerHalfOpen || h.consecutiveFailures >= usage.tunables().breakerThreshold) {
+		if h.breaker == breakerClosed {
+			h.breakerSince = usage.cfg.clock.Now()
+		}
+		if h.breaker != breakerOpen {
+			usage.logs.Warn("{} {} {} 号段申请连续失败 {} 次，熔断 {}", usage.appName, usage.bizType, usage.prefix, h.consecutiveFailures, usage.tunables().breakerCooldown)
+		}
+		h.breaker = breakerOpen
+		h.breakerOpenedAt = usage.cfg.clock.Now()
+	}
+	return false
+}
+
+// callerAborted 号段申请是否因为调用方（GenerateIdCtx 等）传入的 ctx 取消或超时而失败
+func callerAborted(req *ApplyReq, err error) bool {
+	if req.Context().Err() == nil {
+		return false
+	}
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// abandonProbe 号段申请没有结果时结束半开探测，熔断器回到打开状态且冷却时间已过，下一次申请重新探测
+func (usage *RangeUsageInfoStruct) abandonProbe() {
+	h := &usage.health
+	h.m.Lock()
+	defer h.m.Unlock()
+	if h.probeDone != nil {
+		close(h.probeDone)
+		h.probeDone = nil
+	}
+	if h.breaker == breakerHalfOpen {
+		h.breaker = breakerOpen
+	}
+}
+
+// IsHealthy 返回生成器是否处于正常状态，可用于就绪探针
+// 以下任一情况返回 false（降级状态，id 可能来自降级方案）：
+//   - 连续号段申请失败次数达到 WithHealthThreshold 设置的阈值（默认 3 次）
+//   - 开启了熔断器且熔断器处于打开或半开（等待探测结果）状态
+//
+// 任意一次号段申请成功后自动恢复为 true，需要降级原因和开始时间时使用 DegradedReason
+func (usage *RangeUsageInfoStruct) IsHealthy() bool {
+	h := &usage.health
+	h.m.Lock()
+	defer h.m.Unlock()
+	return h.consecutiveFailures < usage.tunables().unhealthyThreshold && h.breaker == breakerClosed
+}
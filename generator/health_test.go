@@ -0,0 +1,134 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIsHealthy(t *testing.T) {
+	cases := []struct {
+		name     string
+		opts     []Option
+		cooldown time.Duration //熔断后需要等待冷却时间才恢复
+	}{
+		{"failure threshold", []Option{WithHealthThreshold(2)}, 0},
+		{"circuit breaker", []Option{WithHealthThreshold(100), WithCircuitBreaker(2, time.Minute)}, time.Minute},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			caller := newMemCaller()
+			clock := newFakeClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local))
+			usage, err := NewWithOptions(caller.apply, testOptions(append(tc.opts, WithClock(clock), WithStep(10))...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer usage.Close()
+			if !usage.IsHealthy() {
+				t.Fatal("new generator is not healthy")
+			}
+
+			caller.setFail(true)
+			for i := 0; i < 2; i++ {
+				if _, err := usage.GenerateId("app"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if usage.IsHealthy() {
+				t.Fatalf("healthy after %d failed range requests", caller.callCount())
+			}
+
+			caller.setFail(false)
+			if tc.cooldown > 0 {
+				//熔断期间不请求号段服务，仍然是降级状态
+				if _, err := usage.GenerateId("app"); err != nil {
+					t.Fatal(err)
+				}
+				if usage.IsHealthy() {
+					t.Fatal("healthy while the circuit breaker is open")
+				}
+				clock.Add(tc.cooldown)
+			}
+			id, err := usage.GenerateId("app")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parts, err := usage.Parse(id); err != nil || parts.Fallback {
+				t.Fatalf("id %s after recovery is a fallback id (%v)", id, err)
+			}
+			if !usage.IsHealthy() {
+				t.Fatal("not healthy after a successful range request")
+			}
+		})
+	}
+}
+
+// ctxCaller 开启 block 时等到申请的 ctx 结束再返回 ctx 的错误，否则按 memCaller 分配
+type ctxCaller struct {
+	*memCaller
+	block int32
+}
+
+func (c *ctxCaller) apply(req *ApplyReq) (*NewRangeResp, error) {
+	if atomic.LoadInt32(&c.block) == 1 {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}
+	return c.memCaller.apply(req)
+}
+
+// TestCallerCancelNotCounted 调用方自己的 ctx 超时导致的号段申请失败不计入失败次数，也不会打开熔断器
+func TestCallerCancelNotCounted(t *testing.T) {
+	caller := &ctxCaller{memCaller: newMemCaller(), block: 1}
+	clock := newFakeClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local))
+	usage, err := NewWithOptions(caller.apply, testOptions(WithClock(clock), WithStep(10), WithHealthThreshold(1), WithCircuitBreaker(1, time.Minute))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+		_, err := usage.GenerateIdCtx(ctx, "app")
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("GenerateIdCtx error %v, want context.DeadlineExceeded", err)
+		}
+	}
+	if !usage.IsHealthy() {
+		t.Fatal("caller deadlines marked the generator unhealthy")
+	}
+	if stats := usage.Stats(); stats.RangeErrors != 0 {
+		t.Fatalf("RangeErrors %d after caller deadlines, want 0", stats.RangeErrors)
+	}
+
+	//熔断后的半开探测因调用方超时没有结果，下一次申请重新探测
+	atomic.StoreInt32(&caller.block, 0)
+	caller.setFail(true)
+	if _, err := usage.GenerateId("app"); err != nil {
+		t.Fatal(err)
+	}
+	if usage.IsHealthy() {
+		t.Fatal("healthy after a failed range request")
+	}
+	caller.setFail(false)
+	atomic.StoreInt32(&caller.block, 1)
+	clock.Add(time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if _, err := usage.GenerateIdCtx(ctx, "app"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("probe error %v, want context.DeadlineExceeded", err)
+	}
+	atomic.StoreInt32(&caller.block, 0)
+	id, err := usage.GenerateId("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts, err := usage.Parse(id); err != nil || parts.Fallback {
+		t.Fatalf("id %s after the abandoned probe is a fallback id (%v)", id, err)
+	}
+	if !usage.IsHealthy() {
+		t.Fatal("not healthy after the probe succeeded")
+	}
+}
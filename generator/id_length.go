@@ -0,0 +1,78 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	constFallbackSuffixLen = 12 //降级后缀的最大长度：Y + 3 位主机标识 + 最多 8 位随机字符
+	constUUIDLen           = 36
+)
+
+var constMaxSeqLen = len(strconv.FormatInt(1<<63-1, 10)) //序号的最大位数
+
+const constSeqPadWidth = 6 //十进制序号默认补零后的位数
+
+// seqPadWidth 十进制序号补零后的最小位数，见 WithSeqWidth
+func (c *config) seqPadWidth() int {
+	if c.seqPad > 0 {
+		return c.seqPad
+	}
+	return constSeqPadWidth
+}
+
+// maxIdLength 按前缀计算不含追加前缀时 id 的最大长度，序号按 int64 的最大位数计算
+func (c *config) maxIdLength(prefix string) int {
+	if c.uuidNamespace != nil {
+		return constUUIDLen
+	}
+	finalPrefix := prefix
+	if c.bizCode != "" {
+		finalPrefix = joinPrefix(finalPrefix, c.bizCode)
+	}
+	n := len(finalPrefix)
+	switch {
+	case c.template != nil:
+		n += c.template.literalLen(finalPrefix)
+	case finalPrefix != "":
+		n++ //前缀之后的分隔符，前缀为空时没有
+	}
+	suffix := constMaxSeqLen
+	if c.seqRadix != 0 {
+		suffix = radixWidthOf(c.seqRadix, c.seqWidth)
+	}
+	if c.dateless {
+		suffix = constDatelessKeyLen
+	} else {
+		n += c.dates().width() + len(c.dateSeparator)
+	}
+	if c.instanceTag {
+		suffix += constFallbackTagLen
+	}
+	if suffix < constFallbackSuffixLen {
+		suffix = constFallbackSuffixLen
+	}
+	n += suffix
+	if c.typeFlag != 0 {
+		n++
+	}
+	if c.shardFunc != nil {
+		n++
+	}
+	if c.checkChar {
+		n++
+	}
+	return n
+}
+
+// checkMaxIdLength 校验前缀在最坏情况下生成的 id 不超过 WithMaxIDLength 设置的长度
+func (c *config) checkMaxIdLength(prefix string) error {
+	if c.maxIdLen <= 0 {
+		return nil
+	}
+	if n := c.maxIdLength(prefix); n > c.maxIdLen {
+		return fmt.Errorf("%w: worst case id length %d exceeds %d", ErrIdTooLong, n, c.maxIdLen)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package generator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestMaxIDLengthConstruction 前缀、补位、降级后缀和校验字符组合后的最坏长度超过限制时构造失败
+func TestMaxIDLengthConstruction(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"long prefix", []Option{WithPrefix("ORDERPREFIX"), WithMaxIDLength(32)}},
+		{"check char", []Option{WithCheckChar(true), WithMaxIDLength(29)}},
+		{"instance tag and shard", []Option{WithInstanceTagInID(true), WithShardFunc(shardByMod), WithMaxIDLength(31)}},
+		{"fallback suffix", []Option{WithSequenceRadix(36, 4), WithMaxIDLength(20)}},
+		{"biz code", []Option{WithBizCodeInID("PAY"), WithMaxIDLength(30)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewWithOptions(newMemCaller().apply, testOptions(tc.opts...)...)
+			if !errors.Is(err, ErrInvalidOption) || !strings.Contains(err.Error(), "worst case id length") {
+				t.Fatalf("got %v, want an ErrInvalidOption length error", err)
+			}
+		})
+	}
+}
+
+func TestMaxIDLengthFits(t *testing.T) {
+	caller := newMemCaller()
+	//T-20240101 加 19 位序号和校验字符
+	usage, err := NewWithOptions(caller.apply, testOptions(WithCheckChar(true), WithMaxIDLength(30))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	if id, err := usage.GenerateId("app"); err != nil || len(id) > 30 {
+		t.Fatalf("GenerateId = %s, %v", id, err)
+	}
+	caller.setFail(true)
+	usage.usageM.Lock()
+	usage.currentMaxId = usage.currentRangeEnd
+	usage.usageM.Unlock()
+	if id, err := usage.GenerateId("app"); err != nil || len(id) > 30 {
+		t.Fatalf("fallback GenerateId = %s, %v", id, err)
+	}
+	caller.setFail(false)
+
+	if _, err := usage.GenerateIdWithAppendPrefix("app", "PAYMENTSERVICE"); !errors.Is(err, ErrIdTooLong) {
+		t.Fatalf("append prefix: got %v, want ErrIdTooLong", err)
+	}
+	if err := usage.SetPrefix("ORDERS"); !errors.Is(err, ErrIdTooLong) {
+		t.Fatalf("SetPrefix: got %v, want ErrIdTooLong", err)
+	}
+}
@@ -0,0 +1,41 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+const constMaxValidatorRetries = 10 //WithIDValidator 拒绝后最多重新生成的次数
+
+// nextValidId 生成 id 并按 WithIDValidator 校验，被拒绝时跳过该号码继续生成下一个，最多重试 constMaxValidatorRetries 次
+// 返回的 id 已按配置转换为 UUID；before 为自检模式下开始生成的时间
+func (usage *RangeUsageInfoStruct) nextValidId(ctx context.Context, applicationName string, appendPrefix string, flag byte, tags map[string]string, before time.Time) (string, error) {
+	for retry := 0; ; retry++ {
+		id, err := usage.generateId(ctx, applicationName, appendPrefix, flag, tags)
+		if err != nil {
+			usage.recordGenerateError(err)
+			return "", err
+		}
+		if usage.cfg.selfCheck {
+			usage.checkIssued(id, before)
+		}
+		if usage.cfg.uuidNamespace != nil {
+			id = usage.toUUID(id)
+		}
+		if usage.cfg.idValidator == nil {
+			return id, nil
+		}
+		verr := usage.cfg.idValidator(id)
+		if verr == nil {
+			return id, nil
+		}
+		atomic.AddInt64(&usage.counters.rejectedIds, 1)
+		if retry >= constMaxValidatorRetries {
+			usage.logs.Error("{} {} {} 连续 {} 个 id 被校验函数拒绝 {} {}", usage.appName, usage.bizType, usage.prefix, retry+1, id, verr.Error())
+			return "", fmt.Errorf("%w: %s after %d attempts: %s", ErrIdRejected, id, retry+1, verr.Error())
+		}
+		usage.logs.Debug("{} {} {} id 被校验函数拒绝，跳过 {} {}", usage.appName, usage.bizType, usage.prefix, id, verr.Error())
+	}
+}
@@ -0,0 +1,63 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestIDValidatorSkipsRejected 校验函数拒绝含数字 1（映射为 C）的 id，被拒绝的号码跳过，最终返回通过校验的 id
+func TestIDValidatorSkipsRejected(t *testing.T) {
+	var rejected []string
+	usage, err := NewWithOptions(newMemCaller().apply, testOptions(WithClock(newFakeClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local))), WithStep(1000), WithIDValidator(func(id string) error {
+		if strings.Contains(id[len("T-20240101"):], "C") {
+			rejected = append(rejected, id)
+			return fmt.Errorf("reserved char in %s", id)
+		}
+		return nil
+	}))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	var got []int64
+	for i := 0; i < 10; i++ {
+		id, err := usage.GenerateId("app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(id[len("T-20240101"):], "C") {
+			t.Fatalf("rejected id %s returned", id)
+		}
+		got = append(got, mustParse(t, usage, id).Sequence)
+	}
+	want := []int64{2, 3, 4, 5, 6, 7, 8, 9, 20, 22}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("sequences %v, want %v", got, want)
+	}
+	//被拒绝的号码不再发放：1、10 到 19、21
+	if len(rejected) != 12 {
+		t.Fatalf("%d ids rejected, want 12: %v", len(rejected), rejected)
+	}
+	if s := usage.Stats(); s.RejectedIds != 12 || s.Generated != 10 {
+		t.Fatalf("stats RejectedIds %d Generated %d, want 12 and 10", s.RejectedIds, s.Generated)
+	}
+}
+
+func TestIDValidatorGivesUp(t *testing.T) {
+	usage, err := NewWithOptions(newMemCaller().apply, testOptions(WithIDValidator(func(string) error {
+		return errors.New("never")
+	}))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	if _, err := usage.GenerateId("app"); !errors.Is(err, ErrIdRejected) {
+		t.Fatalf("got %v, want ErrIdRejected", err)
+	}
+	if s := usage.Stats(); s.RejectedIds != constMaxValidatorRetries+1 {
+		t.Fatalf("RejectedIds %d, want %d", s.RejectedIds, constMaxValidatorRetries+1)
+	}
+}
@@ -0,0 +1,44 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// acquireInflight 配置了 WithMaxInflight 时占用一个并发名额，名额用完时按配置等待或直接返回 ErrBusy
+// 返回的 release 在生成结束后调用；未配置时只统计并发数
+func (usage *RangeUsageInfoStruct) acquireInflight(ctx context.Context) (release func(), err error) {
+	if usage.inflightSem == nil {
+		atomic.AddInt64(&usage.inflight, 1)
+		return usage.releaseInflight, nil
+	}
+	select {
+	case usage.inflightSem <- struct{}{}:
+	default:
+		if usage.cfg.inflightWait <= 0 {
+			atomic.AddInt64(&usage.counters.busyRejects, 1)
+			return nil, fmt.Errorf("%w: %d generate calls in flight", ErrBusy, usage.cfg.maxInflight)
+		}
+		timer := time.NewTimer(usage.cfg.inflightWait)
+		defer timer.Stop()
+		select {
+		case usage.inflightSem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+			atomic.AddInt64(&usage.counters.busyRejects, 1)
+			return nil, fmt.Errorf("%w: waited %v for %d generate calls in flight", ErrBusy, usage.cfg.inflightWait, usage.cfg.maxInflight)
+		}
+	}
+	atomic.AddInt64(&usage.inflight, 1)
+	return func() {
+		usage.releaseInflight()
+		<-usage.inflightSem
+	}, nil
+}
+
+func (usage *RangeUsageInfoStruct) releaseInflight() {
+	atomic.AddInt64(&usage.inflight, -1)
+}
@@ -0,0 +1,107 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// saturate 发起 n 个阻塞在号段申请上的生成调用，等到它们都占用了并发名额后返回，done 在调用结束时收到结果
+func saturate(t *testing.T, usage *RangeUsageInfoStruct, n int) <-chan error {
+	t.Helper()
+	done := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := usage.GenerateId("app")
+			done <- err
+		}()
+	}
+	deadline := time.Now().Add(time.Second)
+	for usage.Stats().Inflight != int64(n) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Inflight %d, want %d", usage.Stats().Inflight, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return done
+}
+
+func newInflightUsage(t *testing.T, caller *gatedCaller, wait time.Duration) *RangeUsageInfoStruct {
+	t.Helper()
+	usage, err := NewWithOptions(caller.apply, testOptions(WithStep(10), WithNoFallback(), WithMaxInflight(2, wait))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return usage
+}
+
+func TestMaxInflightError(t *testing.T) {
+	caller := &gatedCaller{gate: make(chan struct{})}
+	usage := newInflightUsage(t, caller, 0)
+	defer usage.Close()
+	done := saturate(t, usage, 2)
+
+	start := time.Now()
+	if _, err := usage.GenerateId("app"); !errors.Is(err, ErrBusy) {
+		t.Fatalf("got %v, want ErrBusy", err)
+	}
+	if took := time.Since(start); took > 20*time.Millisecond {
+		t.Fatalf("rejected after %s, want immediately", took)
+	}
+	if s := usage.Stats(); s.BusyRejects != 1 || s.Inflight != 2 {
+		t.Fatalf("stats BusyRejects %d Inflight %d, want 1 and 2", s.BusyRejects, s.Inflight)
+	}
+	close(caller.gate)
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := usage.GenerateId("app"); err != nil {
+		t.Fatalf("after the calls finished: %v", err)
+	}
+	if s := usage.Stats(); s.Inflight != 0 {
+		t.Fatalf("Inflight %d after all calls returned", s.Inflight)
+	}
+}
+
+func TestMaxInflightBlock(t *testing.T) {
+	caller := &gatedCaller{gate: make(chan struct{})}
+	usage := newInflightUsage(t, caller, 50*time.Millisecond)
+	defer usage.Close()
+	done := saturate(t, usage, 2)
+
+	start := time.Now()
+	if _, err := usage.GenerateId("app"); !errors.Is(err, ErrBusy) {
+		t.Fatalf("got %v, want ErrBusy after waiting", err)
+	}
+	if took := time.Since(start); took < 50*time.Millisecond {
+		t.Fatalf("rejected after %s, want to wait 50ms", took)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := usage.GenerateIdCtx(ctx, "app"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want the caller's deadline", err)
+	}
+	if s := usage.Stats(); s.BusyRejects != 1 {
+		t.Fatalf("BusyRejects %d, want 1", s.BusyRejects)
+	}
+
+	//等待期间有名额释放时取得名额继续生成
+	waiter := make(chan error, 1)
+	go func() {
+		_, err := usage.GenerateId("app")
+		waiter <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(caller.gate)
+	if err := <-waiter; err != nil {
+		t.Fatalf("blocked call: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
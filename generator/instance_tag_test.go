@@ -0,0 +1,73 @@
+package generator
+
+import (
+	"testing"
+	"time"
+)
+
+// TestInstanceTagInID 两个实例的正常 id 带有各自的实例标识，Parse 跳过标识后仍能还原序号
+func TestInstanceTagInID(t *testing.T) {
+	cases := []struct {
+		name string
+		opts []Option
+	}{
+		{"plain", nil},
+		{"shard and check char", []Option{WithShardFunc(shardByMod), WithCheckChar(true)}},
+		{"radix", []Option{WithSequenceRadix(36, 6)}},
+	}
+	started := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			caller := newMemCaller()
+			var instances []*RangeUsageInfoStruct
+			for i, host := range []string{"pod-a", "pod-b"} {
+				usage, err := NewWithOptions(caller.apply, testOptions(append(tc.opts, WithHostKey(host), WithInstanceTagInID(true), WithStep(10))...)...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer usage.Close()
+				//固定进程号和启动时间，使两个实例的标识可重复
+				usage.fallbackTag = newFallbackTag(host, 100+i, started)
+				instances = append(instances, usage)
+			}
+			if instances[0].fallbackTag == instances[1].fallbackTag {
+				t.Fatalf("both instances tagged %s", instances[0].fallbackTag)
+			}
+
+			seqs := make(map[int64]bool)
+			for round := 0; round < 30; round++ {
+				for _, usage := range instances {
+					id, err := usage.GenerateId("app")
+					if err != nil {
+						t.Fatal(err)
+					}
+					parts, err := usage.Parse(id)
+					if err != nil || parts.Fallback {
+						t.Fatalf("Parse(%s) = %+v, %v", id, parts, err)
+					}
+					if parts.InstanceTag != usage.fallbackTag {
+						t.Fatalf("%s tagged %q, want %q", id, parts.InstanceTag, usage.fallbackTag)
+					}
+					if seqs[parts.Sequence] {
+						t.Fatalf("%s reused sequence %d", id, parts.Sequence)
+					}
+					seqs[parts.Sequence] = true
+				}
+			}
+
+			//降级 id 带有同一个实例标识
+			caller.setFail(true)
+			usage := instances[1]
+			usage.usageM.Lock()
+			usage.currentMaxId = usage.currentRangeEnd
+			usage.usageM.Unlock()
+			id, err := usage.GenerateId("app")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parts, err := usage.Parse(id); err != nil || !parts.Fallback || parts.InstanceTag != usage.fallbackTag {
+				t.Fatalf("fallback Parse(%s) = %+v, %v, want tag %q", id, parts, err, usage.fallbackTag)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package generator
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// GenerateInt64 直接返回号段分配的号码，不做任何字符串格式化，用作数据库整型主键等场景；
+// 号码与 GenerateId 共用同一号段，两者混用时不会重复；静态节点模式下返回的是按 WithStaticNodeAssignment 交错后的号码，
+// 前缀、类型标识、打乱、进制编码、分片、校验位等只作用于字符串的配置都不生效
+// 号段服务按天分配号码，不同日期的号码会重复，需要跨天唯一时配合 WithPackedInt64 使用；
+// 取号流程与 GenerateId 相同（见 nextSeq），包括溢出到后一天、排队重试、熔断恢复和降级宽限期，
+// 只是没有可用号码时不降级，返回包装了 ErrSegmentUnavailable 的错误
+func (usage *RangeUsageInfoStruct) GenerateInt64(applicationName string) (int64, error) {
+	return usage.GenerateInt64Ctx(context.Background(), applicationName)
+}
+
+// GenerateInt64Ctx 与 GenerateInt64 相同，ctx 的处理见 GenerateIdCtx
+func (usage *RangeUsageInfoStruct) GenerateInt64Ctx(ctx context.Context, applicationName string) (int64, error) {
+	release, err := usage.acquireInflight(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	taken, err := usage.nextSeq(ctx, applicationName, nil, 0, time.Time{})
+	if err != nil {
+		return 0, err
+	}
+	if taken.seq == 0 {
+		return 0, taken.unavailable()
+	}
+	if taken.seq < 0 {
+		return 0, ErrSequenceOverflow
+	}
+	v := usage.nodeSeq(taken.seq)
+	if usage.cfg.packed {
+		if v, err = usage.packInt64(v, taken.day); err != nil {
+			return 0, err
+		}
+	}
+	atomic.AddInt64(&usage.counters.generated, 1)
+	return v, nil
+}
@@ -0,0 +1,80 @@
+package generator
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// TestSequenceNearMaxInt64 号段到达 int64 上限后按号段用完处理：不会回绕生成负数序号的 id，
+// 号段服务无法再分配更大的号段，按降级策略生成降级 id 或返回错误
+func TestSequenceNearMaxInt64(t *testing.T) {
+	cases := []struct {
+		name         string
+		opts         []Option
+		wantFallback bool
+	}{
+		{"fallback", nil, true},
+		{"no fallback", []Option{WithNoFallback()}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			caller := func(req *ApplyReq) (*NewRangeResp, error) {
+				return &NewRangeResp{RangeStart: math.MaxInt64 - 2, RangeEnd: math.MaxInt64}, nil
+			}
+			usage, err := NewWithOptions(caller, testOptions(append(tc.opts, WithStep(3), WithThreshold(1))...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer usage.Close()
+			for i := int64(2); i >= 0; i-- {
+				want := int64(math.MaxInt64) - i
+				id, err := usage.GenerateId("app")
+				if err != nil {
+					t.Fatal(err)
+				}
+				parts, err := usage.Parse(id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if parts.Fallback || parts.Sequence != want {
+					t.Fatalf("id %s sequence %d, want %d", id, parts.Sequence, want)
+				}
+			}
+
+			for i := 0; i < 3; i++ {
+				id, err := usage.GenerateId("app")
+				if !tc.wantFallback {
+					if !errors.Is(err, ErrSegmentUnavailable) {
+						t.Fatalf("GenerateId past the int64 limit %q, %v, want ErrSegmentUnavailable", id, err)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if parts, err := usage.Parse(id); err != nil || !parts.Fallback {
+					t.Fatalf("id %s past the int64 limit is not a fallback id (%v)", id, err)
+				}
+			}
+		})
+	}
+}
+
+// TestNextIdAtMaxInt64 号段内的号码已经是 int64 上限时不再递增
+func TestNextIdAtMaxInt64(t *testing.T) {
+	usage, err := NewWithOptions(newMemCaller().apply, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	usage.usageM.Lock()
+	defer usage.usageM.Unlock()
+	usage.setRangeLocked(math.MaxInt64, math.MaxInt64)
+	if seq := usage.nextIdLocked(); seq != seqOverflow {
+		t.Fatalf("nextIdLocked at the int64 limit returned %d", seq)
+	}
+	if usage.currentMaxId != math.MaxInt64 {
+		t.Fatalf("currentMaxId wrapped to %d", usage.currentMaxId)
+	}
+}
@@ -0,0 +1,97 @@
+package generator
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyCaller 前 failures 次申请失败，之后按 memCaller 分配
+type flakyCaller struct {
+	m        sync.Mutex
+	failures int
+	mem      *memCaller
+}
+
+func (c *flakyCaller) apply(req *ApplyReq) (*NewRangeResp, error) {
+	c.m.Lock()
+	if c.failures > 0 {
+		c.failures--
+		c.m.Unlock()
+		return nil, errDown
+	}
+	c.m.Unlock()
+	return c.mem.apply(req)
+}
+
+// generateFuncs GenerateId 与 GenerateInt64 在相同失败场景下的行为应一致
+var generateFuncs = []struct {
+	name     string
+	generate func(usage *RangeUsageInfoStruct) error
+}{
+	{"GenerateId", func(usage *RangeUsageInfoStruct) error {
+		_, err := usage.GenerateId("app")
+		return err
+	}},
+	{"GenerateInt64", func(usage *RangeUsageInfoStruct) error {
+		_, err := usage.GenerateInt64("app")
+		return err
+	}},
+}
+
+func TestGenerateInt64SharesFailureHandling(t *testing.T) {
+	for _, gen := range generateFuncs {
+		t.Run(gen.name+"/grace recovers", func(t *testing.T) {
+			caller := &flakyCaller{failures: 3, mem: newMemCaller()}
+			usage, err := NewWithOptions(caller.apply, testOptions(WithNoFallback(), WithFallbackGrace(time.Second))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := gen.generate(usage); err != nil {
+				t.Fatalf("got %v, want success after the service recovers within the grace window", err)
+			}
+		})
+		t.Run(gen.name+"/unavailable", func(t *testing.T) {
+			caller := &flakyCaller{failures: 1 << 30, mem: newMemCaller()}
+			usage, err := NewWithOptions(caller.apply, testOptions(WithNoFallback(), WithFallbackGrace(20*time.Millisecond))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = gen.generate(usage)
+			if !errors.Is(err, ErrSegmentUnavailable) || !errors.Is(err, errDown) {
+				t.Fatalf("got %v, want ErrSegmentUnavailable wrapping the caller error", err)
+			}
+		})
+	}
+}
+
+func TestGenerateInt64SharesRange(t *testing.T) {
+	usage, err := NewWithOptions(newMemCaller().apply, testOptions(WithStep(7))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[int64]bool)
+	for i := 0; i < 100; i++ {
+		var seq int64
+		if i%2 == 0 {
+			if seq, err = usage.GenerateInt64("app"); err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			id, err := usage.GenerateId("app")
+			if err != nil {
+				t.Fatal(err)
+			}
+			parts, err := usage.Parse(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			seq = parts.Sequence
+		}
+		if seen[seq] {
+			t.Fatalf("sequence %d issued twice", seq)
+		}
+		seen[seq] = true
+	}
+}
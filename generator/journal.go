@@ -0,0 +1,88 @@
+package generator
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// RangeJournal 记录客户端拿到的每个号段，每次成功申请到号段（含备用号段服务）调用一次 Record，
+// day 为号段所属的日期（服务端返回了日期时以服务端为准）；号段服务数据丢失后可以据此重建各日期已分配的最大号码。
+// 这只是客户端的观测手段，不能代替号段服务自身的持久化：客户端崩溃、日志写入失败或没有配置的实例都不会留下记录。
+// Record 在申请号段的路径上同步调用，可能被并发调用，应尽快返回
+type RangeJournal interface {
+	Record(appName, bizType, day string, start, end int64)
+}
+
+// journalEntry FileRangeJournal 每行记录的内容
+type journalEntry struct {
+	Time    time.Time `json:"time"`
+	AppName string    `json:"appName"`
+	BizType string    `json:"bizType"`
+	Day     string    `json:"day"`
+	Start   int64     `json:"rangeStart"`
+	End     int64     `json:"rangeEnd"`
+}
+
+// FileRangeJournal 以 JSON Lines 格式追加写入文件的 RangeJournal，每个号段一行；
+// 写入失败不影响 id 生成，第一个错误通过 Err 返回
+type FileRangeJournal struct {
+	m   sync.Mutex
+	f   *os.File
+	err error
+}
+
+// NewFileRangeJournal 以追加方式打开（不存在时创建）path
+func NewFileRangeJournal(path string) (*FileRangeJournal, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileRangeJournal{f: f}, nil
+}
+
+func (j *FileRangeJournal) Record(appName, bizType, day string, start, end int64) {
+	line, err := json.Marshal(journalEntry{
+		Time:    time.Now(),
+		AppName: appName,
+		BizType: bizType,
+		Day:     day,
+		Start:   start,
+		End:     end,
+	})
+	j.m.Lock()
+	defer j.m.Unlock()
+	if err == nil {
+		_, err = j.f.Write(append(line, '\n'))
+	}
+	if err != nil && j.err == nil {
+		j.err = err
+	}
+}
+
+// Err 返回第一次写入失败的错误
+func (j *FileRangeJournal) Err() error {
+	j.m.Lock()
+	defer j.m.Unlock()
+	return j.err
+}
+
+// Close 关闭文件，由调用方在生成器 Close 之后调用
+func (j *FileRangeJournal) Close() error {
+	j.m.Lock()
+	defer j.m.Unlock()
+	return j.f.Close()
+}
+
+// journalRange 开启 WithRangeJournal 时记录申请到的号段
+func (usage *RangeUsageInfoStruct) journalRange(req *ApplyReq, resp *NewRangeResp) {
+	if usage.cfg.journal == nil {
+		return
+	}
+	day := req.Day
+	if resp.Day != "" {
+		day = resp.Day
+	}
+	usage.cfg.journal.Record(req.AppName, req.BizType, day, resp.RangeStart, resp.RangeEnd)
+}
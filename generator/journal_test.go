@@ -0,0 +1,119 @@
+package generator
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// grantLog 记录号段服务实际返回的每个号段
+type grantLog struct {
+	m      sync.Mutex
+	ranges map[journalEntry]int
+}
+
+func (g *grantLog) wrap(caller NumbersReqFunc) NumbersReqFunc {
+	return func(req *ApplyReq) (*NewRangeResp, error) {
+		resp, err := caller(req)
+		if err == nil {
+			g.m.Lock()
+			g.ranges[journalEntry{AppName: req.AppName, BizType: req.BizType, Day: req.Day, Start: resp.RangeStart, End: resp.RangeEnd}]++
+			g.m.Unlock()
+		}
+		return resp, err
+	}
+}
+
+// TestRangeJournalOnce 主备号段服务、争用时的单次号段和跨天申请到的每个号段都在日志中记录且只记录一次
+func TestRangeJournalOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ranges.jsonl")
+	journal, err := NewFileRangeJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := newMemCaller()
+	primary, backup := &replicaCaller{store: store}, &replicaCaller{store: store}
+	granted := &grantLog{ranges: make(map[journalEntry]int)}
+	slowPrimary := func(req *ApplyReq) (*NewRangeResp, error) {
+		time.Sleep(5 * time.Millisecond)
+		return primary.apply(req)
+	}
+	clock := newFakeClock(time.Date(2024, 1, 1, 23, 0, 0, 0, time.Local))
+	usage, err := NewWithOptions(granted.wrap(slowPrimary), testOptions(WithClock(clock), WithStep(10),
+		WithBackupCaller(granted.wrap(backup.apply)), WithRangeJournal(journal))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	burst(t, usage, 8)
+	for i := 0; i < 30; i++ {
+		if _, err := usage.GenerateId("app"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	primary.setFail(true)
+	for i := 0; i < 30; i++ {
+		if _, err := usage.GenerateId("app"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	primary.setFail(false)
+	clock.Add(2 * time.Hour)
+	for i := 0; i < 30; i++ {
+		if _, err := usage.GenerateId("app"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitRefreshIdle(t, usage)
+	if err := usage.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := journal.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := journal.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	journaled := make(map[journalEntry]int)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("bad journal line %q: %v", scanner.Text(), err)
+		}
+		if entry.Time.IsZero() {
+			t.Fatalf("journal line %q has no time", scanner.Text())
+		}
+		entry.Time = time.Time{}
+		journaled[entry]++
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	granted.m.Lock()
+	defer granted.m.Unlock()
+	days := make(map[string]bool)
+	for entry, n := range granted.ranges {
+		if n != 1 || journaled[entry] != 1 {
+			t.Fatalf("range %+v granted %d times, journaled %d times", entry, n, journaled[entry])
+		}
+		days[entry.Day] = true
+	}
+	if len(journaled) != len(granted.ranges) {
+		t.Fatalf("journaled %d ranges, granted %d", len(journaled), len(granted.ranges))
+	}
+	if len(days) != 2 || backup.callCount() == 0 {
+		t.Fatalf("granted ranges on days %v with %d backup calls, want both days and the backup", days, backup.callCount())
+	}
+}
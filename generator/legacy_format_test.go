@@ -0,0 +1,59 @@
+package generator
+
+import (
+	"testing"
+	"time"
+)
+
+// TestLegacyFormats 调整格式后 Parse 仍能解析两个时期生成的 id，并报告匹配到的格式
+func TestLegacyFormats(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local))
+	//第一个时期：36 进制序号，日期与序号直接相连
+	old, err := NewWithOptions(newMemCaller().apply, testOptions(WithClock(clock), WithSequenceRadix(36, 0))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer old.Close()
+	oldId, err := old.GenerateId("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//第二个时期：十进制逐位映射，日期后带分隔符，序号之后带校验字符
+	current, err := NewWithOptions(newMemCaller().apply, testOptions(WithClock(clock), WithStep(10), WithDateSeparator("_"), WithCheckChar(true),
+		WithLegacyFormats(IdFormat{Name: "radix36", Radix: 36}))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer current.Close()
+	for i := 0; i < 5; i++ {
+		if _, err := current.GenerateId("app"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	newId, err := current.GenerateId("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		id         string
+		wantFormat string
+		wantSeq    int64
+	}{
+		{oldId, "radix36", 1},
+		{newId, CurrentFormat, 6},
+	}
+	for _, tc := range cases {
+		parts, err := current.Parse(tc.id)
+		if err != nil {
+			t.Fatalf("Parse(%s): %v", tc.id, err)
+		}
+		if parts.Format != tc.wantFormat || parts.Sequence != tc.wantSeq || parts.Day != "20240101" || parts.Prefix != "T" {
+			t.Fatalf("Parse(%s) = %+v, want format %s sequence %d", tc.id, parts, tc.wantFormat, tc.wantSeq)
+		}
+	}
+	if _, err := current.Parse("T-2024XYZ"); err == nil {
+		t.Fatal("Parse accepted an id matching no format")
+	}
+}
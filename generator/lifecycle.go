@@ -0,0 +1,75 @@
+package generator
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// DayEventKind 号段日期生命周期事件类型
+type DayEventKind string
+
+const (
+	DayStart     DayEventKind = "day_start"     //当天第一次拿到号段
+	RangeRefresh DayEventKind = "range_refresh" //当天再次切换到新号段，Refreshes 为当天累计的切换次数
+	DayEnd       DayEventKind = "day_end"       //检测到跨天，Refreshes 为旧日期全天的切换次数
+
+	BreakerRecovered DayEventKind = "breaker_recovered" //熔断器半开探测成功、恢复申请号段，RangeStart、RangeEnd 为探测拿到的号段
+)
+
+// DayEvent 号段日期生命周期事件，用于审计每天号段的申请情况和容量规划
+type DayEvent struct {
+	Kind       DayEventKind
+	Time       time.Time
+	Day        string //号段所属日期
+	Refreshes  int64
+	RangeStart int64
+	RangeEnd   int64
+}
+
+// dayLifecycle 当前号段日期的生命周期状态，受 usageM 保护
+type dayLifecycle struct {
+	day       string
+	refreshes int64
+	pending   []DayEvent //锁内产生、待锁外回调的事件
+}
+
+// recordRangeSwitchLocked 切换号段后记录生命周期事件，调用方需持有 usageM，事件由 flushDayEvents 在锁外回调
+func (usage *RangeUsageInfoStruct) recordRangeSwitchLocked() {
+	life := &usage.dayLife
+	now := usage.cfg.clock.Now()
+	event := DayEvent{Time: now, Day: usage.rangeDay, RangeStart: usage.currentRangeStart, RangeEnd: usage.currentRangeEnd}
+	if life.day == usage.rangeDay {
+		life.refreshes++
+		atomic.StoreInt64(&usage.counters.dayRefreshes, life.refreshes)
+		event.Kind, event.Refreshes = RangeRefresh, life.refreshes
+	} else {
+		if life.day != "" {
+			life.pending = append(life.pending, DayEvent{Kind: DayEnd, Time: now, Day: life.day, Refreshes: life.refreshes})
+		}
+		life.day, life.refreshes = usage.rangeDay, 0
+		atomic.StoreInt64(&usage.counters.dayRefreshes, 0)
+		atomic.AddInt64(&usage.counters.dayStarts, 1)
+		event.Kind = DayStart
+	}
+	if usage.cfg.onDayLifecycle != nil {
+		life.pending = append(life.pending, event)
+		atomic.StoreInt32(&usage.pendingDayEvents, int32(len(life.pending)))
+	} else {
+		life.pending = nil
+	}
+}
+
+// flushDayEvents 在锁外依次回调待处理的生命周期事件
+func (usage *RangeUsageInfoStruct) flushDayEvents() {
+	if atomic.LoadInt32(&usage.pendingDayEvents) == 0 {
+		return
+	}
+	usage.usageM.Lock()
+	events := usage.dayLife.pending
+	usage.dayLife.pending = nil
+	atomic.StoreInt32(&usage.pendingDayEvents, 0)
+	usage.usageM.Unlock()
+	for _, event := range events {
+		usage.cfg.onDayLifecycle(event)
+	}
+}
@@ -0,0 +1,89 @@
+package generator
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitRefreshIdle 等待后台号段申请完成，使之后的断言不受异步切换号段的影响
+func waitRefreshIdle(t *testing.T, usage *RangeUsageInfoStruct) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&usage.gettingIdRangeCounter) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("range refresh still in flight")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestDayLifecycleEvents 一天内生成 id 后跨天，依次回调 DayStart、每次切换号段的 RangeRefresh 和旧日期的 DayEnd，计数与号段申请次数一致
+func TestDayLifecycleEvents(t *testing.T) {
+	mem := newMemCaller()
+	var dayCalls sync.Map
+	caller := func(req *ApplyReq) (*NewRangeResp, error) {
+		n, _ := dayCalls.LoadOrStore(req.Day, new(int64))
+		atomic.AddInt64(n.(*int64), 1)
+		return mem.apply(req)
+	}
+	var m sync.Mutex
+	var events []DayEvent
+	clock := newFakeClock(time.Date(2024, 1, 1, 8, 0, 0, 0, time.Local))
+	usage, err := NewWithOptions(caller, testOptions(WithClock(clock), WithStep(10), WithThreshold(1), WithNoFallback(), OnDayLifecycle(func(e DayEvent) {
+		m.Lock()
+		events = append(events, e)
+		m.Unlock()
+	}))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+
+	for i := 0; i < 35; i++ {
+		if _, err := usage.GenerateId("app"); err != nil {
+			t.Fatal(err)
+		}
+		clock.Add(20 * time.Minute)
+	}
+	waitRefreshIdle(t, usage)
+	n, _ := dayCalls.Load("20240101")
+	refreshes := atomic.LoadInt64(n.(*int64)) - 1
+	if refreshes < 3 {
+		t.Fatalf("only %d refreshes for 35 ids with step 10", refreshes)
+	}
+	if s := usage.Stats(); s.DayStarts != 1 || s.DayRangeRefreshes != refreshes {
+		t.Fatalf("stats DayStarts %d DayRangeRefreshes %d, want 1 and %d", s.DayStarts, s.DayRangeRefreshes, refreshes)
+	}
+
+	clock.Add(24 * time.Hour)
+	if _, err := usage.GenerateId("app"); err != nil {
+		t.Fatal(err)
+	}
+	waitRefreshIdle(t, usage)
+	usage.flushDayEvents()
+	if s := usage.Stats(); s.DayStarts != 2 || s.DayRangeRefreshes != 0 {
+		t.Fatalf("stats after rollover DayStarts %d DayRangeRefreshes %d, want 2 and 0", s.DayStarts, s.DayRangeRefreshes)
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	if want := int(refreshes) + 3; len(events) != want {
+		t.Fatalf("got %d events, want %d: %+v", len(events), want, events)
+	}
+	if e := events[0]; e.Kind != DayStart || e.Day != "20240101" || e.RangeStart != 1 {
+		t.Fatalf("first event %+v, want DayStart for 20240101", e)
+	}
+	for i := int64(1); i <= refreshes; i++ {
+		if e := events[i]; e.Kind != RangeRefresh || e.Day != "20240101" || e.Refreshes != i {
+			t.Fatalf("event %d %+v, want RangeRefresh %d", i, e, i)
+		}
+	}
+	if e := events[refreshes+1]; e.Kind != DayEnd || e.Day != "20240101" || e.Refreshes != refreshes {
+		t.Fatalf("event %+v, want DayEnd for 20240101 with %d refreshes", e, refreshes)
+	}
+	if e := events[refreshes+2]; e.Kind != DayStart || e.Day != "20240102" {
+		t.Fatalf("last event %+v, want DayStart for 20240102", e)
+	}
+}
@@ -0,0 +1,66 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const constLockTimeout = 3 * time.Second //获取分布式锁的超时时间
+
+// Locker 分布式锁，号段服务本身不能原子分配号段时（如不支持 INCRBY 的 KV 存储），
+// 通过 WithDistributedLock 设置，保证集群内同一 appName+bizType+日期 同时只有一个申请方在分配号段
+type Locker interface {
+	// Lock 获取 key 对应的锁，返回的 unlock 用于释放锁；ctx 超时或取消时应返回错误
+	Lock(ctx context.Context, key string) (unlock func(), err error)
+}
+
+// nopLocker 默认的空锁，号段服务自身保证原子分配时不需要加锁
+type nopLocker struct{}
+
+func (nopLocker) Lock(ctx context.Context, key string) (func(), error) {
+	return func() {}, nil
+}
+
+func lockKey(req *ApplyReq) string {
+	return req.AppName + ":" + req.BizType + ":" + req.Day
+}
+
+// invokeLocked 持有分布式锁调用号段申请函数，获取锁失败按号段申请失败处理
+func (usage *RangeUsageInfoStruct) invokeLocked(req *ApplyReq, invoke NumbersReqFunc) (*NewRangeResp, error) {
+	if usage.cfg.dateless {
+		req, invoke = datelessInvoke(req, invoke)
+	}
+	ctx := req.Context()
+	lockCtx, cancel := context.WithTimeout(ctx, constLockTimeout)
+	defer cancel()
+	key := lockKey(req)
+	unlock, err := usage.cfg.locker.Lock(lockCtx, key)
+	if err != nil {
+		usage.logs.Warn("{} {} {} 获取号段分布式锁失败 {} {}", usage.appName, usage.bizType, usage.prefix, key, err.Error())
+		return nil, fmt.Errorf("lock %s: %w", key, err)
+	}
+	if ctx.Done() == nil {
+		defer unlock()
+		return invoke(req)
+	}
+
+	//调用方的 ctx 可能取消，申请函数不响应 ctx 时在后台执行完再释放锁，调用方不再等待
+	type result struct {
+		resp *NewRangeResp
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer unlock()
+		resp, err := invoke(req)
+		done <- result{resp: resp, err: err}
+	}()
+	select {
+	case <-ctx.Done():
+		usage.logs.Warn("{} {} {} 调用方取消了号段申请 {} {}", usage.appName, usage.bizType, usage.prefix, req.Day, ctx.Err().Error())
+		return nil, ctx.Err()
+	case r := <-done:
+		return r.resp, r.err
+	}
+}
@@ -0,0 +1,121 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeLocker 进程内模拟的分布式锁，每个 key 一个容量为 1 的通道
+type fakeLocker struct {
+	m     sync.Mutex
+	locks map[string]chan struct{}
+	keys  map[string]int
+}
+
+func newFakeLocker() *fakeLocker {
+	return &fakeLocker{locks: make(map[string]chan struct{}), keys: make(map[string]int)}
+}
+
+func (l *fakeLocker) Lock(ctx context.Context, key string) (func(), error) {
+	l.m.Lock()
+	ch, ok := l.locks[key]
+	if !ok {
+		ch = make(chan struct{}, 1)
+		l.locks[key] = ch
+	}
+	l.keys[key]++
+	l.m.Unlock()
+	select {
+	case ch <- struct{}{}:
+		return func() { <-ch }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// kvCaller 不能原子分配号段的号段服务：先读再写，中间有延迟，不加锁并发时会分配出重叠的号段
+type kvCaller struct {
+	maxId    int64
+	inside   int32
+	overlaps int32
+}
+
+func (c *kvCaller) apply(req *ApplyReq) (*NewRangeResp, error) {
+	if atomic.AddInt32(&c.inside, 1) > 1 {
+		atomic.AddInt32(&c.overlaps, 1)
+	}
+	defer atomic.AddInt32(&c.inside, -1)
+	start := c.maxId + 1
+	time.Sleep(time.Millisecond)
+	c.maxId = start + int64(req.Step) - 1
+	return &NewRangeResp{RangeStart: start, RangeEnd: c.maxId}, nil
+}
+
+// TestDistributedLockMutualExclusion 多个实例共用同一把锁时号段申请互斥，生成的 id 不重复
+func TestDistributedLockMutualExclusion(t *testing.T) {
+	const instances, perInstance = 4, 50
+	caller := &kvCaller{}
+	locker := newFakeLocker()
+	var m sync.Mutex
+	seen := make(map[string]bool, instances*perInstance)
+	var wg sync.WaitGroup
+	for i := 0; i < instances; i++ {
+		usage, err := NewWithOptions(caller.apply, testOptions(WithStep(5), WithDistributedLock(locker))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer usage.Close()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < perInstance; n++ {
+				id, err := usage.GenerateId("app")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				m.Lock()
+				if seen[id] {
+					t.Errorf("duplicate id %s", id)
+				}
+				seen[id] = true
+				m.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if overlaps := atomic.LoadInt32(&caller.overlaps); overlaps != 0 {
+		t.Fatalf("%d range requests ran concurrently under the lock", overlaps)
+	}
+	locker.m.Lock()
+	defer locker.m.Unlock()
+	if len(locker.keys) != 1 {
+		t.Fatalf("lock keys %v, want one key for app + biz type + day", locker.keys)
+	}
+}
+
+type failingLocker struct{}
+
+func (failingLocker) Lock(context.Context, string) (func(), error) {
+	return nil, errors.New("lock service down")
+}
+
+// TestDistributedLockFailure 获取锁失败按号段申请失败处理，不调用号段服务
+func TestDistributedLockFailure(t *testing.T) {
+	caller := newMemCaller()
+	usage, err := NewWithOptions(caller.apply, testOptions(WithDistributedLock(failingLocker{}), WithNoFallback())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	if _, err := usage.GenerateId("app"); !errors.Is(err, ErrSegmentUnavailable) {
+		t.Fatalf("GenerateId with the lock unavailable error %v, want ErrSegmentUnavailable", err)
+	}
+	if calls := caller.callCount(); calls != 0 {
+		t.Fatalf("numbers service called %d times without the lock", calls)
+	}
+}
@@ -0,0 +1,101 @@
+package generator
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// scriptedCaller 依次返回预先设定的号段，用完后重复最后一个
+type scriptedCaller struct {
+	m      sync.Mutex
+	ranges []NewRangeResp
+	calls  int
+}
+
+func (c *scriptedCaller) apply(*ApplyReq) (*NewRangeResp, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	i := c.calls
+	if i >= len(c.ranges) {
+		i = len(c.ranges) - 1
+	}
+	c.calls++
+	resp := c.ranges[i]
+	return &resp, nil
+}
+
+// TestLowerRangeAfterExhaustion 当前号段用完后服务端返回更小的号段时按申请失败处理，不会重复发放用过的序号；
+// 与已用号码部分重叠的号段只使用未用过的部分
+func TestLowerRangeAfterExhaustion(t *testing.T) {
+	cases := []struct {
+		name         string
+		opts         []Option
+		wantFallback bool
+	}{
+		{"fallback", nil, true},
+		{"no fallback", []Option{WithNoFallback()}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			caller := &scriptedCaller{ranges: []NewRangeResp{
+				{RangeStart: 11, RangeEnd: 15},
+				{RangeStart: 1, RangeEnd: 5},
+				{RangeStart: 13, RangeEnd: 18},
+			}}
+			usage, err := NewWithOptions(caller.apply, testOptions(append(tc.opts, WithStep(5), WithThreshold(1))...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer usage.Close()
+			seen := make(map[int64]bool)
+			next := func() (*IdParts, error) {
+				id, err := usage.GenerateId("app")
+				if err != nil {
+					return nil, err
+				}
+				parts, err := usage.Parse(id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !parts.Fallback {
+					if seen[parts.Sequence] {
+						t.Fatalf("sequence %d issued twice", parts.Sequence)
+					}
+					seen[parts.Sequence] = true
+				}
+				return parts, nil
+			}
+			for want := int64(11); want <= 15; want++ {
+				parts, err := next()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if parts.Fallback || parts.Sequence != want {
+					t.Fatalf("sequence %d, want %d", parts.Sequence, want)
+				}
+			}
+
+			//更小的号段无法保证递增
+			parts, err := next()
+			if tc.wantFallback {
+				if err != nil || !parts.Fallback {
+					t.Fatalf("id after a lower range: %+v, %v, want a fallback id", parts, err)
+				}
+			} else if !errors.Is(err, ErrSegmentUnavailable) {
+				t.Fatalf("GenerateId after a lower range error %v, want ErrSegmentUnavailable", err)
+			}
+
+			//部分重叠的号段只使用 16-18
+			for want := int64(16); want <= 18; want++ {
+				parts, err := next()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if parts.Fallback || parts.Sequence != want {
+					t.Fatalf("sequence %d after an overlapping range, want %d", parts.Sequence, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package generator
+
+import "sync"
+
+// Manager 管理多个应用、多个业务类型的生成器，按 (appName, bizType) 在首次使用时创建并缓存
+type Manager struct {
+	caller     NumbersReqFunc
+	opts       []Option
+	m          sync.Mutex
+	generators map[managerKey]*RangeUsageInfoStruct
+	bizOpts    map[string][]Option //Register 为单个业务类型设置的选项
+	closed     bool                //Close 之后为 true，不再缓存新创建的生成器
+}
+
+type managerKey struct {
+	appName string
+	bizType string
+}
+
+// AggregatedStats 同一个应用下所有生成器的汇总统计
+type AggregatedStats struct {
+	Generators int   //该应用下的生成器数量
+	Generated  int64 //成功返回的 id 数（含降级 id）
+	Fallbacks  int64 //降级随机生成的 id 数
+	Remaining  int64 //当前号段和预取队列中剩余可用的号码数
+}
+
+// NewManager 创建 Manager，opts 用于创建每个生成器，前缀默认与 bizType 相同，可通过 WithPrefix 统一设置；
+// caller 为兼容保留的函数形式，Manager 创建的生成器 Close 时不会归还未用完的号码
+func NewManager(caller NumbersReqFunc, opts ...Option) *Manager {
+	return &Manager{
+		caller:     caller,
+		opts:       opts,
+		generators: make(map[managerKey]*RangeUsageInfoStruct),
+		bizOpts:    make(map[string][]Option),
+	}
+}
+
+// Register 为 bizType 设置额外的选项（如 WithPrefix 设置与 bizType 不同的前缀），创建该业务类型的生成器时追加在 NewManager 的选项之后；
+// 只影响之后新创建的生成器，已创建的生成器不变，不调用 Register 的业务类型只使用 NewManager 的选项
+func (m *Manager) Register(bizType string, opts ...Option) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.bizOpts[bizType] = append([]Option{}, opts...)
+}
+
+// Generator 返回 (appName, bizType) 对应的生成器，不存在时按 NewManager 和 Register 的选项创建；
+// 创建时不持有 Manager 的锁，其它 (appName, bizType) 的调用不必等待，并发创建同一个生成器时只保留先完成的一个；
+// Manager 关闭后返回已关闭的生成器，生成调用返回 ErrClosed
+func (m *Manager) Generator(bizType string, applicationName string) *RangeUsageInfoStruct {
+	key := managerKey{appName: applicationName, bizType: bizType}
+	m.m.Lock()
+	if usage, ok := m.generators[key]; ok {
+		m.m.Unlock()
+		return usage
+	}
+	opts := append([]Option{WithPrefix(bizType)}, m.opts...)
+	opts = append(opts, m.bizOpts[bizType]...)
+	opts = append(opts, WithBizType(bizType), WithAppName(applicationName))
+	m.m.Unlock()
+
+	usage := New(m.caller, opts...)
+	m.m.Lock()
+	defer m.m.Unlock()
+	if m.closed {
+		usage.Close()
+		return usage
+	}
+	if existing, ok := m.generators[key]; ok {
+		//其它调用方已经创建了同一个生成器
+		usage.Close()
+		return existing
+	}
+	m.generators[key] = usage
+	return usage
+}
+
+// GenerateId 使用 (appName, bizType) 对应的生成器生成 id，Manager 关闭后返回 ErrClosed
+func (m *Manager) GenerateId(bizType string, applicationName string) (string, error) {
+	m.m.Lock()
+	closed := m.closed
+	m.m.Unlock()
+	if closed {
+		return "", ErrClosed
+	}
+	return m.Generator(bizType, applicationName).GenerateId(applicationName)
+}
+
+// StatsByApp 按应用汇总各生成器的统计，汇总期间持有 Manager 的锁，不会出现新创建的生成器只统计了一部分的情况
+func (m *Manager) StatsByApp() map[string]AggregatedStats {
+	m.m.Lock()
+	defer m.m.Unlock()
+	result := make(map[string]AggregatedStats)
+	for key, usage := range m.generators {
+		s := usage.Stats()
+		agg := result[key.appName]
+		agg.Generators++
+		agg.Generated += s.Generated
+		agg.Fallbacks += s.Fallbacks
+		agg.Remaining += s.Remaining
+		result[key.appName] = agg
+	}
+	return result
+}
+
+// Close 关闭所有生成器，返回第一个关闭错误；之后的 GenerateId 返回 ErrClosed
+func (m *Manager) Close() error {
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.closed = true
+	var firstErr error
+	for _, usage := range m.generators {
+		if err := usage.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
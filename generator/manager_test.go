@@ -0,0 +1,101 @@
+package generator
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestManagerGeneratorConcurrent(t *testing.T) {
+	m := NewManager(newMemCaller().apply)
+	defer m.Close()
+	const workers = 16
+	got := make([]*RangeUsageInfoStruct, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			got[i] = m.Generator("ORD", "app")
+		}(i)
+	}
+	wg.Wait()
+	for i, usage := range got {
+		if usage != got[0] {
+			t.Fatalf("worker %d got a different generator for the same key", i)
+		}
+	}
+	if _, err := got[0].GenerateId("app"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManagerClosed(t *testing.T) {
+	m := NewManager(newMemCaller().apply)
+	if _, err := m.GenerateId("ORD", "app"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, bizType := range []string{"ORD", "PAY"} {
+		if _, err := m.GenerateId(bizType, "app"); !errors.Is(err, ErrClosed) {
+			t.Fatalf("GenerateId %s after Close error %v, want ErrClosed", bizType, err)
+		}
+		if _, err := m.Generator(bizType, "app").GenerateId("app"); !errors.Is(err, ErrClosed) {
+			t.Fatalf("Generator %s after Close error %v, want ErrClosed", bizType, err)
+		}
+	}
+	if stats := m.StatsByApp(); stats["app"].Generators != 1 {
+		t.Fatalf("StatsByApp %+v, want only the generator created before Close", stats)
+	}
+}
+
+// TestManagerStatsByApp 同一应用的多个业务类型按应用汇总，其它应用单独统计
+func TestManagerStatsByApp(t *testing.T) {
+	mem := newMemCaller()
+	caller := func(req *ApplyReq) (*NewRangeResp, error) {
+		if req.BizType == "BAD" {
+			return nil, errDown
+		}
+		return mem.apply(req)
+	}
+	m := NewManager(caller, WithStep(100))
+	defer m.Close()
+	counts := []struct {
+		bizType, appName string
+		n                int
+	}{
+		{"ORD", "app", 10},
+		{"PAY", "app", 5},
+		{"BAD", "app", 3},
+		{"ORD", "other", 7},
+	}
+	for _, c := range counts {
+		for i := 0; i < c.n; i++ {
+			if _, err := m.GenerateId(c.bizType, c.appName); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	stats := m.StatsByApp()
+	if len(stats) != 2 {
+		t.Fatalf("StatsByApp has %d apps, want 2", len(stats))
+	}
+	app := stats["app"]
+	if app.Generators != 3 || app.Generated != 18 || app.Fallbacks != 3 {
+		t.Fatalf("app stats %+v, want 3 generators, 18 generated, 3 fallbacks", app)
+	}
+	var remaining int64
+	for _, bizType := range []string{"ORD", "PAY", "BAD"} {
+		remaining += m.Generator(bizType, "app").Stats().Remaining
+	}
+	if app.Remaining != remaining || remaining <= 0 {
+		t.Fatalf("app remaining %d, want the sum of its generators %d", app.Remaining, remaining)
+	}
+	other := stats["other"]
+	if other.Generators != 1 || other.Generated != 7 || other.Fallbacks != 0 {
+		t.Fatalf("other stats %+v, want 1 generator, 7 generated, no fallbacks", other)
+	}
+}
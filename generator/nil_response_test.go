@@ -0,0 +1,78 @@
+package generator
+
+import (
+	"errors"
+	"testing"
+)
+
+// nilCaller 有 bug 的申请函数：既不返回号段也不返回错误
+func nilCaller(*ApplyReq) (*NewRangeResp, error) {
+	return nil, nil
+}
+
+func TestNilRangeResponse(t *testing.T) {
+	cases := []struct {
+		name         string
+		opts         []Option
+		wantFallback bool
+	}{
+		{"fallback policy", nil, true},
+		{"no fallback", []Option{WithNoFallback()}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			usage, err := NewWithOptions(nilCaller, testOptions(tc.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer usage.Close()
+			id, err := usage.GenerateId("app")
+			if !tc.wantFallback {
+				if !errors.Is(err, ErrSegmentUnavailable) || !errors.Is(err, ErrInvalidResponse) {
+					t.Fatalf("GenerateId %q, %v, want ErrSegmentUnavailable wrapping ErrInvalidResponse", id, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parts, err := usage.Parse(id); err != nil || !parts.Fallback {
+				t.Fatalf("%s is not a fallback id (%v)", id, err)
+			}
+			if stats := usage.Stats(); stats.RangeErrors == 0 {
+				t.Fatal("nil response was not counted as a range error")
+			}
+		})
+	}
+}
+
+func TestNilRangeResponseInt64(t *testing.T) {
+	usage, err := NewWithOptions(nilCaller, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	if _, err := usage.GenerateInt64("app"); !errors.Is(err, ErrSegmentUnavailable) {
+		t.Fatalf("GenerateInt64 error %v, want ErrSegmentUnavailable", err)
+	}
+}
+
+// TestNilRangeResponseBackup 主号段服务返回 nil 号段时按失败处理，改用备用号段服务
+func TestNilRangeResponseBackup(t *testing.T) {
+	backup := newMemCaller()
+	usage, err := NewWithOptions(nilCaller, testOptions(WithBackupCaller(backup.apply))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	id, err := usage.GenerateId("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts, err := usage.Parse(id); err != nil || parts.Fallback {
+		t.Fatalf("%s is a fallback id (%v)", id, err)
+	}
+	if backup.callCount() != 1 {
+		t.Fatalf("backup called %d times, want 1", backup.callCount())
+	}
+}
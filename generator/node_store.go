@@ -0,0 +1,139 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// NodeIDStore 持久化本实例的节点序号，重启后尽量取回同一个节点序号，Load 没有保存过时返回 false
+type NodeIDStore interface {
+	Load() (nodeID int, ok bool, err error)
+	Save(nodeID int) error
+}
+
+// NodeRegistry 集中分配节点序号的注册中心（如基于 etcd/zookeeper/数据库的实现）
+// Acquire 的 preferred 为上次持久化的节点序号，没有时为 -1，注册中心应在该序号空闲时优先分配它；
+// Release 在 Close 时调用，归还后注册中心应保留一段时间再分配给其他实例，给原实例重启取回留出时间
+type NodeRegistry interface {
+	Acquire(preferred int) (int, error)
+	Release(nodeID int) error
+}
+
+// FileNodeIDStore 以文本文件保存节点序号，文件应放在实例重启后仍然保留的目录（如挂载卷）
+type FileNodeIDStore struct {
+	path string
+}
+
+func NewFileNodeIDStore(path string) *FileNodeIDStore {
+	return &FileNodeIDStore{path: path}
+}
+
+func (s *FileNodeIDStore) Load() (int, bool, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	nodeID, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, false, err
+	}
+	return nodeID, true, nil
+}
+
+func (s *FileNodeIDStore) Save(nodeID int) error {
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.Itoa(nodeID)), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// EnvNodeIDStore 从环境变量读取节点序号（如 StatefulSet 的序号），只读，Save 不做任何事
+type EnvNodeIDStore struct {
+	name string
+}
+
+func NewEnvNodeIDStore(name string) *EnvNodeIDStore {
+	return &EnvNodeIDStore{name: name}
+}
+
+func (s *EnvNodeIDStore) Load() (int, bool, error) {
+	v, ok := os.LookupEnv(s.name)
+	if !ok || strings.TrimSpace(v) == "" {
+		return 0, false, nil
+	}
+	nodeID, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		return 0, false, fmt.Errorf("env %s: %w", s.name, err)
+	}
+	return nodeID, true, nil
+}
+
+func (s *EnvNodeIDStore) Save(int) error {
+	return nil
+}
+
+// resolveNodeID 构造时确定静态节点模式使用的节点序号：
+// 1. 从 NodeIDStore 读取上次使用的节点序号；
+// 2. 配置了 NodeRegistry 时以上次的序号作为首选向注册中心申请，否则直接使用上次的序号，都没有时使用 WithStaticNodeAssignment 传入的序号；
+// 3. 把最终使用的序号写回 NodeIDStore，下次重启时取回
+func (c *config) resolveNodeID() error {
+	if c.staticNodes == 0 || (c.nodeIDStore == nil && c.nodeRegistry == nil) {
+		return nil
+	}
+	preferred := -1
+	if c.nodeIDStore != nil {
+		nodeID, ok, err := c.nodeIDStore.Load()
+		if err != nil {
+			return fmt.Errorf("load node id: %w", err)
+		}
+		if ok {
+			preferred = nodeID
+		}
+	}
+	nodeID := preferred
+	if c.nodeRegistry != nil {
+		acquired, err := c.nodeRegistry.Acquire(preferred)
+		if err != nil {
+			return fmt.Errorf("acquire node id: %w", err)
+		}
+		if preferred >= 0 && acquired != preferred && c.logs != nil {
+			c.logs.Warn("节点序号 {} 已被占用，注册中心分配了新的节点序号 {}", preferred, acquired)
+		}
+		nodeID = acquired
+	}
+	if nodeID < 0 {
+		nodeID = c.staticNodeID
+	}
+	c.staticNodeID = nodeID
+	if c.nodeIDStore != nil {
+		if err := c.nodeIDStore.Save(nodeID); err != nil {
+			return fmt.Errorf("save node id: %w", err)
+		}
+	}
+	return nil
+}
+
+// releaseNodeID Close 时把节点序号归还注册中心，持久化的序号保留，重启后作为首选重新申请
+func (usage *RangeUsageInfoStruct) releaseNodeID() {
+	if usage.cfg.nodeRegistry == nil {
+		return
+	}
+	if err := usage.cfg.nodeRegistry.Release(usage.cfg.staticNodeID); err != nil {
+		usage.logs.Warn("{} {} 归还节点序号 {} 失败 {}", usage.bizType, usage.prefix, usage.cfg.staticNodeID, err.Error())
+	}
+}
+
+// NodeID 返回静态节点模式下本实例使用的节点序号，未开启静态节点模式时返回 -1
+func (usage *RangeUsageInfoStruct) NodeID() int {
+	if usage.cfg.staticNodes == 0 {
+		return -1
+	}
+	return usage.cfg.staticNodeID
+}
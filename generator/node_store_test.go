@@ -0,0 +1,132 @@
+package generator
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// fakeRegistry 内存中的节点序号注册中心，首选序号空闲时分配首选序号，否则分配最小的空闲序号
+type fakeRegistry struct {
+	m     sync.Mutex
+	total int
+	used  map[int]bool
+}
+
+func newFakeRegistry(total int) *fakeRegistry {
+	return &fakeRegistry{total: total, used: make(map[int]bool)}
+}
+
+func (r *fakeRegistry) Acquire(preferred int) (int, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if preferred >= 0 && !r.used[preferred] {
+		r.used[preferred] = true
+		return preferred, nil
+	}
+	for node := 0; node < r.total; node++ {
+		if !r.used[node] {
+			r.used[node] = true
+			return node, nil
+		}
+	}
+	return 0, errDown
+}
+
+func (r *fakeRegistry) Release(node int) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+	delete(r.used, node)
+	return nil
+}
+
+func newNodeUsage(t *testing.T, store NodeIDStore, registry NodeRegistry, node int) *RangeUsageInfoStruct {
+	t.Helper()
+	usage, err := NewWithOptions(nil, testOptions(WithStaticNodeAssignment(node, 4), WithNodeIDStore(store, registry))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return usage
+}
+
+// TestNodeIDReusedAfterRestart 实例重启后从持久化的节点序号取回同一个节点，生成的序号仍属于该节点
+func TestNodeIDReusedAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node-id")
+	registry := newFakeRegistry(4)
+	if _, err := registry.Acquire(0); err != nil {
+		t.Fatal(err)
+	}
+
+	first := newNodeUsage(t, NewFileNodeIDStore(path), registry, 0)
+	if first.NodeID() != 1 {
+		t.Fatalf("first start got node %d, want the free node 1", first.NodeID())
+	}
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := newNodeUsage(t, NewFileNodeIDStore(path), registry, 0)
+	defer restarted.Close()
+	if restarted.NodeID() != 1 {
+		t.Fatalf("restart got node %d, want the persisted node 1", restarted.NodeID())
+	}
+	for i := 0; i < 20; i++ {
+		id, err := restarted.GenerateId("app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seq := mustParse(t, restarted, id).Sequence; seq%4 != 1 {
+			t.Fatalf("%s after restart has sequence %d, not node 1's", id, seq)
+		}
+	}
+}
+
+func mustParse(t *testing.T, usage *RangeUsageInfoStruct, id string) *IdParts {
+	t.Helper()
+	parts, err := usage.Parse(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parts
+}
+
+func TestNodeIDTakenWhileDown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node-id")
+	registry := newFakeRegistry(4)
+	first := newNodeUsage(t, NewFileNodeIDStore(path), registry, 0)
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+	//重启之前节点序号被其他实例占用，分配新的序号并写回
+	if _, err := registry.Acquire(first.NodeID()); err != nil {
+		t.Fatal(err)
+	}
+	restarted := newNodeUsage(t, NewFileNodeIDStore(path), registry, 0)
+	defer restarted.Close()
+	if restarted.NodeID() == first.NodeID() {
+		t.Fatalf("restart reused node %d held by another instance", first.NodeID())
+	}
+	if saved, ok, err := NewFileNodeIDStore(path).Load(); err != nil || !ok || saved != restarted.NodeID() {
+		t.Fatalf("persisted node %d, %v, %v, want %d", saved, ok, err, restarted.NodeID())
+	}
+}
+
+func TestNodeIDStoreWithoutRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node-id")
+	first := newNodeUsage(t, NewFileNodeIDStore(path), nil, 2)
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+	restarted := newNodeUsage(t, NewFileNodeIDStore(path), nil, 0)
+	defer restarted.Close()
+	if restarted.NodeID() != 2 {
+		t.Fatalf("restart got node %d, want the persisted node 2", restarted.NodeID())
+	}
+
+	t.Setenv("NUMBERS_NODE_ID", "3")
+	fromEnv := newNodeUsage(t, NewEnvNodeIDStore("NUMBERS_NODE_ID"), nil, 0)
+	defer fromEnv.Close()
+	if fromEnv.NodeID() != 3 {
+		t.Fatalf("env store got node %d, want 3", fromEnv.NodeID())
+	}
+}
@@ -0,0 +1,1122 @@
+package generator
+
+import (
+	"expvar"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// config 生成器的可选配置，通过 Option 在构造时设置
+type config struct {
+	logs            LogInterface
+	prefix          string
+	bizType         string  //号段隔离的业务类型，默认与 prefix 相同
+	environment     string  //部署环境，折叠进申请号段的 BizType
+	rangeQueueDepth int     //预取号段队列深度，0 表示不预取，号段用完时在请求路径上同步申请
+	trustServerDay  bool    //服务端返回的号段日期与申请日期不一致时，是否以服务端日期为准
+	dateless        bool    //id 中不出现日期，日期折叠进定长序号
+	prefetchJitter  float64 //预取触发比例的随机浮动范围
+
+	coalesceWindow      time.Duration //合并并发号段申请的等待窗口，0 表示不合并
+	coalesceMaxMultiple int           //合并申请时步长的最大倍数
+
+	unhealthyThreshold int           //连续号段申请失败多少次后 IsHealthy 返回 false
+	breakerThreshold   int           //连续号段申请失败多少次后熔断，0 表示不熔断
+	breakerCooldown    time.Duration //熔断后多久放行探测请求
+
+	rawCaller       RawNumbersReqFunc //返回原始响应的申请函数，设置后代替构造时传入的申请函数
+	responseAdapter ResponseAdapter   //原始响应到号段的转换
+
+	maxFallbackPerMinute int //每分钟最多生成的降级 id 数，0 表示不限制
+
+	expvarName string //发布运行计数的 expvar 名称，为空时不发布
+
+	clock Clock
+
+	gapPolicy  GapPolicy
+	stateStore StateStore
+
+	bizCode string //id 中展示的业务线代码
+
+	dateSeparator string //日期与序号之间的分隔符，为空时日期与序号直接相连
+
+	legacyFormats []IdFormat //只用于解析的历史 id 格式，按注册顺序尝试
+
+	dayCheckInterval time.Duration //后台检测跨天的间隔，0 表示不检测
+
+	locker Locker //申请号段时持有的分布式锁
+
+	uuidNamespace *uuid.UUID //不为 nil 时以 UUID 形式输出 id
+
+	keyMap     map[byte]byte  //序号中数字到字符的映射，默认为 keyMap
+	keyInverse *inverseKeyMap //keyMap 的逆映射，由 normalize 计算，解析 id 时使用
+
+	shardFunc ShardFunc //不为 nil 时在 id 末尾追加分片字符
+
+	overflowToNextDay bool //当天序号用完后是否溢出到后一天
+
+	clientBlockSize int //客户端模式每次申请的号段大小，0 表示不开启
+
+	scramble bool //编码前打散序号
+
+	typeFlag         byte            //默认类型标识，0 表示不开启
+	typeFlagValidate func(byte) bool //类型标识的业务校验
+
+	diagnostic func(Diagnostic) //诊断事件回调
+
+	staticNodeID int //静态节点模式下本节点的序号
+	staticNodes  int //静态节点模式下的节点总数，0 表示不开启
+
+	onDayLifecycle func(DayEvent) //号段日期生命周期回调
+
+	maxIdLen int //id 的最大长度，0 表示不限制
+
+	nodeIDStore  NodeIDStore  //持久化静态节点序号
+	nodeRegistry NodeRegistry //分配静态节点序号的注册中心
+
+	seqRadix    int    //序号的进制，0 表示按十进制逐位映射
+	seqAlphabet string //自定义进制的字符表，由 normalize 根据 seqRadix 和 keyMap 生成
+	seqWidth    int    //自定义进制下序号的定长位数，0 表示默认位数，normalize 后为实际位数
+
+	safeCharset SafeCharset //序号部分允许出现的字符集合，0 表示不校验
+
+	selfCheck bool //每次生成后自检 id 是否满足约束
+
+	period Period //号段隔离与 id 中日期的周期，默认按天
+
+	backupCaller NumbersReqFunc //主号段服务失败时使用的备用申请函数
+
+	contention ContentionStrategy //并发申请号段争用时的处理策略
+
+	maxInflight  int           //同时进行的生成调用数上限，0 表示不限制
+	inflightWait time.Duration //达到上限时最多等待多久，0 表示直接返回 ErrBusy
+
+	instanceTag bool //正常 id 的序号之后是否追加实例标识
+
+	fallbackGrace time.Duration //降级之前等待号段申请完成或重试的最长时间，0 表示直接降级
+
+	fallbackGen FallbackGenerator //降级后缀的生成算法，nil 时使用默认的随机后缀
+
+	duplicateGuard int //重复 id 检测覆盖的最近 id 数，0 表示不检测
+
+	journal RangeJournal //记录每个申请到的号段，nil 表示不记录
+
+	futureDatePolicy    FutureDatePolicy //号段申请时间晚于当前时钟时的处理策略
+	futureDateTolerance time.Duration    //申请时间晚于当前时钟的容忍范围
+
+	idValidator func(id string) error //生成 id 后的校验函数，返回错误时跳过该 id
+
+	onFallback func(FallbackEvent) //生成降级 id 时的回调
+	onNewRange func(NewRangeEvent) //成功申请到号段时的回调
+
+	step      int        //申请号段的步长，0 表示使用 constIncrementStep
+	threshold int64      //剩余号码少于该值时申请新号段，0 表示使用 LeastAvailableIdNum
+	rander    *rand.Rand //降级 id、分片和预取抖动使用的随机数，nil 时按时间和进程号初始化
+	hostKey   string     //实例的主机标识，为空时使用 GetHostKey
+
+	checkChar bool //id 末尾是否追加校验字符
+
+	seqPad int //十进制序号补零后的最小位数，0 表示使用 constSeqPadWidth
+
+	packed       bool         //GenerateInt64 是否按打包布局返回
+	packedLayout packedLayout //打包布局中机器标识的位数和取值
+
+	format   string      //WithFormat 设置的 id 模板，为空时使用默认格式
+	template *idTemplate //由 validateFormat 根据 format 解析
+
+	dateLayout string //id 中日期部分的格式，为空时直接使用周期标识
+
+	location *time.Location //业务日期所在的时区，nil 时使用时钟返回的时区
+
+	prewarmWindow time.Duration //跨周期前多久提前申请下一周期的号段，0 表示不预热
+
+	appName string //申请号段使用的应用名，必须通过 WithAppName 设置
+
+	client NumbersClient //NewWithClient 传入的客户端，Close 时归还号段并关闭
+}
+
+type Option func(*config)
+
+const (
+	constEnvSeparator   = "@" //BizType 与部署环境的分隔符
+	constBizCodeMaxLen  = 4
+	constDateSeparators = "-_." //可用的日期分隔符，不能是数字映射结果中的字符
+)
+
+// GapPolicy 号码空洞策略
+type GapPolicy int
+
+const (
+	AllowGaps    GapPolicy = iota //默认，重启、跨天、预取都会留下未使用的号码
+	MinimizeGaps                  //尽量减少空洞：关闭时保存未用完的号段供重启后继续使用，不预取、不放大申请步长
+)
+
+func defaultConfig() config {
+	return config{
+		unhealthyThreshold:  constUnhealthyFailures,
+		clock:               systemClock{},
+		locker:              nopLocker{},
+		keyMap:              keyMap,
+		keyInverse:          defaultInverseKeyMap,
+		futureDateTolerance: constFutureDateTolerance,
+	}
+}
+
+// validate 校验配置，NewWithOptions 使用
+func (c *config) validate() error {
+	if err := validatePrefix(c.prefix); err != nil {
+		return err
+	}
+	if c.bizCode != "" && !isBizCode(c.bizCode) {
+		return fmt.Errorf("%w: biz code %q must be 1-%d uppercase letters or digits", ErrInvalidOption, c.bizCode, constBizCodeMaxLen)
+	}
+	if c.environment != "" && !isIdChars(c.environment) {
+		return fmt.Errorf("%w: environment %q contains invalid chars", ErrInvalidOption, c.environment)
+	}
+	if c.rangeQueueDepth < 0 {
+		return fmt.Errorf("%w: range queue depth %d is negative", ErrInvalidOption, c.rangeQueueDepth)
+	}
+	if c.prefetchJitter < 0 || c.prefetchJitter > constPrefetchRatio {
+		return fmt.Errorf("%w: prefetch jitter %v out of [0, %v]", ErrInvalidOption, c.prefetchJitter, constPrefetchRatio)
+	}
+	if c.prefetchJitter > 0 && c.rangeQueueDepth == 0 {
+		return fmt.Errorf("%w: prefetch jitter requires a range queue depth", ErrInvalidOption)
+	}
+	if c.coalesceWindow < 0 {
+		return fmt.Errorf("%w: coalesce window %s is negative", ErrInvalidOption, c.coalesceWindow)
+	}
+	if c.coalesceWindow > 0 && c.coalesceMaxMultiple < 1 {
+		return fmt.Errorf("%w: coalesce max multiple %d must be at least 1", ErrInvalidOption, c.coalesceMaxMultiple)
+	}
+	if c.unhealthyThreshold < 1 {
+		return fmt.Errorf("%w: health threshold %d must be at least 1", ErrInvalidOption, c.unhealthyThreshold)
+	}
+	if c.breakerThreshold < 0 || (c.breakerThreshold > 0 && c.breakerCooldown <= 0) {
+		return fmt.Errorf("%w: circuit breaker threshold %d cooldown %s", ErrInvalidOption, c.breakerThreshold, c.breakerCooldown)
+	}
+	if c.maxFallbackPerMinute < 0 {
+		return fmt.Errorf("%w: max fallback rate %d is negative", ErrInvalidOption, c.maxFallbackPerMinute)
+	}
+	if c.expvarName != "" && expvar.Get(c.expvarName) != nil {
+		return fmt.Errorf("%w: expvar %q already published", ErrInvalidOption, c.expvarName)
+	}
+	if c.gapPolicy == MinimizeGaps && (c.rangeQueueDepth > 0 || c.coalesceWindow > 0 || c.prewarmWindow > 0) {
+		return fmt.Errorf("%w: minimize gaps conflicts with range prefetch, next period prewarm and request coalescing", ErrInvalidOption)
+	}
+	if c.dateSeparator != "" && !isDateSeparator(c.dateSeparator) {
+		return fmt.Errorf("%w: date separator %q must be one of %q", ErrInvalidOption, c.dateSeparator, constDateSeparators)
+	}
+	if c.dateSeparator != "" && c.dateless {
+		return fmt.Errorf("%w: date separator conflicts with dateless mode", ErrInvalidOption)
+	}
+	if err := c.validateDateLayout(); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidOption, err.Error())
+	}
+	if err := c.validateFormat(); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidOption, err.Error())
+	}
+	if err := validateLegacyFormats(c.legacyFormats); err != nil {
+		return err
+	}
+	if err := validateKeyMap(c.keyMap, c.keySeparators(), c.dateGlued()); err != nil {
+		return err
+	}
+	if c.clientBlockSize < 0 {
+		return fmt.Errorf("%w: client side block size %d is negative", ErrInvalidOption, c.clientBlockSize)
+	}
+	if c.step < 0 || c.threshold < 0 {
+		return fmt.Errorf("%w: step %d threshold %d is negative", ErrInvalidOption, c.step, c.threshold)
+	}
+	if c.threshold > 0 && c.threshold >= int64(c.rangeStep()) {
+		return fmt.Errorf("%w: threshold %d must be less than step %d", ErrInvalidOption, c.threshold, c.rangeStep())
+	}
+	if c.typeFlag != 0 {
+		if err := c.validateTypeFlag(c.typeFlag); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidOption, err.Error())
+		}
+	}
+	if c.staticNodes != 0 && (c.staticNodes < 1 || c.staticNodes > constMaxStaticNodes || c.staticNodeID < 0 || c.staticNodeID >= c.staticNodes) {
+		return fmt.Errorf("%w: static node %d of %d out of range, at most %d nodes", ErrInvalidOption, c.staticNodeID, c.staticNodes, constMaxStaticNodes)
+	}
+	if c.staticNodes != 0 && c.dateless {
+		return fmt.Errorf("%w: static node assignment conflicts with dateless mode", ErrInvalidOption)
+	}
+	if !c.period.valid() {
+		return fmt.Errorf("%w: period %d", ErrInvalidOption, c.period)
+	}
+	if c.maxInflight < 0 || c.inflightWait < 0 {
+		return fmt.Errorf("%w: max inflight %d wait %v is negative", ErrInvalidOption, c.maxInflight, c.inflightWait)
+	}
+	if !c.futureDatePolicy.valid() || c.futureDateTolerance < 0 {
+		return fmt.Errorf("%w: future date policy %d tolerance %v", ErrInvalidOption, c.futureDatePolicy, c.futureDateTolerance)
+	}
+	if c.duplicateGuard < 0 {
+		return fmt.Errorf("%w: duplicate guard capacity %d is negative", ErrInvalidOption, c.duplicateGuard)
+	}
+	if c.fallbackGrace < 0 {
+		return fmt.Errorf("%w: fallback grace %v is negative", ErrInvalidOption, c.fallbackGrace)
+	}
+	if !c.contention.valid() {
+		return fmt.Errorf("%w: contention strategy %d", ErrInvalidOption, c.contention)
+	}
+	if c.period != PeriodDay && c.dateless {
+		return fmt.Errorf("%w: period %s conflicts with dateless mode", ErrInvalidOption, c.period)
+	}
+	if c.overflowToNextDay && c.dateless {
+		return fmt.Errorf("%w: overflow to next day conflicts with dateless mode, the sequence is not per day", ErrInvalidOption)
+	}
+	if err := c.validateRadix(); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidOption, err.Error())
+	}
+	if err := c.validatePacked(); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidOption, err.Error())
+	}
+	if err := c.validateSafeCharset(); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidOption, err.Error())
+	}
+	if c.maxIdLen < 0 {
+		return fmt.Errorf("%w: max id length %d is negative", ErrInvalidOption, c.maxIdLen)
+	}
+	if err := c.checkMaxIdLength(c.prefix); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidOption, err.Error())
+	}
+	if c.dayCheckInterval < 0 {
+		return fmt.Errorf("%w: day check interval %s is negative", ErrInvalidOption, c.dayCheckInterval)
+	}
+	if c.prewarmWindow < 0 {
+		return fmt.Errorf("%w: prewarm window %s is negative", ErrInvalidOption, c.prewarmWindow)
+	}
+	if c.checkChar && c.uuidNamespace != nil {
+		return fmt.Errorf("%w: check char conflicts with uuid output", ErrInvalidOption)
+	}
+	if c.seqPad < 0 || c.seqPad > constMaxSeqLen {
+		return fmt.Errorf("%w: sequence width %d out of [0, %d]", ErrInvalidOption, c.seqPad, constMaxSeqLen)
+	}
+	if (c.rawCaller == nil) != (c.responseAdapter == nil) {
+		return fmt.Errorf("%w: raw caller and response adapter must be set together", ErrInvalidOption)
+	}
+	return nil
+}
+
+// normalize 把不合法的配置修正为默认值，保证 New 不会失败
+func (c *config) normalize() {
+	if c.logs == nil {
+		c.logs = nopLogger{}
+	}
+	if c.bizType == "" {
+		c.bizType = c.prefix
+	}
+	if c.environment != "" {
+		c.bizType = c.bizType + constEnvSeparator + c.environment
+	}
+	if c.clock == nil {
+		c.clock = systemClock{}
+	}
+	if c.location != nil {
+		c.clock = locationClock{clock: c.clock, loc: c.location}
+	}
+	if c.locker == nil {
+		c.locker = nopLocker{}
+	}
+	if c.rangeQueueDepth < 0 {
+		c.rangeQueueDepth = 0
+	}
+	if c.prefetchJitter < 0 {
+		c.prefetchJitter = 0
+	}
+	if c.prefetchJitter > constPrefetchRatio {
+		c.prefetchJitter = constPrefetchRatio
+	}
+	if c.coalesceWindow < 0 {
+		c.coalesceWindow = 0
+	}
+	if c.clientBlockSize < 0 {
+		c.clientBlockSize = 0
+	}
+	if c.step < 0 {
+		c.step = 0
+	}
+	if c.threshold < 0 || (c.threshold > 0 && c.threshold >= int64(c.rangeStep())) {
+		c.logs.Warn("申请新号段的阈值 {} 不合法或不小于步长 {}，使用默认阈值", c.threshold, c.rangeStep())
+		c.threshold = 0
+	}
+	if c.coalesceMaxMultiple < 1 {
+		c.coalesceMaxMultiple = 1
+	}
+	if c.unhealthyThreshold < 1 {
+		c.unhealthyThreshold = constUnhealthyFailures
+	}
+	if c.breakerThreshold < 0 || c.breakerCooldown <= 0 {
+		c.breakerThreshold = 0
+	}
+	if c.responseAdapter == nil {
+		c.rawCaller = nil
+	}
+	if c.bizCode != "" && !isBizCode(c.bizCode) {
+		c.logs.Warn("业务线代码 {} 不合法，不在 id 中展示", c.bizCode)
+		c.bizCode = ""
+	}
+	if c.dateSeparator != "" && (c.dateless || !isDateSeparator(c.dateSeparator)) {
+		c.logs.Warn("日期分隔符 {} 不合法或与无日期模式冲突，日期与序号直接相连", c.dateSeparator)
+		c.dateSeparator = ""
+	}
+	if !c.period.valid() || (c.period != PeriodDay && c.dateless) {
+		c.logs.Warn("周期 {} 不合法或与无日期模式冲突，按天隔离号段", c.period)
+		c.period = PeriodDay
+	}
+	if c.overflowToNextDay && c.dateless {
+		c.logs.Warn("无日期模式的序号不按天隔离，不溢出到后一天")
+		c.overflowToNextDay = false
+	}
+	if err := c.validateDateLayout(); err != nil {
+		c.logs.Warn("日期格式不合法，id 中直接使用周期标识 {}", err.Error())
+		c.dateLayout = ""
+	}
+	if err := c.validateFormat(); err != nil {
+		c.logs.Warn("id 模板不合法，使用默认格式 {}", err.Error())
+		c.format = ""
+		_ = c.validateFormat() //设置了日期格式时按默认格式重新生成模板
+	}
+	if err := validateKeyMap(c.keyMap, c.keySeparators(), c.dateGlued()); err != nil {
+		c.logs.Warn("自定义数字映射不合法，使用默认映射 {}", err.Error())
+		c.keyMap = keyMap
+	}
+	if c.typeFlag != 0 {
+		if err := c.validateTypeFlag(c.typeFlag); err != nil {
+			c.logs.Warn("类型标识不合法，不开启类型标识 {}", err.Error())
+			c.typeFlag = 0
+		}
+	}
+	if err := validateLegacyFormats(c.legacyFormats); err != nil {
+		c.logs.Warn("历史 id 格式配置不合法，不解析历史格式 {}", err.Error())
+		c.legacyFormats = nil
+	}
+	if c.staticNodes != 0 && (c.staticNodes < 1 || c.staticNodes > constMaxStaticNodes || c.staticNodeID < 0 || c.staticNodeID >= c.staticNodes || c.dateless) {
+		c.logs.Warn("静态节点 {} / {} 不合法或与无日期模式冲突，不开启静态节点模式", c.staticNodeID, c.staticNodes)
+		c.staticNodes = 0
+	}
+	if c.maxInflight < 0 {
+		c.maxInflight = 0
+	}
+	if c.inflightWait < 0 {
+		c.inflightWait = 0
+	}
+	if c.fallbackGrace < 0 {
+		c.fallbackGrace = 0
+	}
+	if c.duplicateGuard < 0 {
+		c.duplicateGuard = 0
+	}
+	if !c.futureDatePolicy.valid() || c.futureDateTolerance < 0 {
+		c.logs.Warn("未来申请时间策略 {} 容忍范围 {} 不合法，使用默认值", c.futureDatePolicy, c.futureDateTolerance)
+		c.futureDatePolicy = RefreshFutureDate
+		c.futureDateTolerance = constFutureDateTolerance
+	}
+	if !c.contention.valid() {
+		c.logs.Warn("争用策略 {} 不合法，只申请单次使用的号码", c.contention)
+		c.contention = DegradeToSingle
+	}
+	c.normalizeSafeCharset()
+	if err := c.validateRadix(); err != nil {
+		c.logs.Warn("序号进制不合法，按十进制生成序号 {}", err.Error())
+		c.seqRadix = 0
+	}
+	if err := c.validatePacked(); err != nil {
+		c.logs.Warn("打包布局不合法，GenerateInt64 返回原始号码 {}", err.Error())
+		c.packed = false
+		c.packedLayout = packedLayout{}
+	}
+	c.keyInverse = defaultInverseKeyMap
+	if inv, err := invertKeyMap(c.keyMap); err == nil {
+		c.keyInverse = inv
+	}
+	if c.seqRadix != 0 {
+		c.seqAlphabet = radixAlphabet(c.keyMap, c.seqRadix)
+		c.seqWidth = radixWidthOf(c.seqRadix, c.seqWidth)
+	}
+	if c.checkChar && c.uuidNamespace != nil {
+		c.logs.Warn("校验字符与 UUID 输出冲突，不追加校验字符")
+		c.checkChar = false
+	}
+	if c.seqPad < 0 || c.seqPad > constMaxSeqLen {
+		c.logs.Warn("序号位数 {} 不合法，使用默认位数 {}", c.seqPad, constSeqPadWidth)
+		c.seqPad = 0
+	}
+	if c.maxIdLen < 0 {
+		c.maxIdLen = 0
+	}
+	if err := c.checkMaxIdLength(c.prefix); err != nil {
+		c.logs.Warn("配置的最大 id 长度不足，超长的 id 会返回错误 {}", err.Error())
+	}
+	if c.prewarmWindow < 0 {
+		c.prewarmWindow = 0
+	}
+	if c.gapPolicy == MinimizeGaps {
+		c.prewarmWindow = 0
+		c.rangeQueueDepth = 0
+		c.prefetchJitter = 0
+		c.coalesceWindow = 0
+	}
+	if c.expvarName != "" && expvar.Get(c.expvarName) != nil {
+		c.logs.Warn("expvar {} 已被占用，不再发布", c.expvarName)
+		c.expvarName = ""
+	}
+}
+
+// validatePrefix 前缀只能由字母、数字、下划线和 - 组成，为空时 id 以日期开头
+func validatePrefix(prefix string) error {
+	for i := 0; i < len(prefix); i++ {
+		if !isIdChar(prefix[i]) {
+			return fmt.Errorf("%w: prefix %q contains invalid char %q", ErrInvalidOption, prefix, prefix[i])
+		}
+	}
+	return nil
+}
+
+// validateLegacyFormats 历史格式必须有名称且不能重名，分隔符规则与 WithDateSeparator 相同
+func validateLegacyFormats(formats []IdFormat) error {
+	names := make(map[string]bool, len(formats))
+	for _, format := range formats {
+		if format.Name == "" || format.Name == CurrentFormat || names[format.Name] {
+			return fmt.Errorf("%w: legacy format name %q is empty or duplicated", ErrInvalidOption, format.Name)
+		}
+		names[format.Name] = true
+		if format.DateSeparator != "" && (format.Dateless || !isDateSeparator(format.DateSeparator)) {
+			return fmt.Errorf("%w: legacy format %q date separator %q", ErrInvalidOption, format.Name, format.DateSeparator)
+		}
+		if !format.Period.valid() || (format.Period != PeriodDay && format.Dateless) {
+			return fmt.Errorf("%w: legacy format %q period %d", ErrInvalidOption, format.Name, format.Period)
+		}
+		if format.Template != "" {
+			tpl, err := parseTemplate(format.Template)
+			if err != nil || tpl.hasDate() == format.Dateless {
+				return fmt.Errorf("%w: legacy format %q template %q", ErrInvalidOption, format.Name, format.Template)
+			}
+		}
+		if format.DateLayout != "" {
+			layout := config{dateLayout: format.DateLayout, period: format.Period, dateless: format.Dateless}
+			if err := layout.validateDateLayout(); err != nil {
+				return fmt.Errorf("%w: legacy format %q %s", ErrInvalidOption, format.Name, err.Error())
+			}
+		}
+		if format.Radix != 0 && (format.Radix < constMinRadix || format.Radix > constMaxRadix || format.Dateless) {
+			return fmt.Errorf("%w: legacy format %q sequence radix %d", ErrInvalidOption, format.Name, format.Radix)
+		}
+		if format.Radix != 0 {
+			if _, ok := radixCapacity(format.Radix, radixWidthOf(format.Radix, format.RadixWidth)); !ok || format.RadixWidth < 0 {
+				return fmt.Errorf("%w: legacy format %q sequence radix width %d", ErrInvalidOption, format.Name, format.RadixWidth)
+			}
+		}
+	}
+	return nil
+}
+
+func isIdChar(ch byte) bool {
+	return (ch >= 'A' && ch <= 'Z') || (ch >= 'a' && ch <= 'z') || (ch >= '0' && ch <= '9') || ch == '_' || ch == '-'
+}
+
+func isBizCode(code string) bool {
+	if len(code) == 0 || len(code) > constBizCodeMaxLen {
+		return false
+	}
+	for i := 0; i < len(code); i++ {
+		if !(code[i] >= 'A' && code[i] <= 'Z') && !(code[i] >= '0' && code[i] <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func isDateSeparator(sep string) bool {
+	return len(sep) == 1 && strings.Contains(constDateSeparators, sep)
+}
+
+func isIdChars(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isIdChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(format string, v ...any) {}
+func (nopLogger) Info(format string, v ...any)  {}
+func (nopLogger) Warn(format string, v ...any)  {}
+func (nopLogger) Error(format string, v ...any) {}
+
+// WithLogger 设置日志输出，不设置时丢弃日志
+func WithLogger(logs LogInterface) Option {
+	return func(c *config) {
+		c.logs = logs
+	}
+}
+
+// WithPrefix 设置 id 前缀，为空时 id 以日期（无日期模式下为序号）开头、不带开头的分隔符，此时需要通过 WithBizType 设置业务类型
+func WithPrefix(prefix string) Option {
+	return func(c *config) {
+		c.prefix = prefix
+	}
+}
+
+// WithBizType 设置申请号段时的业务类型，不设置时与前缀相同
+func WithBizType(bizType string) Option {
+	return func(c *config) {
+		c.bizType = bizType
+	}
+}
+
+// WithEnvironment 设置部署环境（如 staging、prod），申请号段时 BizType 变为 bizType@env，
+// 使共用同一个号段服务的不同环境拿到相互隔离的号段，不会因配置错误而分配出重叠的号段
+// 只影响发给号段服务的 ApplyReq.BizType，生成的 id 不变
+func WithEnvironment(env string) Option {
+	return func(c *config) {
+		c.environment = env
+	}
+}
+
+// WithRangeQueueDepth 设置后台预取号段队列的深度
+// 当前号段消耗过半后在后台把待用号段补齐到 n 个，当前号段用完时直接切换到队首号段，
+// 避免在请求路径上同步申请号段；n 为 0 时不预取（默认行为），n 为 1 即双缓冲（与 Leaf 的号段双 buffer 相同），
+// 一般 1 就足够，号段服务偶尔响应很慢、一个号段不够撑过一次申请时再加大
+func WithRangeQueueDepth(n int) Option {
+	return func(c *config) {
+		c.rangeQueueDepth = n
+	}
+}
+
+// WithTrustServerDay 设置服务端返回的号段日期（NewRangeResp.Day）与本地申请日期不一致时的处理方式
+// true 时号段隔离和 id 中的日期都使用服务端日期；false 时（默认）拒绝该号段，按号段申请失败处理
+func WithTrustServerDay(trust bool) Option {
+	return func(c *config) {
+		c.trustServerDay = trust
+	}
+}
+
+// WithDateless 设置无日期模式：id 中不再出现可读的日期，生成 prefix-定长编码 格式的 id；
+// 申请号段时所有日期共用一个序号空间（日期固定为 "dateless"），序号跨天继续递增，Parse 只能还原序号
+func WithDateless(dateless bool) Option {
+	return func(c *config) {
+		c.dateless = dateless
+	}
+}
+
+// WithPrefetchJitter 设置后台预取触发点的随机抖动，band 为号段宽度的比例，取值 0 ~ 0.5，需同时开启预取队列
+// 每个号段的预取触发比例在 [0.5-band, 0.5+band] 内随机选取，避免同时启动的实例集中请求号段服务
+func WithPrefetchJitter(band float64) Option {
+	return func(c *config) {
+		c.prefetchJitter = band
+	}
+}
+
+// WithRequestCoalescing 开启并发号段申请合并：号段耗尽时首个申请方等待 window，统计窗口内同时触发申请的数量，
+// 只发出一次 步长 * 申请方数量 的申请（最多 maxMultiple 倍），其它申请方等待并共用这个号段，
+// 代替默认的并发时申请单次号码的策略
+func WithRequestCoalescing(window time.Duration, maxMultiple int) Option {
+	return func(c *config) {
+		c.coalesceWindow = window
+		c.coalesceMaxMultiple = maxMultiple
+	}
+}
+
+// WithHealthThreshold 设置连续号段申请失败多少次后 IsHealthy 返回 false，默认 3 次
+func WithHealthThreshold(failures int) Option {
+	return func(c *config) {
+		c.unhealthyThreshold = failures
+	}
+}
+
+// WithCircuitBreaker 开启号段申请熔断：连续失败 failures 次后熔断，熔断期间不再请求号段服务（按申请失败处理），
+// cooldown 后放行一个探测请求，探测成功则恢复，失败则继续熔断
+func WithCircuitBreaker(failures int, cooldown time.Duration) Option {
+	return func(c *config) {
+		c.breakerThreshold = failures
+		c.breakerCooldown = cooldown
+	}
+}
+
+// WithRawCaller 设置返回服务端原始响应的号段申请函数，代替构造时传入的申请函数，需同时设置 WithResponseAdapter
+func WithRawCaller(caller RawNumbersReqFunc) Option {
+	return func(c *config) {
+		c.rawCaller = caller
+	}
+}
+
+// WithResponseAdapter 设置原始响应到号段的转换，用于对接返回 {max_id, step}、{base, step} 等格式的号段服务，
+// 常见的 {max_id, step} 格式可直接使用 MaxIdStepAdapter
+func WithResponseAdapter(adapter ResponseAdapter) Option {
+	return func(c *config) {
+		c.responseAdapter = adapter
+	}
+}
+
+// WithMaxFallbackRate 限制每分钟最多生成的降级 id 数，超过后 GenerateId 直接返回 ErrFallbackRateExceeded，
+// 让号段服务故障尽快暴露给上游，而不是持续生成大量降级 id
+func WithMaxFallbackRate(perMinute int) Option {
+	return func(c *config) {
+		c.maxFallbackPerMinute = perMinute
+	}
+}
+
+// WithExpvar 将生成数、降级数、号段申请数、申请失败数和当前号段使用率以 map 形式发布到 expvar 的 name 下，
+// 引入 expvar 包的 http 服务即可在 /debug/vars 查看，name 不能与已发布的变量重名
+func WithExpvar(name string) Option {
+	return func(c *config) {
+		c.expvarName = name
+	}
+}
+
+// WithClock 设置时间来源，用于决定 id 日期、跨天、熔断冷却和降级统计，默认使用系统时间
+func WithClock(clock Clock) Option {
+	return func(c *config) {
+		c.clock = clock
+	}
+}
+
+// WithStateStore 设置号段状态存储，配合 MinimizeGaps 使用
+func WithStateStore(store StateStore) Option {
+	return func(c *config) {
+		c.stateStore = store
+	}
+}
+
+// WithGapPolicy 设置号码空洞策略，MinimizeGaps 模式下 Close 时把未用完的号段保存到 StateStore，
+// 重启后同一天内继续使用，并且不预取号段、不放大申请步长，以少量可用性换取号码的连续性
+// 号段方案无法完全消除空洞：进程崩溃、跨天、并发申请的单次号码、降级 id 都会留下空洞
+func WithGapPolicy(policy GapPolicy) Option {
+	return func(c *config) {
+		c.gapPolicy = policy
+	}
+}
+
+// WithBizCodeInID 在 id 中展示业务线代码（1-4 位大写字母或数字），格式为 prefix-code-日期序号，
+// 便于一眼看出 id 来自哪个业务线，Parse 会解析出 BizCode；与申请号段时的 bizType 隔离无关
+func WithBizCodeInID(code string) Option {
+	return func(c *config) {
+		c.bizCode = code
+	}
+}
+
+// WithDateSeparator 在 id 的日期与序号之间加入分隔符，可选 -、_、.，如 sep 为 - 时生成 PREFIX-20240101-ACEF 格式的 id，
+// 降级 id 使用同样的格式；Parse 同时支持带分隔符和不带分隔符的 id，无日期模式下不可用
+func WithDateSeparator(sep string) Option {
+	return func(c *config) {
+		c.dateSeparator = sep
+	}
+}
+
+// WithLegacyFormats 注册只用于解析的历史 id 格式，Parse 按当前格式解析失败时按注册顺序依次尝试，
+// 建议从新到旧注册；生成 id 始终使用当前格式，调整格式后无需重新处理已存储的 id
+func WithLegacyFormats(formats ...IdFormat) Option {
+	return func(c *config) {
+		c.legacyFormats = append(c.legacyFormats, formats...)
+	}
+}
+
+// WithDayChangeCheck 每隔 interval 按 WithClock 的时间检查一次是否跨天，跨天后在后台提前申请新一天的号段，
+// 避免休眠、暂停或长时间空闲的实例在跨天后的第一次生成 id 时同步申请号段；后台协程在 Close 时退出
+func WithDayChangeCheck(interval time.Duration) Option {
+	return func(c *config) {
+		c.dayCheckInterval = interval
+	}
+}
+
+// WithDistributedLock 设置申请号段时持有的分布式锁，锁的 key 为 appName:bizType:日期，
+// 用于号段服务本身不能原子分配号段的场景；默认不加锁，获取锁失败（超时 3 秒）按号段申请失败处理
+func WithDistributedLock(locker Locker) Option {
+	return func(c *config) {
+		c.locker = locker
+	}
+}
+
+// WithUUIDOutput 以 UUID 形式输出 id：按原有格式生成 id 后，以 namespace 为命名空间派生确定的 v5 UUID，
+// 号段保证 id 不重复，因此得到的 UUID 也不重复；转换是单向的，Parse 和 DecodeKey 返回 ErrNotDecodable，
+// ReserveID 回滚时也无法归还号码
+func WithUUIDOutput(namespace uuid.UUID) Option {
+	return func(c *config) {
+		c.uuidNamespace = &namespace
+	}
+}
+
+// WithKeyMap 自定义序号中数字到字符的映射，代替默认的 0-9 -> A,C,E,F,H,N,Q,R,S,U
+// 构造时校验映射结果不能与分隔符、降级标记冲突，日期与序号直接相连时不能映射为数字，保证 Parse 没有歧义
+func WithKeyMap(m map[byte]byte) Option {
+	copied := make(map[byte]byte, len(m))
+	for k, v := range m {
+		copied[k] = v
+	}
+	return func(c *config) {
+		c.keyMap = copied
+	}
+}
+
+// WithShardFunc 在 id 末尾追加一个由序号计算出的分片字符（0-9、A-Z），分库分表的路由可以通过 ShardOf 直接取出，
+// 降级 id 以随机数计算分片字符；Parse 会去掉分片字符再解析序号，并在 IdParts.Shard 中返回
+func WithShardFunc(fn ShardFunc) Option {
+	return func(c *config) {
+		c.shardFunc = fn
+	}
+}
+
+// WithOverflowToNextDay 当天的序号空间用完后（WithSequenceRadix 定长编码的每天上限，十进制编码为 int64 上限），
+// 申请后一天的号段并在 id 中使用后一天的日期，而不是返回 ErrSequenceOverflow；这只是缓解容量不足的手段，
+// id 中的日期可能比实际生成时间晚一天，号段服务按日期独占分配，后一天实际生成的 id 不会与之重复；
+// 无日期模式的序号不按天隔离，不能同时使用
+func WithOverflowToNextDay(overflow bool) Option {
+	return func(c *config) {
+		c.overflowToNextDay = overflow
+	}
+}
+
+// WithClientSideMode 开启客户端模式：每次申请 blockSize 个号码的大号段，完全在本地发号，
+// 只在启动、跨天和号段完全用完时请求号段服务，而不是剩余号码低于 LeastAvailableIdNum 时提前申请，
+// 用于需要尽量降低号段服务 QPS 的场景；号段越大，重启留下的空洞越大
+func WithClientSideMode(blockSize int) Option {
+	return func(c *config) {
+		c.clientBlockSize = blockSize
+	}
+}
+
+// WithScramble 编码前对序号做可逆的打散变换（每 100 万个序号为一块，块内仿射变换），
+// 使连续生成的 id 分散到哈希分区的不同分片，避免热点；id 仍然唯一，Parse 和 DecodeKey 会还原原始序号
+func WithScramble(scramble bool) Option {
+	return func(c *config) {
+		c.scramble = scramble
+	}
+}
+
+// WithTypeFlag 在日期之后、序号之前加入一个字符的类型标识（如区分退款和支付），defaultFlag 用于 GenerateId，
+// GenerateIdOfType 可以指定其它类型；类型标识必须是字母或数字，不能与降级标记、数字映射结果冲突，
+// validate 不为 nil 时还需通过业务校验；Parse 会解析出 IdParts.TypeFlag，也可以用 TypeFlagOf 直接取出
+func WithTypeFlag(defaultFlag byte, validate func(byte) bool) Option {
+	return func(c *config) {
+		c.typeFlag = defaultFlag
+		c.typeFlagValidate = validate
+	}
+}
+
+// WithDiagnostic 设置诊断事件回调，号段服务出现连续返回相同号段等异常时调用，便于发现服务端问题；
+// 回调在生成 id 的调用路径上同步执行，不应阻塞
+func WithDiagnostic(fn func(Diagnostic)) Option {
+	return func(c *config) {
+		c.diagnostic = fn
+	}
+}
+
+// WithStaticNodeAssignment 开启静态节点模式，无需号段服务：节点总数固定时，每个节点独占 序号 ≡ nodeID (mod totalNodes) 的序号，
+// 只要各节点的 nodeID 不重复，生成的 id 就全局唯一；本地按当天已过去的毫秒数分配号段，重启后不会回退，
+// 但时钟回拨或重启前提前分配了大量号码时仍可能重复；构造时传入的申请函数不再使用，可以为 nil，最多 10000 个节点，不能与无日期模式同时使用
+func WithStaticNodeAssignment(nodeID, totalNodes int) Option {
+	return func(c *config) {
+		c.staticNodeID = nodeID
+		c.staticNodes = totalNodes
+	}
+}
+
+// OnDayLifecycle 设置号段日期生命周期回调：当天第一次拿到号段时回调 DayStart，之后每次切换号段回调 RangeRefresh，
+// 检测到跨天时先回调旧日期的 DayEnd；回调在锁外同步执行，对应的计数也可以通过 Stats 查看
+func OnDayLifecycle(fn func(DayEvent)) Option {
+	return func(c *config) {
+		c.onDayLifecycle = fn
+	}
+}
+
+// WithMaxIDLength 限制 id 的最大长度（如数据库字段为 VARCHAR(32)）：构造时按最坏情况（序号按 int64 最大位数、
+// 降级 id、类型标识、分片字符）校验前缀和各个组成部分，超过时 NewWithOptions 返回错误；
+// 运行时生成的 id（含追加前缀）超过 n 时返回 ErrIdTooLong，而不是交给下游截断
+func WithMaxIDLength(n int) Option {
+	return func(c *config) {
+		c.maxIdLen = n
+	}
+}
+
+// WithNodeIDStore 持久化静态节点模式的节点序号，重启后优先取回上次的序号，避免换了序号的新实例与旧实例未完成的 id 交叠；
+// registry 可以为 nil，设置时构造时以上次的序号为首选向注册中心申请，Close 时归还，详见 NodeRegistry
+func WithNodeIDStore(store NodeIDStore, registry NodeRegistry) Option {
+	return func(c *config) {
+		c.nodeIDStore = store
+		c.nodeRegistry = registry
+	}
+}
+
+// WithSequenceRadix 序号部分按 base 进制（2 到 62）编码为 width 位的定长字符串以缩短 id，前缀和日期格式不变：
+// 字符表前 10 位沿用 keyMap 的映射结果，之后依次为未使用的数字和字母。
+// 定长编码的首位不使用字符表的最后一个字符，每天最多 (base-1) * base^(width-1) 个序号，超出时返回 ErrSequenceOverflow，
+// 开启 WithOverflowToNextDay 时溢出到后一天；width 为 0 时取能容纳 1 亿个序号的最小位数（base36 为 6 位）。
+// 不能与无日期模式和静态节点模式同时使用
+func WithSequenceRadix(base int, width int) Option {
+	return func(c *config) {
+		c.seqRadix = base
+		c.seqWidth = width
+	}
+}
+
+// WithSafeCharset 限制序号部分（keyMap 映射结果、自定义进制字符表和类型标识）只能产生 set 中的安全字符，
+// 多个集合可以用 | 组合，如 WithSafeCharset(SafeURL|SafeCaseInsensitive)；不满足时 NewWithOptions 返回错误
+func WithSafeCharset(set SafeCharset) Option {
+	return func(c *config) {
+		c.safeCharset = set
+	}
+}
+
+// WithSelfCheck 开启自检调试模式，用于长时间压测：每次生成 id 后校验序号单调递增、日期与本地时钟一致、
+// 号段服务正常时没有生成降级 id，不满足时通过 WithDiagnostic 的回调上报 DiagnosticSelfCheck 事件；
+// 自检模式下生成 id 串行执行，不应在生产环境开启，关闭时没有额外开销
+func WithSelfCheck(enabled bool) Option {
+	return func(c *config) {
+		c.selfCheck = enabled
+	}
+}
+
+// WithPeriod 设置号段隔离与 id 中日期的周期（PeriodHour、PeriodDay、PeriodWeek、PeriodMonth），默认按天：
+// ApplyReq.Day 与 id 中的日期部分都使用对应的周期标识，跨周期时申请新的号段，号段服务需要按周期标识独占分配号段；
+// 不能与无日期模式同时使用
+func WithPeriod(p Period) Option {
+	return func(c *config) {
+		c.period = p
+	}
+}
+
+// WithBackupCaller 设置备用号段申请函数（如只读副本或其它区域的分配器），主号段服务申请失败或熔断时改用备用服务，
+// 两者都失败才降级生成随机 id；备用服务必须与主服务共享同一个号段空间（或分配互不重叠的号段），否则会生成重复 id，
+// 各自提供的号段数可以通过 Stats 的 PrimaryRanges、BackupRanges 查看
+func WithBackupCaller(caller NumbersReqFunc) Option {
+	return func(c *config) {
+		c.backupCaller = caller
+	}
+}
+
+// WithContentionStrategy 设置号段即将用完且已有其它协程在申请号段时的处理策略：
+// DegradeToSingle（默认）只申请 1 个单次号码，QueueAndWait 等待进行中的申请并共用它的号段，
+// ProportionalStep 按争用数量申请一小段号码，用于在号段服务压力与调用方请求次数之间取舍；
+// 希望同一时刻只有一个申请方请求号段服务（singleflight）、其它申请方既不申请单次号码也不降级时使用 QueueAndWait；
+// 开启 WithRequestCoalescing 时以合并申请为准
+func WithContentionStrategy(strategy ContentionStrategy) Option {
+	return func(c *config) {
+		c.contention = strategy
+	}
+}
+
+// WithMaxInflight 限制同时进行的生成调用数（舱壁隔离），保护号段服务并限制突发时的资源占用，与号段申请的限流相互独立：
+// 达到 n 个时新的调用最多等待 wait，仍没有名额则返回 ErrBusy，wait 为 0 时直接返回 ErrBusy；
+// 当前进行中的调用数和被拒绝的次数可以通过 Stats 的 Inflight、BusyRejects 查看
+func WithMaxInflight(n int, wait time.Duration) Option {
+	return func(c *config) {
+		c.maxInflight = n
+		c.inflightWait = wait
+	}
+}
+
+// WithInstanceTagInID 在正常 id 的序号之后（分片字符之前）追加 3 位大写字母的实例标识，与降级 id 中的标识相同，
+// 由 hostKey、进程号和启动时间计算，排查问题时可以从 id 追溯到生成它的实例；唯一性仍由序号保证，
+// Parse 会跳过实例标识并在 IdParts.InstanceTag 中返回
+func WithInstanceTagInID(enabled bool) Option {
+	return func(c *config) {
+		c.instanceTag = enabled
+	}
+}
+
+// WithFallbackGrace 设置降级宽限期：号段用完且没有拿到新号段时，先等待进行中的号段申请完成，
+// 没有进行中的申请时按短间隔重新申请，最多等待 d，仍然失败才生成降级 id，用于短暂抖动时减少降级 id；
+// 宽限期内生成调用会被阻塞，d 应小于调用方可以接受的延迟；使用 GenerateIdCtx 时 ctx 先到期则直接返回 ctx 的错误，不再降级
+func WithFallbackGrace(d time.Duration) Option {
+	return func(c *config) {
+		c.fallbackGrace = d
+	}
+}
+
+// WithFallbackGenerator 使用自定义算法生成降级 id 的后缀（如 Crockford base32、指定长度等），
+// 生成器仍负责判断何时降级、限流以及拼接前缀、日期、类型标识和分片字符；后缀不以 Y 开头时会自动补上，
+// 长度超过默认的 12 位时 WithMaxIDLength 的最坏长度校验不再覆盖降级 id；
+// 内置的算法有 RandomFallback（默认）、SnowflakeFallback、ULIDFallback 以及不降级、直接返回错误的 ErrorFallback
+func WithFallbackGenerator(gen FallbackGenerator) Option {
+	return func(c *config) {
+		c.fallbackGen = gen
+	}
+}
+
+// WithDuplicateGuard 开启重复 id 检测，用于开发和灰度期间尽早发现重复 id：用布隆过滤器记录最近 capacity 到 2*capacity 个 id，
+// 生成的 id 可能已经出现过时计入 Stats.DuplicateIds 并通过 WithDiagnostic 的回调上报 DiagnosticDuplicateId 事件；
+// 误判率不超过约 0.2%，每个 id 占用约 30 位内存，默认关闭
+func WithDuplicateGuard(capacity int) Option {
+	return func(c *config) {
+		c.duplicateGuard = capacity
+	}
+}
+
+// WithRangeJournal 每次成功申请到号段时调用 journal.Record 记录号段，用于审计和号段服务数据丢失后的恢复，
+// 可以使用 NewFileRangeJournal 写入本地文件；这只是客户端的记录，不能代替号段服务的持久化
+func WithRangeJournal(journal RangeJournal) Option {
+	return func(c *config) {
+		c.journal = journal
+	}
+}
+
+// WithFutureDatePolicy 设置号段申请时间晚于当前时钟超过 tolerance 时的处理策略（默认 RefreshFutureDate、容忍 1 分钟），
+// 避免时钟回拨或交接、恢复了偏快时钟申请的号段后继续用未来的日期生成 id；发生次数可以通过 Stats.FutureApplyDates 查看，
+// tolerance 应大于实例间时钟的正常偏差
+func WithFutureDatePolicy(policy FutureDatePolicy, tolerance time.Duration) Option {
+	return func(c *config) {
+		c.futureDatePolicy = policy
+		c.futureDateTolerance = tolerance
+	}
+}
+
+// WithIDValidator 设置 id 校验函数（如过滤编码后拼出不雅单词的 id、与保留列表冲突的 id），每个 id 返回之前调用：
+// 返回错误时跳过该号码继续生成下一个，连续拒绝超过 10 次返回 ErrIdRejected；校验的是最终返回的 id（开启 UUID 输出时为 UUID）。
+// 被跳过的号码不会再发放，会在序号中留下空洞，被拒绝的次数可以通过 Stats.RejectedIds 查看；校验函数可能被并发调用
+func WithIDValidator(fn func(id string) error) Option {
+	return func(c *config) {
+		c.idValidator = fn
+	}
+}
+
+// OnFallback 设置生成降级 id 时的回调，回调在生成调用中同步执行，事件带有 GenerateIdWithTags 传入的标签
+func OnFallback(fn func(FallbackEvent)) Option {
+	return func(c *config) {
+		c.onFallback = fn
+	}
+}
+
+// OnNewRange 设置成功申请到号段（含备用号段服务、后台预取）时的回调，回调在申请号段的路径上同步执行，
+// 由 GenerateIdWithTags 触发的申请带有调用方的标签
+func OnNewRange(fn func(NewRangeEvent)) Option {
+	return func(c *config) {
+		c.onNewRange = fn
+	}
+}
+
+// WithStep 设置申请号段的步长，不设置时为 constIncrementStep；步长越大申请号段越少，重启、跨天时浪费的号码越多
+// 开启 WithClientSideMode 时以客户端模式的块大小为准
+func WithStep(step int) Option {
+	return func(c *config) {
+		c.step = step
+	}
+}
+
+// WithThreshold 设置剩余号码少于多少个时申请新号段，不设置时为 LeastAvailableIdNum，必须小于步长；
+// 服务端实际分配的步长较小时阈值会相应缩小，见 NewRangeResp.GrantedStep
+func WithThreshold(threshold int64) Option {
+	return func(c *config) {
+		c.threshold = threshold
+	}
+}
+
+// WithRand 设置降级 id、降级分片字符和预取抖动使用的随机数，测试中可传入固定种子得到可重复的结果；
+// 生成器内部加锁使用，传入的 rand.Rand 不要再在其它地方使用
+func WithRand(r *rand.Rand) Option {
+	return func(c *config) {
+		c.rander = r
+	}
+}
+
+// WithHostKey 设置实例的主机标识，代替按本机 IP 计算的 GetHostKey，用于降级 id 的实例标识和 FallbackGenerator；
+// 容器中多个实例 IP 相同或取不到 IP 时，可以传入 pod 名称等更能区分实例的值
+func WithHostKey(hostKey string) Option {
+	return func(c *config) {
+		c.hostKey = hostKey
+	}
+}
+
+// WithNoFallback 关闭降级：号段用完且申请失败时生成调用返回 ErrSegmentUnavailable（带有申请失败的原因），
+// 不再生成随机 id，保证发出的 id 都来自号段服务分配的号段；与 WithFallbackGenerator(ErrorFallback()) 相同，
+// 可以配合 WithFallbackGrace 在返回错误之前等待一段时间
+func WithNoFallback() Option {
+	return func(c *config) {
+		c.fallbackGen = errorFallback{}
+	}
+}
+
+// WithCheckChar 在 id 末尾（分片字符之后）追加 1 位校验字符（Luhn mod 36，取值 0-9、A-Z），
+// 面向用户的表单可以用 Validate 离线拒绝输错单个字符或颠倒相邻字符的 id，不必查库；降级 id 同样带有校验字符。
+// 开启后 Parse 会先校验并去掉校验字符，ShardOf 取校验字符之前的分片字符；不能与 WithUUIDOutput 同时使用
+func WithCheckChar(enabled bool) Option {
+	return func(c *config) {
+		c.checkChar = enabled
+	}
+}
+
+// WithPlainDigits 序号不做映射，直接使用十进制数字，等同于把 0-9 映射为自身的 WithKeyMap；
+// 日期与序号直接相连时数字无法区分，需要同时设置 WithDateSeparator 或 WithDateless，否则 NewWithOptions 返回 ErrInvalidOption，New 使用默认映射。
+// 只接受数字单据号的系统可以使用 WithPlainDigits()、WithDateSeparator("-")、WithSeqWidth(6)，生成 ORD-20240601-000123 形式的 id
+func WithPlainDigits() Option {
+	return func(c *config) {
+		plain := make(map[byte]byte, 10)
+		for d := byte('0'); d <= '9'; d++ {
+			plain[d] = d
+		}
+		c.keyMap = plain
+	}
+}
+
+// WithSeqWidth 设置十进制序号补零后的最小位数，不设置或为 0 时为 6 位，序号超过该位数时按实际位数输出；
+// 无日期模式和 WithSequenceRadix 的序号是定长的，不受影响
+func WithSeqWidth(width int) Option {
+	return func(c *config) {
+		c.seqPad = width
+	}
+}
+
+// WithPackedInt64 GenerateInt64 改为返回打包后的 int64：最高位为 0，之后依次为距 2020-01-01 的天数（16 位）、
+// 号段分配的号码（47 - machineBits 位）、机器标识（machineBits 位），跨天也不会重复，且整体随日期和号码递增，
+// 适合作为 MySQL 聚簇索引的主键；机器标识只用于追溯生成的实例，号码本身已由号段服务保证当天唯一。
+// machineBits 最多 16 位，machineID 需在位数范围内，只能按天隔离号段，可以用 Unpack 拆分
+func WithPackedInt64(machineBits int, machineID int64) Option {
+	return func(c *config) {
+		c.packed = true
+		c.packedLayout = packedLayout{machineBits: machineBits, machineID: machineID}
+	}
+}
+
+// WithFormat 用模板控制 id 的组成顺序和分隔符，占位符为 {prefix}、{date}、{seq}，固定字符只能是 -、_、.，
+// 默认格式相当于 {prefix}-{date}{seq}，WithDateSeparator("-") 相当于 {prefix}-{date}-{seq}；不需要分隔符的系统可以使用 {prefix}{date}{seq}。
+// 前缀为空时省略 {prefix} 及其后的分隔符；无日期模式下模板不能包含 {date}，其它情况必须包含，不能与 WithDateSeparator 同时使用。
+// {date} 与 {seq} 直接相连时数字映射结果不能是数字；降级 id 使用同样的模板，Parse 按模板拆分
+func WithFormat(format string) Option {
+	return func(c *config) {
+		c.format = format
+	}
+}
+
+// WithDateLayout 设置 id 中日期部分的格式，可以是 Go 时间格式（如 0601、060102、2006-01-02）或 DateLayoutJulian，
+// 只改变 id 中日期的写法，ApplyReq.Day 和 IdParts.Day 仍为周期标识，号段仍按 WithPeriod 设置的周期切换；
+// 格式需定长、只输出数字和 -、_、.，且精确到所设置的周期，如按天隔离时不能使用 0601，按小时隔离时需包含小时；
+// 无日期模式下不可用
+func WithDateLayout(layout string) Option {
+	return func(c *config) {
+		c.dateLayout = layout
+	}
+}
+
+// WithLocation 设置业务日期所在的时区，id 中的日期、ApplyReq.Day 和跨天、跨周期的判断都按该时区计算，
+// 如服务器运行在 UTC 而业务日期按北京时间时使用 time.LoadLocation("Asia/Shanghai")；
+// 不设置时使用时钟返回的时区，默认的系统时钟为服务器本地时区，可以与 WithClock 同时使用
+func WithLocation(loc *time.Location) Option {
+	return func(c *config) {
+		c.location = loc
+	}
+}
+
+// WithNextDayPrewarm 在跨天（按 WithPeriod 设置的周期）前 window 内由后台协程提前申请下一天的第一个号段，
+// 跨天后第一批生成调用直接使用预热的号段，避免零点的订单高峰都等待同一次同步申请或降级为随机 id；
+// 号段服务需要支持按未来的日期分配号段，实例要先生成过 id 才会预热，预热失败时每 5 秒重试直到跨天；
+// 跨天前关闭实例时预热的号段不再使用，不能与 MinimizeGaps 同时使用，后台协程在 Close 时退出
+func WithNextDayPrewarm(window time.Duration) Option {
+	return func(c *config) {
+		c.prewarmWindow = window
+	}
+}
+
+// WithAppName 设置申请号段使用的应用名，必须设置：NewWithOptions 和 Init 没有设置时返回错误，New 创建的生成器每次生成都返回 ErrNotInitialized。
+// 生成调用传入不同的 applicationName 返回 ErrAppNameMismatch，传入空串时使用设置的应用名
+func WithAppName(name string) Option {
+	return func(c *config) {
+		c.appName = name
+	}
+}
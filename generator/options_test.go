@@ -0,0 +1,112 @@
+package generator
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewFunctionalOptions(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 2, 29, 8, 0, 0, 0, time.Local))
+	var steps []int
+	caller := func(req *ApplyReq) (*NewRangeResp, error) {
+		steps = append(steps, req.Step)
+		start := int64(len(steps)) * 1000
+		return &NewRangeResp{RangeStart: start, RangeEnd: start + int64(req.Step) - 1}, nil
+	}
+	usage := New(caller, WithAppName("app"), WithPrefix("ORD"), WithStep(20), WithThreshold(5), WithClock(clock), WithRand(rand.New(rand.NewSource(1))), WithHostKey("pod-a"))
+	var ids []string
+	for i := 0; i < 30; i++ {
+		id, err := usage.GenerateId("app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if !strings.HasPrefix(ids[0], "ORD-20240229") {
+		t.Fatalf("id %s does not use WithPrefix and WithClock", ids[0])
+	}
+	//步长 20、阈值 5：第一个号段用到剩余不足 5 个时申请第二个号段
+	if len(steps) != 2 || steps[0] != 20 || steps[1] != 20 {
+		t.Fatalf("requested steps %v, want [20 20]", steps)
+	}
+	if usage.hostKey != "pod-a" {
+		t.Fatalf("host key %q, want pod-a", usage.hostKey)
+	}
+}
+
+func TestNewNormalizesInvalidOptions(t *testing.T) {
+	cases := []struct {
+		name string
+		opt  Option
+	}{
+		{"negative step", WithStep(-1)},
+		{"threshold above step", WithThreshold(1 << 40)},
+		{"bad date separator", WithDateSeparator("/")},
+		{"bad seq width", WithSeqWidth(-3)},
+		{"bad period", WithPeriod(Period(99))},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewWithOptions(newMemCaller().apply, testOptions(tc.opt)...); err == nil {
+				t.Fatal("NewWithOptions accepted the invalid option")
+			}
+			usage := New(newMemCaller().apply, testOptions(tc.opt)...)
+			if _, err := usage.GenerateId("app"); err != nil {
+				t.Fatalf("New did not normalize the option: %v", err)
+			}
+		})
+	}
+}
+
+func TestSeqWidthBounds(t *testing.T) {
+	cases := []struct {
+		width   int
+		wantErr bool
+	}{
+		{0, false},
+		{1, false},
+		{constMaxSeqLen, false},
+		{-1, true},
+		{constMaxSeqLen + 1, true},
+	}
+	for _, tc := range cases {
+		_, err := NewWithOptions(newMemCaller().apply, testOptions(WithSeqWidth(tc.width))...)
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("WithSeqWidth(%d) error %v, want error %v", tc.width, err, tc.wantErr)
+		}
+		if err != nil && !strings.Contains(err.Error(), "[0, ") {
+			t.Fatalf("WithSeqWidth(%d) error %q does not state the accepted range", tc.width, err)
+		}
+	}
+}
+
+func TestNewWithOptionsRejectsInvalidConfig(t *testing.T) {
+	caller := newMemCaller().apply
+	cases := []struct {
+		name   string
+		caller NumbersReqFunc
+		opts   []Option
+	}{
+		{"nil caller", nil, testOptions()},
+		{"no app name", caller, []Option{WithPrefix("T")}},
+		{"no prefix or biz type", caller, []Option{WithAppName("app")}},
+		{"dateless with date separator", caller, testOptions(WithDateless(true), WithDateSeparator("-"))},
+		{"dateless with static nodes", caller, testOptions(WithDateless(true), WithStaticNodeAssignment(0, 2))},
+		{"dateless with radix", caller, testOptions(WithDateless(true), WithSequenceRadix(36, 0))},
+		{"minimize gaps with range queue", caller, testOptions(WithGapPolicy(MinimizeGaps), WithRangeQueueDepth(1))},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			usage, err := NewWithOptions(tc.caller, tc.opts...)
+			if !errors.Is(err, ErrInvalidOption) {
+				t.Fatalf("NewWithOptions error %v, want ErrInvalidOption", err)
+			}
+			if usage != nil {
+				t.Fatal("NewWithOptions returned a generator with an error")
+			}
+		})
+	}
+}
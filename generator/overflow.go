@@ -0,0 +1,68 @@
+package generator
+
+import "time"
+
+// exceedsDailyCap 序号是否超出了一天可用的序号空间：达到 int64 上限，或超出当前编码的每天上限 dailyCap
+func (usage *RangeUsageInfoStruct) exceedsDailyCap(seq int64) bool {
+	if seq == seqOverflow {
+		return true
+	}
+	limit := usage.cfg.dailyCap()
+	return limit > 0 && seq >= limit
+}
+
+// dailyCap 当前编码每天可用的序号数，WithSequenceRadix 的定长编码为 radixCapacity，十进制编码没有上限时返回 0
+func (c *config) dailyCap() int64 {
+	if c.seqRadix == 0 {
+		return 0
+	}
+	limit, _ := radixCapacity(c.seqRadix, radixWidthOf(c.seqRadix, c.seqWidth))
+	return limit
+}
+
+// requestDay 申请号段时使用的日期，当天序号用完并溢出到后一天后，当天剩余时间内都申请后一天的号段
+func (usage *RangeUsageInfoStruct) requestDay(today string) string {
+	if !usage.cfg.overflowToNextDay {
+		return today
+	}
+	usage.usageM.Lock()
+	defer usage.usageM.Unlock()
+	if usage.overflowFrom == today {
+		return usage.overflowDay
+	}
+	return today
+}
+
+// overflowNextDay 当天序号用完后申请后一天的号段，id 中的日期也使用后一天
+// 号段服务按日期独占分配号段，后一天真正到来时申请到的号段排在溢出号段之后，不会生成重复 id；按其它周期隔离时溢出到下一个周期
+func (usage *RangeUsageInfoStruct) overflowNextDay(now time.Time, idDay string) (int64, string) {
+	nextDay, err := usage.cfg.period.next(idDay, now.Location())
+	if err != nil {
+		return seqOverflow, idDay
+	}
+	req := ApplyReq{
+		AppName: usage.appName,
+		BizType: usage.bizType,
+		Day:     nextDay,
+		Step:    usage.step(),
+	}
+	usage.logs.Warn("{} {} {} {} 的序号已用完，溢出到 {}", usage.appName, usage.bizType, usage.prefix, idDay, req.Day)
+	resp, bUseOnce, err := usage.getNewIdRange(&req)
+	var rangeDay string
+	if err == nil {
+		rangeDay, err = usage.checkRangeDay(&req, resp)
+	}
+	if err != nil {
+		usage.logs.Error("{} {} {} 申请溢出号段出错 {}", usage.appName, usage.bizType, usage.prefix, err.Error())
+		return 0, rangeDay
+	}
+
+	usage.usageM.Lock()
+	usage.overflowFrom = usage.periodKey(now)
+	usage.overflowDay = req.Day
+	usage.usageM.Unlock()
+	if bUseOnce {
+		return usage.useOnce(resp, rangeDay), rangeDay
+	}
+	return usage.replaceRange(resp.RangeStart, resp.RangeEnd, now, rangeDay)
+}
@@ -0,0 +1,92 @@
+package generator
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestOverflowToNextDay base16 两位的序号每天最多 240 个，用完后溢出到后一天，后一天真正到来时继续递增不重复
+func TestOverflowToNextDay(t *testing.T) {
+	caller := newMemCaller()
+	clock := newFakeClock(time.Date(2024, 1, 1, 22, 0, 0, 0, time.Local))
+	usage, err := NewWithOptions(caller.apply, testOptions(WithClock(clock), WithStep(100), WithSequenceRadix(16, 2), WithOverflowToNextDay(true))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+
+	seen := make(map[string]bool)
+	days := make(map[string]int)
+	var lastNextDay int64
+	generate := func(n int) {
+		for i := 0; i < n; i++ {
+			id, err := usage.GenerateId("app")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if seen[id] {
+				t.Fatalf("%s issued twice", id)
+			}
+			seen[id] = true
+			parts, err := usage.Parse(id)
+			if err != nil || parts.Fallback {
+				t.Fatalf("%s is a fallback id (%v)", id, err)
+			}
+			days[parts.Day]++
+			if parts.Day == "20240102" {
+				if parts.Sequence <= lastNextDay {
+					t.Fatalf("%s sequence %d not above %d", id, parts.Sequence, lastNextDay)
+				}
+				lastNextDay = parts.Sequence
+			}
+		}
+	}
+
+	//当天的 240 个序号用完后改用后一天的号段
+	for i := 0; days["20240102"] == 0; i++ {
+		if i > 240 {
+			t.Fatalf("no overflow after %d ids: %v", i, days)
+		}
+		generate(1)
+	}
+	overflowed := days["20240102"]
+	generate(20)
+	if days["20240102"] != overflowed+20 || len(days) != 2 {
+		t.Fatalf("ids per day %v after overflowing, want the rest on 20240102", days)
+	}
+	//后一天真正到来后继续使用该日期的号段，序号接着溢出时用过的号码递增
+	clock.Add(3 * time.Hour)
+	generate(20)
+	if days["20240102"] != overflowed+40 {
+		t.Fatalf("ids per day %v on the next day, want all on 20240102", days)
+	}
+}
+
+func TestDailyCapWithoutOverflow(t *testing.T) {
+	caller := newMemCaller()
+	usage, err := NewWithOptions(caller.apply, testOptions(WithStep(100), WithSequenceRadix(16, 2), WithNoFallback())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	for i := 0; ; i++ {
+		_, err := usage.GenerateId("app")
+		if err == nil {
+			if i >= 240 {
+				t.Fatalf("%d ids generated past the daily cap of 240", i+1)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrSequenceOverflow) {
+			t.Fatalf("GenerateId past the daily cap error %v, want ErrSequenceOverflow", err)
+		}
+		break
+	}
+}
+
+func TestOverflowToNextDayConflictsWithDateless(t *testing.T) {
+	if _, err := NewWithOptions(newMemCaller().apply, testOptions(WithDateless(true), WithOverflowToNextDay(true))...); !errors.Is(err, ErrInvalidOption) {
+		t.Fatalf("NewWithOptions error %v, want ErrInvalidOption", err)
+	}
+}
@@ -0,0 +1,97 @@
+package generator
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	constPackedDayBits        = 16 //日期占用的位数，从 packedEpoch 起可用约 179 年
+	constPackedMaxMachineBits = 16 //机器标识最多占用的位数，保证序号至少有 31 位
+	constPackedBits           = 63 //最高位为符号位，始终为 0
+)
+
+// packedEpoch 打包布局中日期的起点，日期部分为距该日的天数
+var packedEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// PackedParts Unpack 拆分出的打包 int64 的各组成部分
+type PackedParts struct {
+	Day     string //号码所属日期，格式 20060102
+	Machine int64  //机器标识
+	Seq     int64  //号段分配的号码
+}
+
+// packedLayout 打包布局，machineBits 为 0 时不打包
+type packedLayout struct {
+	machineBits int
+	machineID   int64
+}
+
+func (l packedLayout) seqBits() int {
+	return constPackedBits - constPackedDayBits - l.machineBits
+}
+
+// valid 机器标识位数在范围内，移位不会出现负数或越界的位数
+func (l packedLayout) valid() bool {
+	return l.machineBits >= 0 && l.machineBits <= constPackedMaxMachineBits
+}
+
+// validatePacked 校验打包布局：机器标识在位数范围内，且只能按天隔离号段
+func (c *config) validatePacked() error {
+	if !c.packed {
+		return nil
+	}
+	l := c.packedLayout
+	if !l.valid() {
+		return fmt.Errorf("packed machine bits %d out of range 0-%d", l.machineBits, constPackedMaxMachineBits)
+	}
+	if l.machineID < 0 || l.machineID >= int64(1)<<l.machineBits {
+		return fmt.Errorf("packed machine id %d does not fit in %d bits", l.machineID, l.machineBits)
+	}
+	if c.period != PeriodDay {
+		return fmt.Errorf("packed int64 layout requires day period, got %s", c.period)
+	}
+	return nil
+}
+
+// packInt64 按 日期 | 序号 | 机器标识 的顺序拼成 int64，同一天内按序号有序，不同日期按日期有序
+func (usage *RangeUsageInfoStruct) packInt64(seq int64, day string) (int64, error) {
+	l := usage.cfg.packedLayout
+	if !l.valid() {
+		return 0, fmt.Errorf("%w: packed machine bits %d out of range 0-%d", ErrInvalidOption, l.machineBits, constPackedMaxMachineBits)
+	}
+	if seq < 0 || seq >= int64(1)<<l.seqBits() {
+		usage.logs.Error("{} {} {} 序号超出打包布局可用的位数 {} {}", usage.appName, usage.bizType, usage.prefix, seq, l.seqBits())
+		return 0, fmt.Errorf("%w: seq %d does not fit in %d packed bits", ErrSequenceOverflow, seq, l.seqBits())
+	}
+	t, err := time.ParseInLocation("20060102", day, time.UTC)
+	if err != nil {
+		return 0, fmt.Errorf("%w: day %q", ErrInvalidId, day)
+	}
+	days := int64(t.Sub(packedEpoch) / (24 * time.Hour))
+	if days < 0 || days >= 1<<constPackedDayBits {
+		return 0, fmt.Errorf("%w: day %s outside packed range", ErrSequenceOverflow, day)
+	}
+	return days<<(constPackedBits-constPackedDayBits) | seq<<l.machineBits | l.machineID, nil
+}
+
+// Unpack 把 WithPackedInt64 布局下 GenerateInt64 返回的 int64 拆分为日期、机器标识和序号，
+// 未开启打包布局或 v 为负数时返回 ErrInvalidId
+func (usage *RangeUsageInfoStruct) Unpack(v int64) (PackedParts, error) {
+	if !usage.cfg.packed {
+		return PackedParts{}, fmt.Errorf("%w: packed int64 layout not enabled", ErrInvalidId)
+	}
+	if v < 0 {
+		return PackedParts{}, fmt.Errorf("%w: packed int64 %d is negative", ErrInvalidId, v)
+	}
+	l := usage.cfg.packedLayout
+	if !l.valid() {
+		return PackedParts{}, fmt.Errorf("%w: packed machine bits %d out of range 0-%d", ErrInvalidOption, l.machineBits, constPackedMaxMachineBits)
+	}
+	days := v >> (constPackedBits - constPackedDayBits)
+	return PackedParts{
+		Day:     packedEpoch.AddDate(0, 0, int(days)).Format("20060102"),
+		Machine: v & (int64(1)<<l.machineBits - 1),
+		Seq:     v >> l.machineBits & (int64(1)<<l.seqBits() - 1),
+	}, nil
+}
@@ -0,0 +1,77 @@
+package generator
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPackedRoundTrip(t *testing.T) {
+	cases := []struct {
+		name        string
+		machineBits int
+		machineID   int64
+	}{
+		{"no machine bits", 0, 0},
+		{"8 bits", 8, 200},
+		{"max bits", constPackedMaxMachineBits, 1<<constPackedMaxMachineBits - 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := newFakeClock(time.Date(2024, 3, 1, 10, 0, 0, 0, time.Local))
+			usage, err := NewWithOptions(newMemCaller().apply, testOptions(WithClock(clock), WithStep(50), WithPackedInt64(tc.machineBits, tc.machineID))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var last, lastSeq int64
+			for i := 0; i < 120; i++ {
+				v, err := usage.GenerateInt64("app")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if v <= last {
+					t.Fatalf("packed value %d not above previous %d", v, last)
+				}
+				last = v
+				parts, err := usage.Unpack(v)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if parts.Day != "20240301" || parts.Machine != tc.machineID || parts.Seq <= lastSeq {
+					t.Fatalf("Unpack(%d) = %+v, previous seq %d", v, parts, lastSeq)
+				}
+				lastSeq = parts.Seq
+			}
+		})
+	}
+}
+
+func TestPackedInvalidLayout(t *testing.T) {
+	cases := []struct {
+		name        string
+		machineBits int
+		machineID   int64
+	}{
+		{"negative bits", -1, 0},
+		{"too many bits", constPackedMaxMachineBits + 1, 0},
+		{"machine id too large", 4, 16},
+		{"negative machine id", 4, -1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			opt := WithPackedInt64(tc.machineBits, tc.machineID)
+			if _, err := NewWithOptions(newMemCaller().apply, testOptions(opt)...); !errors.Is(err, ErrInvalidOption) {
+				t.Fatalf("NewWithOptions: got %v, want ErrInvalidOption", err)
+			}
+			//New 不校验，关闭打包布局后返回原始号码
+			usage := New(newMemCaller().apply, append(testOptions(), opt)...)
+			v, err := usage.GenerateInt64("app")
+			if err != nil || v != 1 {
+				t.Fatalf("GenerateInt64 = %d, %v, want 1", v, err)
+			}
+			if _, err := usage.Unpack(v); !errors.Is(err, ErrInvalidId) {
+				t.Fatalf("Unpack: got %v, want ErrInvalidId", err)
+			}
+		})
+	}
+}
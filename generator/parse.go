@@ -0,0 +1,281 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const constFallbackMarker = 'Y' //降级随机 id 后缀的首字符，不在 keyMap 的映射结果中
+
+var ErrInvalidId = codedError("INVALID_ID", "invalid id")
+
+// IdParts 从 id 中解析出的各部分
+type IdParts struct {
+	Prefix   string //含追加前缀，如 ORD-PAY
+	BizCode  string //开启 WithBizCodeInID 时 id 中的业务线代码
+	Day      string //日期，格式 20060102，WithPeriod 设置了其它周期时为周期标识，无日期模式下为空
+	Sequence int64  //号段内的序号，降级 id 为 0
+	Fallback bool   //是否为降级随机生成的 id
+	Format   string //匹配到的格式名称，当前生成格式为 CurrentFormat
+	Shard    byte   //开启 WithShardFunc 时 id 末尾的分片字符
+	TypeFlag byte   //开启 WithTypeFlag 时序号之前的类型标识
+
+	InstanceTag string //生成 id 的实例标识，默认算法生成的降级 id 固定带有，正常 id 在开启 WithInstanceTagInID 时带有
+
+	AppendPrefix string //GenerateIdWithAppendPrefix 追加的前缀，如 PAY；Prefix 不是以当前实例的前缀开头时为空
+}
+
+// IdFormat 描述一种 id 格式，通过 WithLegacyFormats 注册后 Parse 可以解析格式调整之前生成的历史 id
+type IdFormat struct {
+	Name          string //格式名称，解析成功时写入 IdParts.Format
+	DateSeparator string //日期与序号之间的分隔符，为空时日期与序号直接相连
+	Dateless      bool   //无日期模式
+	BizCode       bool   //id 中是否带业务线代码
+	Shard         bool   //id 末尾是否带分片字符
+	Scramble      bool   //序号是否经过打散
+	TypeFlag      bool   //序号之前是否带类型标识
+	Radix         int    //序号的进制，0 表示按十进制逐位映射
+	Period        Period //日期部分的周期，默认按天
+	InstanceTag   bool   //正常 id 的序号之后是否带实例标识
+	CheckChar     bool   //id 末尾是否带校验字符
+	Template      string //WithFormat 设置的 id 模板，设置后忽略 DateSeparator
+	DateLayout    string //WithDateLayout 设置的日期格式，为空时日期部分为周期标识
+	RadixWidth    int    //自定义进制的序号位数，0 表示 WithSequenceRadix 的默认位数
+}
+
+// CurrentFormat 按当前生成格式解析成功时 IdParts.Format 的值
+const CurrentFormat = "current"
+
+// Parse 先按当前实例的生成格式解析 id，失败时按 WithLegacyFormats 注册的顺序依次尝试历史格式，
+// IdParts.Format 为匹配到的格式名称；都不匹配时返回按当前格式解析的错误
+func (usage *RangeUsageInfoStruct) Parse(id string) (*IdParts, error) {
+	if usage.cfg.uuidNamespace != nil {
+		return nil, fmt.Errorf("%w: %s is a uuid", ErrNotDecodable, id)
+	}
+	parts, err := parseFormat(id, usage.currentFormat(), usage.cfg.keyMap, usage.cfg.keyInverse)
+	if err == nil {
+		usage.splitAppendPrefix(parts)
+		return parts, nil
+	}
+	for _, format := range usage.cfg.legacyFormats {
+		if legacy, legacyErr := parseFormat(id, format, usage.cfg.keyMap, usage.cfg.keyInverse); legacyErr == nil {
+			usage.splitAppendPrefix(legacy)
+			return legacy, nil
+		}
+	}
+	return nil, err
+}
+
+// splitAppendPrefix 按当前实例的前缀从 Prefix 中分出追加的前缀，Prefix 保持不变
+func (usage *RangeUsageInfoStruct) splitAppendPrefix(parts *IdParts) {
+	prefix := usage.currentPrefix()
+	if prefix == "" {
+		parts.AppendPrefix = parts.Prefix
+		return
+	}
+	if strings.HasPrefix(parts.Prefix, prefix+"-") {
+		parts.AppendPrefix = parts.Prefix[len(prefix)+1:]
+	}
+}
+
+func (usage *RangeUsageInfoStruct) currentFormat() IdFormat {
+	return IdFormat{
+		Name:          CurrentFormat,
+		DateSeparator: usage.cfg.dateSeparator,
+		Dateless:      usage.cfg.dateless,
+		BizCode:       usage.cfg.bizCode != "",
+		Shard:         usage.cfg.shardFunc != nil,
+		Scramble:      usage.cfg.scramble,
+		TypeFlag:      usage.cfg.typeFlag != 0,
+		Radix:         usage.cfg.seqRadix,
+		RadixWidth:    usage.cfg.seqWidth,
+		Period:        usage.cfg.period,
+		InstanceTag:   usage.cfg.instanceTag,
+		CheckChar:     usage.cfg.checkChar,
+		Template:      usage.cfg.format,
+		DateLayout:    usage.cfg.dateLayout,
+	}
+}
+
+func parseFormat(id string, format IdFormat, keyMap map[byte]byte, inv *inverseKeyMap) (*IdParts, error) {
+	parts := &IdParts{Format: format.Name}
+	if format.CheckChar {
+		var err error
+		if id, err = stripCheckChar(id); err != nil {
+			return nil, err
+		}
+	}
+	var rest string
+	if format.Template != "" || format.DateLayout != "" {
+		template := format.Template
+		if template == "" {
+			template = defaultTemplate(format.DateSeparator, format.Dateless)
+		}
+		tpl, err := parseTemplate(template)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidId, err.Error())
+		}
+		dates := dateCodec{layout: format.DateLayout, period: format.Period}
+		if parts.Prefix, parts.Day, rest, err = tpl.split(id, dates); err != nil {
+			return nil, err
+		}
+	} else if format.Dateless {
+		//没有分隔符时为不带前缀的 id
+		if pos := strings.LastIndexByte(id, '-'); pos >= 0 {
+			parts.Prefix, rest = id[:pos], id[pos+1:]
+		} else {
+			rest = id
+		}
+	} else {
+		var err error
+		parts.Prefix, parts.Day, rest, err = splitDay(id, format.DateSeparator, format.Period)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if format.BizCode {
+		if parts.Prefix == "" {
+			return nil, fmt.Errorf("%w: %s missing biz code", ErrInvalidId, id)
+		}
+		//前缀为空时业务线代码位于 id 开头
+		if codePos := strings.LastIndexByte(parts.Prefix, '-'); codePos >= 0 {
+			parts.Prefix, parts.BizCode = parts.Prefix[:codePos], parts.Prefix[codePos+1:]
+		} else {
+			parts.Prefix, parts.BizCode = "", parts.Prefix
+		}
+	}
+
+	if format.Shard {
+		if rest == "" || !isShardChar(rest[len(rest)-1]) {
+			return nil, fmt.Errorf("%w: %s missing shard char", ErrInvalidId, id)
+		}
+		rest, parts.Shard = rest[:len(rest)-1], rest[len(rest)-1]
+	}
+
+	if format.TypeFlag {
+		if rest == "" {
+			return nil, fmt.Errorf("%w: %s missing type flag", ErrInvalidId, id)
+		}
+		parts.TypeFlag, rest = rest[0], rest[1:]
+	}
+
+	if rest == "" {
+		return nil, fmt.Errorf("%w: %s missing sequence", ErrInvalidId, id)
+	}
+	if rest[0] == constFallbackMarker {
+		parts.Fallback = true
+		if len(rest) > constFallbackTagLen {
+			parts.InstanceTag = rest[1 : 1+constFallbackTagLen]
+		}
+		return parts, nil
+	}
+
+	if format.InstanceTag {
+		if len(rest) <= constFallbackTagLen {
+			return nil, fmt.Errorf("%w: %s missing instance tag", ErrInvalidId, id)
+		}
+		rest, parts.InstanceTag = rest[:len(rest)-constFallbackTagLen], rest[len(rest)-constFallbackTagLen:]
+	}
+
+	var err error
+	if format.Radix != 0 {
+		parts.Sequence, err = decodeRadix(rest, radixAlphabet(keyMap, format.Radix), radixWidthOf(format.Radix, format.RadixWidth))
+	} else {
+		parts.Sequence, err = parseDigits(rest, inv, format, parts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s %s", ErrInvalidId, id, err.Error())
+	}
+	if format.Scramble {
+		parts.Sequence = unscrambleSeq(parts.Sequence)
+	}
+	return parts, nil
+}
+
+// parseDigits 还原按位映射的十进制序号，无日期模式下的序号与日期无关，不还原日期
+func parseDigits(rest string, inv *inverseKeyMap, format IdFormat, parts *IdParts) (int64, error) {
+	digits, err := decodeDigits(rest, inv)
+	if err != nil {
+		return 0, err
+	}
+	if format.Dateless {
+		return splitDatelessKey(digits)
+	}
+	return strconv.ParseInt(digits, 10, 64)
+}
+
+// DecodeKey 将 GenerateKey 生成的后缀还原为序号
+// UUID 输出模式下返回 ErrNotDecodable
+func (usage *RangeUsageInfoStruct) DecodeKey(key string) (int64, error) {
+	if usage.cfg.uuidNamespace != nil {
+		return 0, ErrNotDecodable
+	}
+	var seq int64
+	var err error
+	if usage.cfg.seqRadix != 0 {
+		seq, err = decodeRadix(key, usage.cfg.seqAlphabet, usage.cfg.seqWidth)
+	} else {
+		var digits string
+		digits, err = decodeDigits(key, usage.cfg.keyInverse)
+		if err != nil {
+			return 0, err
+		}
+		seq, err = strconv.ParseInt(digits, 10, 64)
+	}
+	if err != nil || !usage.cfg.scramble {
+		return seq, err
+	}
+	return unscrambleSeq(seq), nil
+}
+
+// decodeDigits 按预先计算的逆映射把序号字符还原为数字
+func decodeDigits(key string, inv *inverseKeyMap) (string, error) {
+	digits := make([]byte, len(key))
+	for i := 0; i < len(key); i++ {
+		digit := inv[key[i]]
+		if digit == 0 {
+			return "", fmt.Errorf("unknown key char %q", key[i])
+		}
+		digits[i] = digit
+	}
+	return string(digits), nil
+}
+
+// splitDay 把带日期的 id 拆成前缀、日期和序号三部分，不带前缀的 id 前缀为空
+// 配置了日期分隔符时先按 前缀-日期<分隔符>序号 拆分，不符合时再按日期与序号直接相连的格式拆分，兼容配置分隔符之前生成的 id
+func splitDay(id string, sep string, period Period) (string, string, string, error) {
+	keyLen := period.keyLen()
+	if sep != "" {
+		if pos := strings.LastIndex(id, sep); pos > 0 {
+			head := id[:pos]
+			if dayPos := len(head) - keyLen - 1; dayPos >= 0 && head[dayPos] == '-' && period.isKey(head[dayPos+1:]) {
+				return head[:dayPos], head[dayPos+1:], id[pos+len(sep):], nil
+			}
+			if len(head) == keyLen && period.isKey(head) {
+				return "", head, id[pos+len(sep):], nil
+			}
+		}
+	}
+
+	prefix, rest := "", id
+	if pos := strings.LastIndexByte(id, '-'); pos >= 0 {
+		prefix, rest = id[:pos], id[pos+1:]
+	}
+	if len(rest) <= keyLen {
+		return "", "", "", fmt.Errorf("%w: %s too short", ErrInvalidId, id)
+	}
+	if !period.isKey(rest[:keyLen]) {
+		return "", "", "", fmt.Errorf("%w: %s bad date", ErrInvalidId, id)
+	}
+	return prefix, rest[:keyLen], rest[keyLen:], nil
+}
+
+func isDay(s string) bool {
+	if len(s) != 8 {
+		return false
+	}
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
@@ -0,0 +1,140 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Period 号段隔离与 id 中日期的周期，ApplyReq.Day 和 id 中的日期部分都使用周期标识
+type Period int
+
+const (
+	PeriodDay   Period = iota //按天，周期标识格式 20060102，默认
+	PeriodHour                //按小时，周期标识格式 2006010215，适合量大且希望序号较短的场景
+	PeriodWeek                //按 ISO 周，周期标识格式 2006W01，周一为一周的开始
+	PeriodMonth               //按月，周期标识格式 200601，适合量小的场景
+)
+
+func (p Period) valid() bool {
+	return p >= PeriodDay && p <= PeriodMonth
+}
+
+func (p Period) String() string {
+	switch p {
+	case PeriodHour:
+		return "hour"
+	case PeriodWeek:
+		return "week"
+	case PeriodMonth:
+		return "month"
+	default:
+		return "day"
+	}
+}
+
+// keyLen 周期标识的长度
+func (p Period) keyLen() int {
+	switch p {
+	case PeriodHour:
+		return 10
+	case PeriodWeek:
+		return 7
+	case PeriodMonth:
+		return 6
+	default:
+		return 8
+	}
+}
+
+// key 时间 t 所在周期的标识
+func (p Period) key(t time.Time) string {
+	switch p {
+	case PeriodHour:
+		return t.Format("2006010215")
+	case PeriodWeek:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%04dW%02d", year, week)
+	case PeriodMonth:
+		return t.Format("200601")
+	default:
+		return t.Format("20060102")
+	}
+}
+
+// start 周期标识对应周期的开始时间
+func (p Period) start(key string, loc *time.Location) (time.Time, error) {
+	if len(key) != p.keyLen() {
+		return time.Time{}, fmt.Errorf("%w: %s period key %q", ErrInvalidId, p, key)
+	}
+	switch p {
+	case PeriodHour:
+		return time.ParseInLocation("2006010215", key, loc)
+	case PeriodWeek:
+		year, err := strconv.Atoi(key[:4])
+		if err != nil || key[4] != 'W' {
+			return time.Time{}, fmt.Errorf("%w: week period key %q", ErrInvalidId, key)
+		}
+		week, err := strconv.Atoi(key[5:])
+		if err != nil || week < 1 || week > 53 {
+			return time.Time{}, fmt.Errorf("%w: week period key %q", ErrInvalidId, key)
+		}
+		//1 月 4 日总在第 1 周内，退到当周周一
+		jan4 := time.Date(year, 1, 4, 0, 0, 0, 0, loc)
+		monday := jan4.AddDate(0, 0, -((int(jan4.Weekday())+6)%7)+(week-1)*7)
+		if y, w := monday.ISOWeek(); y != year || w != week {
+			return time.Time{}, fmt.Errorf("%w: week period key %q", ErrInvalidId, key)
+		}
+		return monday, nil
+	case PeriodMonth:
+		return time.ParseInLocation("200601", key, loc)
+	default:
+		return time.ParseInLocation("20060102", key, loc)
+	}
+}
+
+// startOf 时间 t 所在周期的开始时间
+func (p Period) startOf(t time.Time) time.Time {
+	start, _ := p.start(p.key(t), t.Location())
+	return start
+}
+
+// next 周期标识的下一个周期
+func (p Period) next(key string, loc *time.Location) (string, error) {
+	start, err := p.start(key, loc)
+	if err != nil {
+		return "", err
+	}
+	switch p {
+	case PeriodHour:
+		return p.key(start.Add(time.Hour)), nil
+	case PeriodWeek:
+		return p.key(start.AddDate(0, 0, 7)), nil
+	case PeriodMonth:
+		return p.key(start.AddDate(0, 1, 0)), nil
+	default:
+		return p.key(start.AddDate(0, 0, 1)), nil
+	}
+}
+
+// isKey 判断 s 是否为合法的周期标识
+func (p Period) isKey(s string) bool {
+	if p == PeriodDay {
+		return isDay(s)
+	}
+	_, err := p.start(s, time.UTC)
+	return err == nil
+}
+
+// periodKey 按配置的周期计算时间 t 的周期标识
+func (usage *RangeUsageInfoStruct) periodKey(t time.Time) string {
+	return usage.cfg.period.key(t)
+}
+
+// samePeriod 两个时间是否在同一个周期内，零值时间不与任何时间同周期
+func (usage *RangeUsageInfoStruct) samePeriod(a, b time.Time) bool {
+	if a.IsZero() || b.IsZero() {
+		return false
+	}
+	return usage.periodKey(a) == usage.periodKey(b)
+}
@@ -0,0 +1,59 @@
+package generator
+
+import (
+	"testing"
+	"time"
+)
+
+// TestPeriodRollover 小时、周、月周期在周期边界切换号段，周期内不重复申请，申请参数和 id 中都使用周期标识
+func TestPeriodRollover(t *testing.T) {
+	cases := []struct {
+		name         string
+		period       Period
+		start        time.Time
+		within       time.Duration //仍在同一周期内
+		across       time.Duration //跨过周期边界
+		first, later string
+	}{
+		{"hourly", PeriodHour, time.Date(2024, 1, 1, 10, 30, 0, 0, time.Local), 29 * time.Minute, 2 * time.Minute, "2024010110", "2024010111"},
+		{"weekly", PeriodWeek, time.Date(2024, 1, 3, 12, 0, 0, 0, time.Local), 4 * 24 * time.Hour, 12 * time.Hour, "2024W01", "2024W02"},
+		{"monthly", PeriodMonth, time.Date(2024, 1, 10, 12, 0, 0, 0, time.Local), 21 * 24 * time.Hour, 24 * time.Hour, "202401", "202402"},
+		{"monthly leap february", PeriodMonth, time.Date(2024, 2, 1, 0, 0, 0, 0, time.Local), 28*24*time.Hour + 23*time.Hour, 2 * time.Hour, "202402", "202403"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			caller := &dayRecorder{memCaller: newMemCaller(), days: make(map[string]bool)}
+			clock := newFakeClock(tc.start)
+			usage, err := NewWithOptions(caller.apply, testOptions(WithClock(clock), WithPeriod(tc.period), WithStep(100))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer usage.Close()
+			generate := func(wantKey string, wantSeq int64) {
+				t.Helper()
+				id, err := usage.GenerateId("app")
+				if err != nil {
+					t.Fatal(err)
+				}
+				parts, err := usage.Parse(id)
+				if err != nil || parts.Fallback || parts.Day != wantKey || parts.Sequence != wantSeq {
+					t.Fatalf("id %s parsed to %+v (%v), want sequence %d in period %s", id, parts, err, wantSeq, wantKey)
+				}
+			}
+
+			generate(tc.first, 1)
+			clock.Add(tc.within)
+			generate(tc.first, 2)
+			if calls := caller.callCount(); calls != 1 {
+				t.Fatalf("%d range requests within one period, want 1", calls)
+			}
+			clock.Add(tc.across)
+			generate(tc.later, 1)
+			caller.m.Lock()
+			defer caller.m.Unlock()
+			if len(caller.days) != 2 || !caller.days[tc.first] || !caller.days[tc.later] {
+				t.Fatalf("requested periods %v, want %s and %s", caller.days, tc.first, tc.later)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package generator
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// prefetchPoint 生成一个 id 取得号段后返回触发后台预取的号码相对号段起点的偏移
+func prefetchPoint(t *testing.T, opts ...Option) int64 {
+	t.Helper()
+	usage, err := NewWithOptions(newMemCaller().apply, testOptions(opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	if _, err := usage.GenerateId("app"); err != nil {
+		t.Fatal(err)
+	}
+	usage.usageM.Lock()
+	defer usage.usageM.Unlock()
+	return usage.prefetchAt - usage.currentRangeStart + 1
+}
+
+func TestPrefetchJitterSpreadsInstances(t *testing.T) {
+	const instances = 8
+	const step = 1000
+	points := make(map[int64]bool, instances)
+	for i := 0; i < instances; i++ {
+		point := prefetchPoint(t, WithStep(step), WithRangeQueueDepth(1), WithPrefetchJitter(0.3), WithRand(rand.New(rand.NewSource(int64(i)))))
+		//触发点在 0.5 上下 0.3 的范围内
+		if point < step*2/10 || point > step*8/10 {
+			t.Fatalf("instance %d prefetches at %d of %d, outside the jitter band", i, point, step)
+		}
+		points[point] = true
+	}
+	if len(points) < instances {
+		t.Fatalf("%d distinct prefetch points across %d instances: %v", len(points), instances, points)
+	}
+
+	//不配置抖动时所有实例都在号段一半处触发
+	for i := 0; i < 3; i++ {
+		if point := prefetchPoint(t, WithStep(step), WithRangeQueueDepth(1), WithRand(rand.New(rand.NewSource(int64(i))))); point != step/2 {
+			t.Fatalf("prefetch point %d without jitter, want %d", point, step/2)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package generator
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSetPrefixMidStream(t *testing.T) {
+	caller := newMemCaller()
+	usage, err := NewWithOptions(caller.apply, testOptions(WithStep(100))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	var last int64
+	generate := func(wantPrefix string) {
+		t.Helper()
+		for i := 0; i < 5; i++ {
+			id, err := usage.GenerateId("app")
+			if err != nil {
+				t.Fatal(err)
+			}
+			parts, err := usage.Parse(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parts.Prefix != wantPrefix {
+				t.Fatalf("id %s prefix %q, want %q", id, parts.Prefix, wantPrefix)
+			}
+			//号段继续使用，序号接着递增
+			if parts.Fallback || parts.Sequence != last+1 {
+				t.Fatalf("id %s sequence %d, want %d", id, parts.Sequence, last+1)
+			}
+			last = parts.Sequence
+		}
+	}
+	generate("T")
+	if err := usage.SetPrefix("NEW"); err != nil {
+		t.Fatal(err)
+	}
+	generate("NEW")
+	if calls := caller.callCount(); calls != 1 {
+		t.Fatalf("%d range requests, want the range to continue across the prefix change", calls)
+	}
+
+	for _, bad := range []string{"ORD 1", "T\n", "订单"} {
+		if err := usage.SetPrefix(bad); !errors.Is(err, ErrInvalidOption) {
+			t.Fatalf("SetPrefix(%q) error %v, want ErrInvalidOption", bad, err)
+		}
+	}
+	generate("NEW")
+}
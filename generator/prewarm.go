@@ -0,0 +1,122 @@
+package generator
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const constPrewarmRetry = 5 * time.Second //预热失败后的重试间隔
+
+// startPrewarm 配置了 WithNextDayPrewarm 时启动后台预热协程，与跨天检测共用 stopCh，Close 时退出
+func (usage *RangeUsageInfoStruct) startPrewarm() {
+	if usage.cfg.prewarmWindow <= 0 {
+		return
+	}
+	if usage.stopCh == nil {
+		usage.stopCh = make(chan struct{})
+	}
+	go usage.prewarmLoop(usage.cfg.prewarmWindow)
+}
+
+// prewarmLoop 等到下一周期开始前 window 时申请下一周期的号段，成功后等到跨过周期边界再为之后的周期预热
+func (usage *RangeUsageInfoStruct) prewarmLoop(window time.Duration) {
+	for {
+		now := usage.cfg.clock.Now()
+		next, err := usage.cfg.period.next(usage.periodKey(now), now.Location())
+		if err != nil {
+			usage.logs.Error("{} {} {} 计算下一周期出错 {}", usage.appName, usage.bizType, usage.prefix, err.Error())
+			return
+		}
+		boundary, _ := usage.cfg.period.start(next, now.Location())
+		if !usage.sleep(boundary.Sub(now) - window) {
+			return
+		}
+		for !usage.prewarmNext(next) {
+			wait := boundary.Sub(usage.cfg.clock.Now())
+			if wait <= 0 {
+				break
+			}
+			if wait > constPrewarmRetry {
+				wait = constPrewarmRetry
+			}
+			if !usage.sleep(wait) {
+				return
+			}
+		}
+		if !usage.sleep(boundary.Sub(usage.cfg.clock.Now())) {
+			return
+		}
+	}
+}
+
+// sleep 等待 d，Close 时提前返回 false
+func (usage *RangeUsageInfoStruct) sleep(d time.Duration) bool {
+	if d <= 0 {
+		return atomic.LoadInt32(&usage.closed) == 0
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-usage.stopCh:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// prewarmNext 申请 day 的号段保存待用，还没有生成过 id（appName 未知）或已经预热过时直接返回 true
+func (usage *RangeUsageInfoStruct) prewarmNext(day string) bool {
+	usage.usageM.Lock()
+	appName := usage.appName
+	done := usage.prewarmed != nil && usage.prewarmed.day == day
+	usage.usageM.Unlock()
+	if appName == "" || done {
+		return true
+	}
+
+	req := ApplyReq{
+		AppName: appName,
+		BizType: usage.bizType,
+		Day:     day,
+		Step:    usage.step(),
+	}
+	resp, err := usage.callNumbers(&req)
+	if err != nil {
+		usage.logs.Warn("{} {} {} 提前申请下一周期号段失败 {} {}", appName, usage.bizType, usage.prefix, day, err.Error())
+		return false
+	}
+	rangeDay, err := usage.checkRangeDay(&req, resp)
+	if err != nil {
+		usage.logs.Warn("{} {} {} 提前申请下一周期号段失败 {} {}", appName, usage.bizType, usage.prefix, day, err.Error())
+		return false
+	}
+	usage.usageM.Lock()
+	usage.prewarmed = &idRange{start: resp.RangeStart, end: resp.RangeEnd, day: day, rangeDay: rangeDay}
+	usage.usageM.Unlock()
+	usage.logs.Info("{} {} {} 提前申请到下一周期号段 {} {} {}", appName, usage.bizType, usage.prefix, day, resp.RangeStart, resp.RangeEnd)
+	return true
+}
+
+// takePrewarmed takeId 判断需要申请新一天的号段时，改用预热的号段；其它协程已经切换到预热号段时重新取号
+func (usage *RangeUsageInfoStruct) takePrewarmed(now time.Time, taken takenId) takenId {
+	if taken.refresh != refreshNewDay || usage.cfg.prewarmWindow <= 0 {
+		return taken
+	}
+	usage.usageM.Lock()
+	switched := usage.samePeriod(now, usage.applyDate)
+	if r := usage.prewarmed; r != nil && !switched && r.day == usage.periodKey(now) {
+		usage.prewarmed = nil
+		id, day, sw := usage.replaceRangeLocked(r.start, r.end, now, r.rangeDay)
+		usage.usageM.Unlock()
+		usage.logRangeSwitch(&sw)
+		if id <= 0 {
+			return taken
+		}
+		return takenId{id: id, day: day}
+	}
+	usage.usageM.Unlock()
+	if switched {
+		return usage.takeId(now)
+	}
+	return taken
+}
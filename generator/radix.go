@@ -0,0 +1,127 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	constRadixSymbols     = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	constMinRadix         = 2
+	constMaxRadix         = 62
+	constRadixDefaultSpan = 100000000 //不指定位数时，定长位数至少容纳的序号数
+	constRadixMaxCapacity = 1<<63 - 1
+)
+
+// radixAlphabet 自定义进制的字符表：前 10 个为 keyMap 中 0-9 的映射结果，保留按位映射的混淆效果，
+// 之后依次为未被 keyMap 使用的数字和字母，降级标识 Y 排在最后
+func radixAlphabet(m map[byte]byte, base int) string {
+	alphabet := make([]byte, 0, len(constRadixSymbols))
+	used := make(map[byte]bool, len(m)+1)
+	for d := byte('0'); d <= '9'; d++ {
+		alphabet = append(alphabet, m[d])
+		used[m[d]] = true
+	}
+	used[constFallbackMarker] = true
+	for i := 0; i < len(constRadixSymbols); i++ {
+		if !used[constRadixSymbols[i]] {
+			alphabet = append(alphabet, constRadixSymbols[i])
+		}
+	}
+	alphabet = append(alphabet, constFallbackMarker)
+	if len(alphabet) > base {
+		alphabet = alphabet[:base]
+	}
+	return string(alphabet)
+}
+
+// radixWidth 不指定位数时序号的定长位数：保证 constRadixDefaultSpan 个序号的最高位都小于 base-1，
+// 这样字符表最后的 Y 不会出现在序号首位，与降级 id 区分
+func radixWidth(base int) int {
+	width, span := 1, int64(base-1)
+	for span < constRadixDefaultSpan {
+		width++
+		span *= int64(base)
+	}
+	return width
+}
+
+// radixCapacity base 进制 width 位可用的序号数，即每天的序号上限：最高位小于 base-1，共 (base-1) * base^(width-1) 个；
+// 超过 int64 时返回 false
+func radixCapacity(base, width int) (int64, bool) {
+	if base < constMinRadix || width < 1 {
+		return 0, false
+	}
+	capacity := int64(base - 1)
+	for i := 1; i < width; i++ {
+		if capacity > constRadixMaxCapacity/int64(base) {
+			return 0, false
+		}
+		capacity *= int64(base)
+	}
+	return capacity, true
+}
+
+// radixWidthOf WithSequenceRadix 设置的位数，为 0 时使用 radixWidth 的默认位数
+func radixWidthOf(base, width int) int {
+	if width == 0 {
+		return radixWidth(base)
+	}
+	return width
+}
+
+// validateRadix 校验进制以及与其它选项的组合，base 为 0 表示不开启
+func (c *config) validateRadix() error {
+	if c.seqRadix == 0 {
+		return nil
+	}
+	if c.seqRadix < constMinRadix || c.seqRadix > constMaxRadix {
+		return fmt.Errorf("sequence radix %d out of range [%d, %d]", c.seqRadix, constMinRadix, constMaxRadix)
+	}
+	if c.dateless {
+		return fmt.Errorf("sequence radix conflicts with dateless mode")
+	}
+	if c.seqWidth < 0 {
+		return fmt.Errorf("sequence radix width %d is negative", c.seqWidth)
+	}
+	if _, ok := radixCapacity(c.seqRadix, radixWidthOf(c.seqRadix, c.seqWidth)); !ok {
+		return fmt.Errorf("sequence radix %d width %d exceeds int64", c.seqRadix, c.seqWidth)
+	}
+	if c.staticNodes != 0 {
+		return fmt.Errorf("sequence radix conflicts with static node assignment, strided sequences exceed the fixed width")
+	}
+	if n := len(radixAlphabet(c.keyMap, c.seqRadix)); n < c.seqRadix {
+		return fmt.Errorf("sequence radix %d needs %d symbols, key map leaves %d", c.seqRadix, c.seqRadix, n)
+	}
+	return nil
+}
+
+// encodeRadix 按字符表把序号编码为定长字符串，序号超出 radixCapacity 时返回 ErrSequenceOverflow
+func encodeRadix(seq int64, alphabet string, width int) (string, error) {
+	if capacity, _ := radixCapacity(len(alphabet), width); seq < 0 || seq >= capacity {
+		return "", fmt.Errorf("%w: sequence %d exceeds daily capacity %d", ErrSequenceOverflow, seq, capacity)
+	}
+	base := int64(len(alphabet))
+	key := make([]byte, width)
+	for i := width - 1; i >= 0; i-- {
+		key[i] = alphabet[seq%base]
+		seq /= base
+	}
+	return string(key), nil
+}
+
+func decodeRadix(key string, alphabet string, width int) (int64, error) {
+	if len(key) != width {
+		return 0, fmt.Errorf("radix key length %d, want %d", len(key), width)
+	}
+	base := int64(len(alphabet))
+	var seq int64
+	for i := 0; i < len(key); i++ {
+		d := strings.IndexByte(alphabet, key[i])
+		if d < 0 {
+			return 0, fmt.Errorf("unknown key char %q", key[i])
+		}
+		seq = seq*base + int64(d)
+	}
+	return seq, nil
+}
@@ -0,0 +1,77 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSequenceRadixRoundTrip(t *testing.T) {
+	cases := []struct {
+		base, width int
+		wantWidth   int
+	}{
+		{16, 0, 7},
+		{36, 0, 6},
+		{62, 0, 5},
+		{16, 10, 10},
+		{36, 4, 4},
+		{62, 8, 8},
+	}
+	for _, tc := range cases {
+		t.Run(fmt.Sprintf("base %d width %d", tc.base, tc.width), func(t *testing.T) {
+			usage, err := NewWithOptions(newMemCaller().apply, testOptions(WithStep(1000), WithSequenceRadix(tc.base, tc.width))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer usage.Close()
+			if usage.cfg.seqWidth != tc.wantWidth {
+				t.Fatalf("width %d, want %d", usage.cfg.seqWidth, tc.wantWidth)
+			}
+			capacity, _ := radixCapacity(tc.base, tc.wantWidth)
+			for _, seq := range []int64{0, 1, int64(tc.base), 999, capacity - 1} {
+				id, err := usage.GenerateKey(seq, "T", "20240101")
+				if err != nil {
+					t.Fatalf("GenerateKey(%d): %v", seq, err)
+				}
+				parts, err := usage.Parse(id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if parts.Sequence != seq || parts.Day != "20240101" || parts.Fallback {
+					t.Fatalf("%s parsed %+v, want sequence %d", id, parts, seq)
+				}
+				key := strings.TrimPrefix(id, "T-20240101")
+				if len(key) != tc.wantWidth {
+					t.Fatalf("%s sequence part %q has width %d, want %d", id, key, len(key), tc.wantWidth)
+				}
+				if decoded, err := usage.DecodeKey(key); err != nil || decoded != seq {
+					t.Fatalf("DecodeKey(%q) = %d, %v, want %d", key, decoded, err, seq)
+				}
+			}
+			if _, err := usage.GenerateKey(capacity, "T", "20240101"); !errors.Is(err, ErrSequenceOverflow) {
+				t.Fatalf("GenerateKey(capacity %d) error %v, want ErrSequenceOverflow", capacity, err)
+			}
+		})
+	}
+}
+
+func TestSequenceRadixInvalid(t *testing.T) {
+	cases := []struct {
+		name        string
+		base, width int
+	}{
+		{"base too small", 1, 0},
+		{"base too large", 63, 0},
+		{"negative width", 36, -1},
+		{"width exceeds int64", 62, 12},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewWithOptions(newMemCaller().apply, testOptions(WithSequenceRadix(tc.base, tc.width))...); !errors.Is(err, ErrInvalidOption) {
+				t.Fatalf("NewWithOptions error %v, want ErrInvalidOption", err)
+			}
+		})
+	}
+}
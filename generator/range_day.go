@@ -0,0 +1,18 @@
+package generator
+
+import "fmt"
+
+// checkRangeDay 校验服务端返回号段所属日期，返回 id 中应使用的日期
+// 服务端未返回日期或与申请日期一致时使用申请日期；不一致（客户端与服务端时钟偏差）时，
+// 信任服务端日期则号段隔离与 id 中的日期统一使用服务端日期，否则拒绝该号段，按申请失败处理
+func (usage *RangeUsageInfoStruct) checkRangeDay(req *ApplyReq, resp *NewRangeResp) (string, error) {
+	if resp.Day == "" || resp.Day == req.Day {
+		return req.Day, nil
+	}
+	if usage.cfg.trustServerDay {
+		usage.logs.Warn("{} {} {} 服务端号段日期与本地不一致，使用服务端日期 {} {}", usage.appName, usage.bizType, usage.prefix, req.Day, resp.Day)
+		return resp.Day, nil
+	}
+	usage.logs.Warn("{} {} {} 服务端号段日期与本地不一致，拒绝该号段 {} {}", usage.appName, usage.bizType, usage.prefix, req.Day, resp.Day)
+	return "", fmt.Errorf("%w: requested %s, got %s", ErrRangeDayMismatch, req.Day, resp.Day)
+}
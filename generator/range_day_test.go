@@ -0,0 +1,82 @@
+package generator
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// skewedCaller 模拟时钟比客户端快一天的号段服务，返回的号段都属于服务端日期
+func skewedCaller(serverDay string) NumbersReqFunc {
+	inner := newMemCaller()
+	return func(req *ApplyReq) (*NewRangeResp, error) {
+		skewed := *req
+		skewed.Day = serverDay
+		resp, err := inner.apply(&skewed)
+		if err != nil {
+			return nil, err
+		}
+		resp.Day = serverDay
+		return resp, nil
+	}
+}
+
+func TestServerDaySkew(t *testing.T) {
+	cases := []struct {
+		name    string
+		trust   bool
+		wantDay string
+		wantErr error
+	}{
+		{"trust server day", true, "20240102", nil},
+		{"reject skewed range", false, "", ErrRangeDayMismatch},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := newFakeClock(time.Date(2024, 1, 1, 23, 59, 0, 0, time.Local))
+			usage, err := NewWithOptions(skewedCaller("20240102"), testOptions(WithClock(clock), WithStep(10), WithTrustServerDay(tc.trust), WithNoFallback())...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer usage.Close()
+			id, err := usage.GenerateId("app")
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("GenerateId %q, %v, want %v", id, err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			parts, err := usage.Parse(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			//id 中的日期与号段所属的服务端日期一致
+			if parts.Day != tc.wantDay || parts.Sequence != 1 {
+				t.Fatalf("id %s day %s sequence %d, want day %s sequence 1", id, parts.Day, parts.Sequence, tc.wantDay)
+			}
+		})
+	}
+}
+
+// TestServerDaySkewFallback 不信任服务端日期时被拒绝的号段按申请失败处理，默认降级
+func TestServerDaySkewFallback(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 1, 23, 59, 0, 0, time.Local))
+	usage, err := NewWithOptions(skewedCaller("20240102"), testOptions(WithClock(clock), WithStep(10))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	id, err := usage.GenerateId("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts, err := usage.Parse(id); err != nil || !parts.Fallback {
+		t.Fatalf("id %s for a skewed range is not a fallback id (%v)", id, err)
+	}
+	if stats := usage.Stats(); stats.Fallbacks != 1 {
+		t.Fatalf("Fallbacks %d, want 1", stats.Fallbacks)
+	}
+}
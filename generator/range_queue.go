@@ -0,0 +1,118 @@
+package generator
+
+import "sync/atomic"
+
+const constPrefetchRatio = 0.5 //当前号段消耗超过该比例且预取队列未满时，触发后台预取
+
+// idRange 预取到的待用号段
+type idRange struct {
+	start    int64
+	end      int64
+	day      string //申请号段时的本地日期，格式 20060102，用于判断预取号段是否过期
+	rangeDay string //号段所属日期，即 id 中的日期
+}
+
+// nextQueuedIdLocked 开启预取且号段即将用完时调用，先用完当前号段剩余的号码，用完后按顺序切换到预取队列中的下一个号段
+// 返回 false 表示没有可用号码，需要走同步申请，调用方需持有 usageM
+func (usage *RangeUsageInfoStruct) nextQueuedIdLocked() (int64, bool) {
+	if usage.currentMaxId < usage.currentRangeEnd {
+		return usage.nextIdLocked(), true
+	}
+	applyDay := usage.periodKey(usage.applyDate)
+	for len(usage.rangeQueue) > 0 {
+		next := usage.rangeQueue[0]
+		usage.rangeQueue = usage.rangeQueue[1:]
+		if next.day != applyDay || next.rangeDay != usage.rangeDay || next.start <= usage.currentRangeEnd {
+			//跨天或不能保证单调递增的号段直接丢弃
+			usage.logs.Debug("{} {} {} 丢弃预取号段 {} {} {}", usage.appName, usage.bizType, usage.prefix, next.start, next.end, next.day)
+			continue
+		}
+		usage.setRangeLocked(next.start, next.end)
+		usage.recordRangeSwitchLocked()
+		usage.logs.Debug("{} {} {} 切换到预取号段 {} {}", usage.appName, usage.bizType, usage.prefix, next.start, next.end)
+		return usage.currentMaxId, true
+	}
+	return 0, false
+}
+
+// prefetchNeededLocked 判断是否需要触发后台预取，调用方需持有 usageM
+func (usage *RangeUsageInfoStruct) prefetchNeededLocked() bool {
+	if usage.cfg.rangeQueueDepth <= 0 || len(usage.rangeQueue) >= usage.cfg.rangeQueueDepth {
+		return false
+	}
+	return usage.currentRangeEnd >= usage.currentRangeStart && usage.currentMaxId >= usage.prefetchAt
+}
+
+// resetPrefetchPointLocked 号段切换后重新计算触发预取的号码，调用方需持有 usageM
+// 配置了抖动时，触发比例在 constPrefetchRatio 上下 prefetchJitter 的范围内随机，避免同时启动的实例同步请求号段
+func (usage *RangeUsageInfoStruct) resetPrefetchPointLocked() {
+	ratio := constPrefetchRatio
+	if jitter := usage.tunables().prefetchJitter; jitter > 0 {
+		usage.randM.Lock()
+		ratio += (usage.rander.Float64()*2 - 1) * jitter
+		usage.randM.Unlock()
+	}
+	width := usage.currentRangeEnd - usage.currentRangeStart + 1
+	usage.prefetchAt = usage.currentRangeStart + int64(float64(width)*ratio) - 1
+}
+
+// triggerPrefetch 在后台补齐预取队列，同一时刻只有一个预取协程
+func (usage *RangeUsageInfoStruct) triggerPrefetch(req ApplyReq) {
+	if usage.cfg.rangeQueueDepth <= 0 {
+		return
+	}
+	req.ctx = nil //后台预取不随触发它的调用取消
+	if !atomic.CompareAndSwapInt32(&usage.prefetching, 0, 1) {
+		return
+	}
+	go usage.prefetchRanges(req)
+}
+
+func (usage *RangeUsageInfoStruct) prefetchRanges(req ApplyReq) {
+	defer atomic.StoreInt32(&usage.prefetching, 0)
+	req.Step = usage.step()
+	for usage.prefetchQueueLen(req.Day) < usage.cfg.rangeQueueDepth {
+		resp, err := usage.callNumbers(&req)
+		if err != nil {
+			usage.logs.Warn("{} {} {} 后台预取号段失败 {}", usage.appName, usage.bizType, usage.prefix, err.Error())
+			return
+		}
+		rangeDay, err := usage.checkRangeDay(&req, resp)
+		if err != nil {
+			return
+		}
+		if !usage.enqueueRange(idRange{start: resp.RangeStart, end: resp.RangeEnd, day: req.Day, rangeDay: rangeDay}) {
+			return
+		}
+	}
+}
+
+func (usage *RangeUsageInfoStruct) prefetchQueueLen(day string) int {
+	usage.usageM.Lock()
+	defer usage.usageM.Unlock()
+	if usage.periodKey(usage.applyDate) != day {
+		return usage.cfg.rangeQueueDepth //已经跨天，不再为旧日期预取
+	}
+	return len(usage.rangeQueue)
+}
+
+// enqueueRange 将预取到的号段追加到队尾，只接受同一天且严格大于已有号段的号段，保证队列内号段有序且单调递增
+func (usage *RangeUsageInfoStruct) enqueueRange(r idRange) bool {
+	usage.usageM.Lock()
+	defer usage.usageM.Unlock()
+	if usage.periodKey(usage.applyDate) != r.day {
+		usage.logs.Debug("{} {} {} 预取号段日期已过期 {} {} {}", usage.appName, usage.bizType, usage.prefix, r.start, r.end, r.day)
+		return false
+	}
+	last := usage.currentRangeEnd
+	if n := len(usage.rangeQueue); n > 0 {
+		last = usage.rangeQueue[n-1].end
+	}
+	if r.start <= last || r.end < r.start {
+		usage.logs.Warn("{} {} {} 预取号段不是递增的，丢弃 {} {} 已有号段结束于 {}", usage.appName, usage.bizType, usage.prefix, r.start, r.end, last)
+		return false
+	}
+	usage.rangeQueue = append(usage.rangeQueue, r)
+	usage.logs.Debug("{} {} {} 预取号段入队 {} {} 队列长度 {}", usage.appName, usage.bizType, usage.prefix, r.start, r.end, len(usage.rangeQueue))
+	return true
+}
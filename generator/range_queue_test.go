@@ -0,0 +1,68 @@
+package generator
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// slowCaller 每次申请号段耗时 delay，模拟号段服务的网络延迟
+func slowCaller(delay time.Duration) NumbersReqFunc {
+	caller := newMemCaller()
+	return func(req *ApplyReq) (*NewRangeResp, error) {
+		time.Sleep(delay)
+		return caller.apply(req)
+	}
+}
+
+func TestRangeQueueMonotonic(t *testing.T) {
+	for _, depth := range []int{0, 1, 3} {
+		t.Run(fmt.Sprintf("depth %d", depth), func(t *testing.T) {
+			usage, err := NewWithOptions(slowCaller(time.Millisecond), testOptions(WithStep(20), WithRangeQueueDepth(depth))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer usage.Close()
+			var last int64
+			for i := 0; i < 500; i++ {
+				id, err := usage.GenerateId("app")
+				if err != nil {
+					t.Fatal(err)
+				}
+				parts, err := usage.Parse(id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if parts.Fallback {
+					t.Fatalf("fallback id %s", id)
+				}
+				if parts.Sequence <= last {
+					t.Fatalf("%s sequence %d not above %d", id, parts.Sequence, last)
+				}
+				last = parts.Sequence
+			}
+		})
+	}
+}
+
+// BenchmarkRangeQueueDepth 号段服务有延迟时比较不同预取深度下的生成吞吐，预取后几乎不在请求路径上同步申请号段
+func BenchmarkRangeQueueDepth(b *testing.B) {
+	for _, depth := range []int{0, 1, 3} {
+		b.Run(fmt.Sprintf("depth-%d", depth), func(b *testing.B) {
+			usage, err := NewWithOptions(slowCaller(200*time.Microsecond), testOptions(WithStep(1000), WithRangeQueueDepth(depth))...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer usage.Close()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := usage.GenerateId("app"); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package generator
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// liveFields 可以通过 Reconfigure 热更新的配置字段，其余字段只能在构造时设置，修改需要重建实例
+var liveFields = map[string]bool{
+	"clientBlockSize":      true, //WithClientSideMode，下次申请号段时生效
+	"maxFallbackPerMinute": true, //WithMaxFallbackRate
+	"unhealthyThreshold":   true, //WithHealthThreshold
+	"breakerThreshold":     true, //WithCircuitBreaker
+	"breakerCooldown":      true, //WithCircuitBreaker
+	"prefetchJitter":       true, //WithPrefetchJitter，下次切换号段时生效
+	"step":                 true, //WithStep，下次申请号段时生效
+	"threshold":            true, //WithThreshold
+}
+
+// tunables 返回当前生效的可热更新配置，生成路径上读取 liveFields 中的字段都应通过它，不加锁
+func (usage *RangeUsageInfoStruct) tunables() *config {
+	return usage.live.Load()
+}
+
+// Reconfigure 热更新配置：在当前配置上应用 opts 并校验，通过后原子替换，不阻塞正在生成 id 的调用；
+// 只有 liveFields 中的字段可以热更新（客户端模式块大小、降级限流、健康和熔断阈值、预取抖动），
+// opts 设置了其它字段时返回 ErrInvalidOption，这些字段需要重建实例才能修改
+func (usage *RangeUsageInfoStruct) Reconfigure(opts ...Option) error {
+	usage.reconfigM.Lock()
+	defer usage.reconfigM.Unlock()
+
+	probe := config{}
+	for _, opt := range opts {
+		opt(&probe)
+	}
+	if field, ok := restartOnlyField(&probe); ok {
+		return fmt.Errorf("%w: %s can not be changed without restart", ErrInvalidOption, field)
+	}
+
+	current := usage.tunables()
+	next := *current
+	for _, opt := range opts {
+		opt(&next)
+	}
+	if err := next.validate(); err != nil {
+		return err
+	}
+	usage.live.Store(&next)
+	usage.logs.Info("{} {} {} 配置已热更新", usage.appName, usage.bizType, usage.prefix)
+	return nil
+}
+
+// restartOnlyField 返回 opts 设置的第一个不能热更新的字段名
+func restartOnlyField(probe *config) (string, bool) {
+	v := reflect.ValueOf(probe).Elem()
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		name := t.Field(i).Name
+		if !liveFields[name] && !v.Field(i).IsZero() {
+			return name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,77 @@
+package generator
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// TestReconfigureStepConcurrently 并发生成 id 时反复热更新步长，用 -race 运行检查数据竞争
+func TestReconfigureStepConcurrently(t *testing.T) {
+	caller := &cappedCaller{memCaller: newMemCaller()}
+	usage, err := NewWithOptions(caller.apply, testOptions(WithStep(20))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			if err := usage.Reconfigure(WithStep(10 + i%3*10)); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	ids := generateConcurrently(t, usage, 8, 500)
+	wg.Wait()
+
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			t.Fatalf("%s issued twice", id)
+		}
+		seen[id] = true
+	}
+
+	if err := usage.Reconfigure(WithStep(50)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100 && caller.lastStep() != 50; i++ {
+		if _, err := usage.GenerateId("app"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := caller.lastStep(); got != 50 {
+		t.Fatalf("requested step %d after Reconfigure, want 50", got)
+	}
+}
+
+func TestReconfigureRejectsInvalid(t *testing.T) {
+	cases := []struct {
+		name string
+		opt  Option
+	}{
+		{"restart only prefix", WithPrefix("OTHER")},
+		{"restart only dateless", WithDateless(true)},
+		{"invalid step", WithStep(-1)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			usage, err := NewWithOptions(newMemCaller().apply, testOptions(WithStep(20))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer usage.Close()
+			if err := usage.Reconfigure(tc.opt); !errors.Is(err, ErrInvalidOption) {
+				t.Fatalf("Reconfigure error %v, want ErrInvalidOption", err)
+			}
+			if step := usage.tunables().step; step != 20 {
+				t.Fatalf("step %d after a rejected Reconfigure, want 20", step)
+			}
+		})
+	}
+}
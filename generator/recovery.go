@@ -0,0 +1,40 @@
+package generator
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const constProbeWait = time.Second //熔断器半开探测进行中时，降级前最多等待探测结果的时间
+
+// awaitRecovery 即将降级时调用：熔断器半开、探测请求进行中时先等待探测结果，
+// 返回 since 之后号段申请是否恢复过正常，恢复时调用方应重新取号或申请号段，而不是继续降级
+func (usage *RangeUsageInfoStruct) awaitRecovery(since int64) bool {
+	h := &usage.health
+	h.m.Lock()
+	probe := h.probeDone
+	h.m.Unlock()
+	if probe != nil {
+		timer := time.NewTimer(constProbeWait)
+		select {
+		case <-probe:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+	return atomic.LoadInt64(&h.recoveries) != since
+}
+
+// emitRecovered 半开探测成功、熔断器关闭后回调 BreakerRecovered 事件
+func (usage *RangeUsageInfoStruct) emitRecovered(req *ApplyReq, resp *NewRangeResp) {
+	if usage.cfg.onDayLifecycle == nil {
+		return
+	}
+	usage.cfg.onDayLifecycle(DayEvent{
+		Kind:       BreakerRecovered,
+		Time:       usage.cfg.clock.Now(),
+		Day:        req.Day,
+		RangeStart: resp.RangeStart,
+		RangeEnd:   resp.RangeEnd,
+	})
+}
@@ -0,0 +1,113 @@
+package generator
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// probeCaller 故障期间直接返回错误，恢复后在 gate 关闭之前阻塞，用于让其它请求在半开探测进行中到达
+type probeCaller struct {
+	mem   *memCaller
+	m     sync.Mutex
+	fail  bool
+	calls int
+	gate  chan struct{}
+}
+
+func (c *probeCaller) apply(req *ApplyReq) (*NewRangeResp, error) {
+	c.m.Lock()
+	c.calls++
+	fail := c.fail
+	c.m.Unlock()
+	if fail {
+		return nil, errDown
+	}
+	<-c.gate
+	return c.mem.apply(req)
+}
+
+func (c *probeCaller) callCount() int {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.calls
+}
+
+// TestFallbacksStopAfterProbe 故障恢复后第一次半开探测成功，探测期间到达的请求等待探测结果，之后都生成正常 id
+func TestFallbacksStopAfterProbe(t *testing.T) {
+	caller := &probeCaller{mem: newMemCaller(), fail: true, gate: make(chan struct{})}
+	clock := newFakeClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local))
+	var m sync.Mutex
+	var recovered []DayEvent
+	usage, err := NewWithOptions(caller.apply, testOptions(WithClock(clock), WithStep(10), WithHealthThreshold(100), WithCircuitBreaker(2, time.Minute), OnDayLifecycle(func(e DayEvent) {
+		if e.Kind == BreakerRecovered {
+			m.Lock()
+			recovered = append(recovered, e)
+			m.Unlock()
+		}
+	}))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	gen := func() *IdParts {
+		id, err := usage.GenerateId("app")
+		if err != nil {
+			t.Error(err)
+			return &IdParts{}
+		}
+		parts, err := usage.Parse(id)
+		if err != nil {
+			t.Error(err)
+			return &IdParts{}
+		}
+		return parts
+	}
+
+	for i := 0; i < 5; i++ {
+		if !gen().Fallback {
+			t.Fatal("real id during the outage")
+		}
+	}
+	if calls := caller.callCount(); calls != 2 {
+		t.Fatalf("%d range requests during the outage, want the breaker to open after 2", calls)
+	}
+	caller.m.Lock()
+	caller.fail = false
+	caller.m.Unlock()
+	clock.Add(2 * time.Minute)
+
+	const waiting = 4
+	results := make(chan *IdParts, waiting+1)
+	go func() { results <- gen() }()
+	deadline := time.Now().Add(time.Second)
+	for caller.callCount() != 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("no probe request after the cooldown")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for i := 0; i < waiting; i++ {
+		go func() { results <- gen() }()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(caller.gate)
+	for i := 0; i < waiting+1; i++ {
+		if parts := <-results; parts.Fallback {
+			t.Fatal("fallback id while the probe was succeeding")
+		}
+	}
+	for i := 0; i < 20; i++ {
+		if gen().Fallback {
+			t.Fatal("fallback id after recovery")
+		}
+	}
+	if s := usage.Stats(); s.Fallbacks != 5 {
+		t.Fatalf("Fallbacks %d, want only the 5 during the outage", s.Fallbacks)
+	}
+	m.Lock()
+	defer m.Unlock()
+	if len(recovered) != 1 || recovered[0].RangeStart != 1 {
+		t.Fatalf("BreakerRecovered events %+v, want one for the probe range", recovered)
+	}
+}
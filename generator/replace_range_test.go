@@ -0,0 +1,79 @@
+package generator
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// lockProbeLogger 记录在持有 usageM 时输出的日志，只适用于单协程生成，TryLock 失败即说明是生成协程自己持有锁
+type lockProbeLogger struct {
+	usage *RangeUsageInfoStruct
+	m     sync.Mutex
+	held  []string
+}
+
+func (l *lockProbeLogger) probe(format string) {
+	if l.usage == nil {
+		return
+	}
+	if l.usage.usageM.TryLock() {
+		l.usage.usageM.Unlock()
+		return
+	}
+	l.m.Lock()
+	l.held = append(l.held, format)
+	l.m.Unlock()
+}
+
+func (l *lockProbeLogger) Debug(format string, v ...any) { l.probe(format) }
+func (l *lockProbeLogger) Info(format string, v ...any)  { l.probe(format) }
+func (l *lockProbeLogger) Warn(format string, v ...any)  { l.probe(format) }
+func (l *lockProbeLogger) Error(format string, v ...any) { l.probe(format) }
+
+// TestReplaceRangeLogsOutsideLock 切换号段时的日志在释放 usageM 之后输出
+func TestReplaceRangeLogsOutsideLock(t *testing.T) {
+	for _, step := range []int{1, 5, 100} {
+		t.Run(fmt.Sprintf("step %d", step), func(t *testing.T) {
+			logs := &lockProbeLogger{}
+			usage, err := NewWithOptions(newMemCaller().apply, testOptions(WithStep(step), WithLogger(logs))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer usage.Close()
+			logs.usage = usage
+			for i := 0; i < 300; i++ {
+				if _, err := usage.GenerateId("app"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if len(logs.held) > 0 {
+				t.Fatalf("logged while holding usageM: %q", logs.held)
+			}
+		})
+	}
+}
+
+// BenchmarkRangeRefreshContention 步长很小、频繁切换号段时的并发生成耗时，衡量 replaceRange 临界区对生成的阻塞
+func BenchmarkRangeRefreshContention(b *testing.B) {
+	for _, step := range []int{10, 100, 10000} {
+		b.Run(fmt.Sprintf("step-%d", step), func(b *testing.B) {
+			caller := newMemCaller()
+			usage, err := NewWithOptions(caller.apply, testOptions(WithStep(step))...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer usage.Close()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := usage.GenerateId("app"); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+			b.ReportMetric(float64(caller.callCount())/float64(b.N), "refreshes/op")
+		})
+	}
+}
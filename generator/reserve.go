@@ -0,0 +1,70 @@
+package generator
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// releasedSeq 回滚后可以重新发放的号码
+type releasedSeq struct {
+	seq int64
+	day string
+}
+
+// ReserveID 预留一个 id，用于事务场景：事务提交后调用 commit 确认使用，事务回滚时调用 rollback 归还号码，
+// 归还的号码会在同一天内被之后的 GenerateId 优先重新发放，commit 和 rollback 只有先调用的一个生效
+// 回滚只能减少而不能消除号码空洞：降级 id 无法归还，跨天或实例重启后未重新发放的号码也会丢失
+func (usage *RangeUsageInfoStruct) ReserveID(applicationName string) (id string, commit func(), rollback func(), err error) {
+	id, err = usage.GenerateId(applicationName)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	var once sync.Once
+	commit = func() {
+		once.Do(func() {})
+	}
+	rollback = func() {
+		once.Do(func() { usage.releaseId(id) })
+	}
+	return id, commit, rollback, nil
+}
+
+func (usage *RangeUsageInfoStruct) releaseId(id string) {
+	parts, err := usage.Parse(id)
+	var seq int64
+	ok := err == nil && !parts.Fallback
+	if ok {
+		seq, ok = usage.localSeq(parts.Sequence)
+	}
+	if !ok {
+		usage.logs.Debug("{} {} {} 无法归还的 id {}", usage.appName, usage.bizType, usage.prefix, id)
+		return
+	}
+	usage.usageM.Lock()
+	defer usage.usageM.Unlock()
+	usage.released = append(usage.released, releasedSeq{seq: seq, day: parts.Day})
+	atomic.StoreInt32(&usage.releasedCount, int32(len(usage.released)))
+	usage.logs.Debug("{} {} {} 归还号码 {} {}", usage.appName, usage.bizType, usage.prefix, seq, parts.Day)
+}
+
+// takeReleasedSeq 取出一个当天归还的号码，跨天后旧日期的号码直接丢弃
+func (usage *RangeUsageInfoStruct) takeReleasedSeq(today string) (int64, string, bool) {
+	if atomic.LoadInt32(&usage.releasedCount) == 0 {
+		return 0, "", false
+	}
+	usage.usageM.Lock()
+	defer usage.usageM.Unlock()
+	defer func() { atomic.StoreInt32(&usage.releasedCount, int32(len(usage.released))) }()
+	if usage.periodKey(usage.applyDate) != today {
+		usage.released = nil
+		return 0, "", false
+	}
+	for len(usage.released) > 0 {
+		r := usage.released[0]
+		usage.released = usage.released[1:]
+		if r.day == usage.rangeDay {
+			return r.seq, r.day, true
+		}
+	}
+	return 0, "", false
+}
@@ -0,0 +1,59 @@
+package generator
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReserveCommitRollback(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local))
+	usage, err := NewWithOptions(newMemCaller().apply, testOptions(WithClock(clock), WithStep(100))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+
+	committed, commit, rollback, err := usage.ReserveID("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit()
+	rollback() //commit 之后的 rollback 不生效
+	rolledBack, _, rollback, err := usage.ReserveID("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rollback()
+	rollback()
+
+	//回滚的 id 优先重新发放，且只发放一次
+	reissued, err := usage.GenerateId("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reissued != rolledBack {
+		t.Fatalf("GenerateId after rollback %s, want the rolled back %s", reissued, rolledBack)
+	}
+	next, err := usage.GenerateId("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next == committed || next == rolledBack {
+		t.Fatalf("GenerateId reissued %s", next)
+	}
+
+	//跨天后前一天归还的号码不再发放
+	_, _, rollback, err = usage.ReserveID("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rollback()
+	clock.Add(24 * time.Hour)
+	id, err := usage.GenerateId("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts, err := usage.Parse(id); err != nil || parts.Day != "20240102" {
+		t.Fatalf("id %s after the day changed (%v), want a 20240102 id", id, err)
+	}
+}
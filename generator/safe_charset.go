@@ -0,0 +1,93 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SafeCharset id 序号部分允许出现的字符集合，可以用 | 组合多个
+type SafeCharset int
+
+const (
+	SafeURL             SafeCharset = 1 << iota //只允许 URL 非保留字符：字母、数字和 - . _ ~
+	SafeFilename                                //只允许字母、数字和 - . _，可以直接用作文件名
+	SafeCaseInsensitive                         //不允许小写字母，大小写不敏感的存储中不会冲突
+)
+
+const constUnreservedURL = "-._~"
+
+func (s SafeCharset) allows(ch byte) bool {
+	isAlnum := (ch >= 'A' && ch <= 'Z') || (ch >= 'a' && ch <= 'z') || (ch >= '0' && ch <= '9')
+	if s&SafeURL != 0 && !isAlnum && strings.IndexByte(constUnreservedURL, ch) < 0 {
+		return false
+	}
+	if s&SafeFilename != 0 && !isAlnum && ch != '-' && ch != '.' && ch != '_' {
+		return false
+	}
+	if s&SafeCaseInsensitive != 0 && ch >= 'a' && ch <= 'z' {
+		return false
+	}
+	return true
+}
+
+// check 校验 chars 中的字符都在安全字符集合内，what 为出错时提示的来源
+func (s SafeCharset) check(what string, chars string) error {
+	for i := 0; i < len(chars); i++ {
+		if !s.allows(chars[i]) {
+			return fmt.Errorf("%s produces unsafe char %q", what, chars[i])
+		}
+	}
+	return nil
+}
+
+func keyMapChars(m map[byte]byte) string {
+	chars := make([]byte, 0, len(m))
+	for d := byte('0'); d <= '9'; d++ {
+		chars = append(chars, m[d])
+	}
+	return string(chars)
+}
+
+// validateSafeCharset 校验 keyMap、自定义进制的字符表和默认类型标识产生的字符都在 WithSafeCharset 设置的集合内
+func (c *config) validateSafeCharset() error {
+	if c.safeCharset == 0 {
+		return nil
+	}
+	if err := c.safeCharset.check("key map", keyMapChars(c.keyMap)); err != nil {
+		return err
+	}
+	if c.seqRadix != 0 {
+		if err := c.safeCharset.check("sequence radix", radixAlphabet(c.keyMap, c.seqRadix)); err != nil {
+			return err
+		}
+	}
+	if c.typeFlag != 0 {
+		if err := c.safeCharset.check("type flag", string([]byte{c.typeFlag})); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizeSafeCharset 依次把不安全的 keyMap、进制和类型标识修正为默认值
+func (c *config) normalizeSafeCharset() {
+	if c.safeCharset == 0 {
+		return
+	}
+	if err := c.safeCharset.check("key map", keyMapChars(c.keyMap)); err != nil {
+		c.logs.Warn("自定义数字映射包含不安全的字符，使用默认映射 {}", err.Error())
+		c.keyMap = keyMap
+	}
+	if c.seqRadix != 0 {
+		if err := c.safeCharset.check("sequence radix", radixAlphabet(c.keyMap, c.seqRadix)); err != nil {
+			c.logs.Warn("序号进制的字符表包含不安全的字符，按十进制生成序号 {}", err.Error())
+			c.seqRadix = 0
+		}
+	}
+	if c.typeFlag != 0 {
+		if err := c.safeCharset.check("type flag", string([]byte{c.typeFlag})); err != nil {
+			c.logs.Warn("类型标识包含不安全的字符，不开启类型标识 {}", err.Error())
+			c.typeFlag = 0
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package generator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestSafeCharsetRejected 产生小写字母或特殊字符的映射在对应的安全字符集合下构造失败
+func TestSafeCharsetRejected(t *testing.T) {
+	cases := []struct {
+		name string
+		opts []Option
+	}{
+		{"lowercase case-insensitive", []Option{WithKeyMap(keyMapWith('3', 'f')), WithSafeCharset(SafeCaseInsensitive)}},
+		{"lowercase url and case-insensitive", []Option{WithKeyMap(keyMapWith('5', 'n')), WithSafeCharset(SafeURL | SafeCaseInsensitive)}},
+		{"plus url", []Option{WithKeyMap(keyMapWith('1', '+')), WithSafeCharset(SafeURL)}},
+		{"percent url", []Option{WithKeyMap(keyMapWith('7', '%')), WithSafeCharset(SafeURL)}},
+		{"tilde filename", []Option{WithKeyMap(keyMapWith('9', '~')), WithSafeCharset(SafeFilename)}},
+		{"radix 62 case-insensitive", []Option{WithSequenceRadix(62, 0), WithSafeCharset(SafeCaseInsensitive)}},
+		{"type flag case-insensitive", []Option{WithTypeFlag('p', nil), WithSafeCharset(SafeCaseInsensitive)}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewWithOptions(newMemCaller().apply, testOptions(tc.opts...)...)
+			if !errors.Is(err, ErrInvalidOption) || !strings.Contains(err.Error(), "unsafe char") {
+				t.Fatalf("got %v, want an unsafe char ErrInvalidOption", err)
+			}
+		})
+	}
+}
+
+func TestSafeCharsetAccepted(t *testing.T) {
+	cases := []struct {
+		name string
+		opts []Option
+	}{
+		{"default map all profiles", []Option{WithSafeCharset(SafeURL | SafeFilename | SafeCaseInsensitive)}},
+		{"lowercase url", []Option{WithKeyMap(keyMapWith('3', 'f')), WithSafeCharset(SafeURL)}},
+		{"tilde url", []Option{WithKeyMap(keyMapWith('9', '~')), WithSafeCharset(SafeURL)}},
+		{"radix 32 case-insensitive", []Option{WithSequenceRadix(32, 0), WithSafeCharset(SafeCaseInsensitive)}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			usage, err := NewWithOptions(newMemCaller().apply, testOptions(tc.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer usage.Close()
+			if _, err := usage.GenerateId("app"); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package generator
+
+const (
+	//序号按 constScrambleBlock 分块，块内做仿射变换 (x*A + C) mod constScrambleBlock，块号不变，
+	//因此变换后的序号仍然唯一、位数不变，无日期模式下仍在当天的序号范围内；
+	//变换是所有非负序号上的一一映射，静态节点模式下对 k*totalNodes+nodeID 变换，各节点的序号不会因此重复
+	constScrambleBlock      int64 = 1000000
+	constScrambleMultiplier int64 = 387403 //与 constScrambleBlock 互质，保证变换可逆
+	constScrambleOffset     int64 = 271828
+)
+
+var scrambleInverse = modInverse(constScrambleMultiplier, constScrambleBlock)
+
+// scrambleSeq 打散序号，使连续的序号分散到不同的分片
+func scrambleSeq(seq int64) int64 {
+	block, offset := seq/constScrambleBlock, seq%constScrambleBlock
+	return block*constScrambleBlock + (offset*constScrambleMultiplier+constScrambleOffset)%constScrambleBlock
+}
+
+// unscrambleSeq scrambleSeq 的逆变换
+func unscrambleSeq(seq int64) int64 {
+	block, offset := seq/constScrambleBlock, seq%constScrambleBlock
+	offset = (offset - constScrambleOffset + constScrambleBlock) % constScrambleBlock
+	return block*constScrambleBlock + offset*scrambleInverse%constScrambleBlock
+}
+
+// modInverse 扩展欧几里得求 a 模 m 的逆元，a 与 m 必须互质
+func modInverse(a, m int64) int64 {
+	t, newT := int64(0), int64(1)
+	r, newR := m, a
+	for newR != 0 {
+		q := r / newR
+		t, newT = newT, t-q*newT
+		r, newR = newR, r-q*newR
+	}
+	if r != 1 {
+		panic("scramble multiplier is not coprime with block")
+	}
+	if t < 0 {
+		t += m
+	}
+	return t
+}
@@ -0,0 +1,69 @@
+package generator
+
+import "testing"
+
+func TestScrambleInverse(t *testing.T) {
+	for _, seq := range []int64{0, 1, 2, 999, constScrambleBlock - 1, constScrambleBlock, constScrambleBlock + 1, 123456789, 1<<40 + 7} {
+		scrambled := scrambleSeq(seq)
+		if scrambled/constScrambleBlock != seq/constScrambleBlock {
+			t.Fatalf("scrambleSeq(%d) = %d moved to another block", seq, scrambled)
+		}
+		if got := unscrambleSeq(scrambled); got != seq {
+			t.Fatalf("unscrambleSeq(scrambleSeq(%d)) = %d", seq, got)
+		}
+	}
+}
+
+// TestScrambleDistribution 打散后的 id 唯一、能还原出原序号，连续的序号按值范围分区时均匀落到各个分区
+func TestScrambleDistribution(t *testing.T) {
+	const (
+		total   = 8000
+		buckets = 16
+	)
+	usage, err := NewWithOptions(newMemCaller().apply, testOptions(WithScramble(true), WithStep(1000))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	//不打散的同格式实例解析出的序号即为 id 中实际编码的值
+	raw, err := NewWithOptions(newMemCaller().apply, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+
+	seen := make(map[string]bool, total)
+	var counts [buckets]int
+	var last int64
+	for i := 0; i < total; i++ {
+		id, err := usage.GenerateId("app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[id] {
+			t.Fatalf("duplicate id %s", id)
+		}
+		seen[id] = true
+		parts, err := usage.Parse(id)
+		if err != nil || parts.Fallback {
+			t.Fatalf("Parse(%s) = %+v, %v", id, parts, err)
+		}
+		if parts.Sequence <= last {
+			t.Fatalf("%s decoded to sequence %d after %d", id, parts.Sequence, last)
+		}
+		last = parts.Sequence
+		encoded, err := raw.Parse(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encoded.Sequence != scrambleSeq(parts.Sequence) {
+			t.Fatalf("%s encodes %d, want scrambleSeq(%d) = %d", id, encoded.Sequence, parts.Sequence, scrambleSeq(parts.Sequence))
+		}
+		counts[encoded.Sequence%constScrambleBlock*buckets/constScrambleBlock]++
+	}
+	for bucket, n := range counts {
+		if n < total/buckets/2 || n > total/buckets*3/2 {
+			t.Fatalf("bucket %d has %d of %d ids: %v", bucket, n, total, counts)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package generator
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// selfCheckState 自检模式下最近一次发放的号码，受 m 保护
+type selfCheckState struct {
+	m        sync.Mutex
+	day      string
+	seq      int64
+	reissued bool //本次发放的是 ReserveID 回滚归还的号码，不参与单调递增检查
+}
+
+// checkIssued 自检模式下校验刚生成的 id，before 为开始生成时的时间，调用方需持有 selfCheck.m：
+// 1. 序号在同一天内严格递增；
+// 2. 未开启信任服务端日期和溢出到后一天时，id 中的日期与生成期间的本地日期一致；
+// 3. 号段服务没有失败时不应该生成降级 id
+// 不满足时通过 WithDiagnostic 的回调上报 DiagnosticSelfCheck 事件，id 照常返回
+func (usage *RangeUsageInfoStruct) checkIssued(id string, before time.Time) {
+	s := &usage.selfCheck
+	reissued := s.reissued
+	s.reissued = false
+	parts, err := usage.Parse(id)
+	if err != nil {
+		usage.reportSelfCheck("", fmt.Sprintf("generated id %s can not be parsed: %s", id, err.Error()))
+		return
+	}
+	if parts.Fallback {
+		if usage.consecutiveFailures() == 0 {
+			usage.reportSelfCheck(parts.Day, fmt.Sprintf("fallback id %s generated while numbers service is healthy", id))
+		}
+		return
+	}
+	after := usage.cfg.clock.Now()
+	if !usage.cfg.trustServerDay && !usage.cfg.overflowToNextDay && !usage.cfg.dateless &&
+		parts.Day != usage.periodKey(before) && parts.Day != usage.periodKey(after) {
+		usage.reportSelfCheck(parts.Day, fmt.Sprintf("id %s is dated %s but clock is %s", id, parts.Day, usage.periodKey(after)))
+	}
+	if reissued {
+		return
+	}
+	if parts.Day == s.day && parts.Sequence <= s.seq {
+		usage.reportSelfCheck(parts.Day, fmt.Sprintf("id %s sequence %d is not greater than previous %d", id, parts.Sequence, s.seq))
+	}
+	s.day, s.seq = parts.Day, parts.Sequence
+}
+
+func (usage *RangeUsageInfoStruct) reportSelfCheck(day string, message string) {
+	usage.usageM.Lock()
+	start, end := usage.currentRangeStart, usage.currentRangeEnd
+	usage.usageM.Unlock()
+	usage.logs.Error("{} {} {} 自检失败 {}", usage.appName, usage.bizType, usage.prefix, message)
+	usage.emitDiagnostic(Diagnostic{
+		Kind:       DiagnosticSelfCheck,
+		Day:        day,
+		RangeStart: start,
+		RangeEnd:   end,
+		Message:    message,
+	})
+}
+
+func (usage *RangeUsageInfoStruct) consecutiveFailures() int {
+	h := &usage.health
+	h.m.Lock()
+	defer h.m.Unlock()
+	return h.consecutiveFailures
+}
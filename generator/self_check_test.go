@@ -0,0 +1,96 @@
+package generator
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// diagnosticRecorder 记录 WithDiagnostic 回调收到的事件
+type diagnosticRecorder struct {
+	m      sync.Mutex
+	events []Diagnostic
+}
+
+func (r *diagnosticRecorder) record(d Diagnostic) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.events = append(r.events, d)
+}
+
+func (r *diagnosticRecorder) selfChecks() []Diagnostic {
+	r.m.Lock()
+	defer r.m.Unlock()
+	var found []Diagnostic
+	for _, d := range r.events {
+		if d.Kind == DiagnosticSelfCheck {
+			found = append(found, d)
+		}
+	}
+	return found
+}
+
+func newSelfCheckUsage(t *testing.T, caller NumbersReqFunc, rec *diagnosticRecorder) *RangeUsageInfoStruct {
+	t.Helper()
+	clock := newFakeClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local))
+	usage, err := NewWithOptions(caller, testOptions(WithClock(clock), WithStep(20), WithSelfCheck(true), WithDiagnostic(rec.record))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return usage
+}
+
+func TestSelfCheckClean(t *testing.T) {
+	rec := &diagnosticRecorder{}
+	usage := newSelfCheckUsage(t, newMemCaller().apply, rec)
+	defer usage.Close()
+	for i := 0; i < 100; i++ {
+		if _, err := usage.GenerateId("app"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if found := rec.selfChecks(); len(found) != 0 {
+		t.Fatalf("self-check reported %+v for a healthy run", found)
+	}
+}
+
+// TestSelfCheckCatchesRegression 号段状态被改回已发放的位置时，自检发现序号没有递增
+func TestSelfCheckCatchesRegression(t *testing.T) {
+	rec := &diagnosticRecorder{}
+	usage := newSelfCheckUsage(t, newMemCaller().apply, rec)
+	defer usage.Close()
+	for i := 0; i < 5; i++ {
+		if _, err := usage.GenerateId("app"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	usage.usageM.Lock()
+	usage.currentMaxId = usage.currentRangeStart
+	usage.usageM.Unlock()
+	if _, err := usage.GenerateId("app"); err != nil {
+		t.Fatal(err)
+	}
+	found := rec.selfChecks()
+	if len(found) != 1 || !strings.Contains(found[0].Message, "not greater than previous") || found[0].Day != "20240101" {
+		t.Fatalf("self-check reported %+v, want one sequence regression", found)
+	}
+}
+
+// TestSelfCheckCatchesUnexpectedFallback 号段服务返回其它日期的号段导致降级时，服务并未报错，自检上报不应出现的降级 id
+func TestSelfCheckCatchesUnexpectedFallback(t *testing.T) {
+	rec := &diagnosticRecorder{}
+	usage := newSelfCheckUsage(t, skewedCaller("20231231"), rec)
+	defer usage.Close()
+	id, err := usage.GenerateId("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts, err := usage.Parse(id); err != nil || !parts.Fallback {
+		t.Fatalf("%s (%v) is not a fallback id", id, err)
+	}
+	found := rec.selfChecks()
+	if len(found) != 1 || !strings.Contains(found[0].Message, "while numbers service is healthy") {
+		t.Fatalf("self-check reported %+v, want one unexpected fallback", found)
+	}
+}
@@ -0,0 +1,51 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+const constShardAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ" //分片字符可用的字符
+
+// ShardFunc 根据序号计算分片字符，返回值必须在 0-9、A-Z 范围内
+type ShardFunc func(seq int64) byte
+
+func isShardChar(ch byte) bool {
+	return strings.IndexByte(constShardAlphabet, ch) >= 0
+}
+
+// shardChar 计算序号对应的分片字符，分片函数返回不合法的字符时报错而不是生成无法路由的 id
+func (usage *RangeUsageInfoStruct) shardChar(seq int64) (byte, error) {
+	ch := usage.cfg.shardFunc(seq)
+	if !isShardChar(ch) {
+		usage.logs.Error("{} {} {} 分片函数返回不合法的字符 {} {}", usage.appName, usage.bizType, usage.prefix, seq, ch)
+		return 0, fmt.Errorf("%w: shard char %q for sequence %d", ErrInvalidShard, ch, seq)
+	}
+	return ch, nil
+}
+
+// fallbackShardChar 降级 id 没有序号，用随机数计算分片字符，使降级 id 也能分散到各个分片
+func (usage *RangeUsageInfoStruct) fallbackShardChar() (byte, error) {
+	usage.randM.Lock()
+	seq := usage.rander.Int63()
+	usage.randM.Unlock()
+	return usage.shardChar(seq)
+}
+
+// ShardOf 返回 id 中的分片字符，分片字符固定是 id 的最后一个字符（开启 WithCheckChar 时为校验字符之前的字符），路由时无需解析整个 id
+// 没有配置 WithShardFunc 时返回 ErrInvalidOption，UUID 输出模式下返回 ErrNotDecodable
+func (usage *RangeUsageInfoStruct) ShardOf(id string) (byte, error) {
+	if usage.cfg.uuidNamespace != nil {
+		return 0, fmt.Errorf("%w: %s is a uuid", ErrNotDecodable, id)
+	}
+	if usage.cfg.shardFunc == nil {
+		return 0, fmt.Errorf("%w: shard func not configured", ErrInvalidOption)
+	}
+	if usage.cfg.checkChar && id != "" {
+		id = id[:len(id)-1]
+	}
+	if id == "" || !isShardChar(id[len(id)-1]) {
+		return 0, fmt.Errorf("%w: %s missing shard char", ErrInvalidId, id)
+	}
+	return id[len(id)-1], nil
+}
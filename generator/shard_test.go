@@ -0,0 +1,81 @@
+package generator
+
+import (
+	"errors"
+	"testing"
+)
+
+func shardByMod(seq int64) byte {
+	return constShardAlphabet[seq%16]
+}
+
+// TestShardOfMatchesShardFunc 抽样的 id 中分片字符与分片函数对序号的计算结果一致，Parse 不受分片字符影响
+func TestShardOfMatchesShardFunc(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"plain", nil},
+		{"check char", []Option{WithCheckChar(true)}},
+		{"radix", []Option{WithSequenceRadix(36, 6)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			usage, err := NewWithOptions(newMemCaller().apply, testOptions(append(tc.opts, WithShardFunc(shardByMod), WithStep(50))...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer usage.Close()
+			shards := make(map[byte]bool)
+			for i := 0; i < 200; i++ {
+				id, err := usage.GenerateId("app")
+				if err != nil {
+					t.Fatal(err)
+				}
+				parts, err := usage.Parse(id)
+				if err != nil {
+					t.Fatalf("Parse(%s): %v", id, err)
+				}
+				if parts.Fallback {
+					t.Fatalf("unexpected fallback id %s", id)
+				}
+				ch, err := usage.ShardOf(id)
+				if err != nil {
+					t.Fatalf("ShardOf(%s): %v", id, err)
+				}
+				if want := shardByMod(parts.Sequence); ch != want || parts.Shard != want {
+					t.Fatalf("%s: ShardOf %q, Parse %q, want %q for sequence %d", id, ch, parts.Shard, want, parts.Sequence)
+				}
+				shards[ch] = true
+			}
+			if len(shards) != 16 {
+				t.Fatalf("sampled %d distinct shards, want 16", len(shards))
+			}
+		})
+	}
+}
+
+func TestShardFuncInvalidChar(t *testing.T) {
+	usage, err := NewWithOptions(newMemCaller().apply, testOptions(WithShardFunc(func(int64) byte { return '-' }), WithNoFallback())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	if _, err := usage.GenerateId("app"); !errors.Is(err, ErrInvalidShard) {
+		t.Fatalf("got %v, want ErrInvalidShard", err)
+	}
+}
+
+func TestShardOfNotConfigured(t *testing.T) {
+	usage, err := NewWithOptions(newMemCaller().apply, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	id, err := usage.GenerateId("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := usage.ShardOf(id); !errors.Is(err, ErrInvalidOption) {
+		t.Fatalf("got %v, want ErrInvalidOption", err)
+	}
+}
@@ -0,0 +1,151 @@
+package generator
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync/atomic"
+)
+
+// State 可持久化的生成器号段状态
+type State struct {
+	AppName    string `json:"appName"`
+	BizType    string `json:"bizType"`
+	ApplyDay   string `json:"applyDay"` //申请号段时的本地日期
+	RangeDay   string `json:"rangeDay"` //号段所属日期
+	RangeStart int64  `json:"rangeStart"`
+	MaxId      int64  `json:"maxId"` //已分配的最大号码
+	RangeEnd   int64  `json:"rangeEnd"`
+
+	NodeID    int   `json:"nodeId"`              //静态节点模式下的节点序号，否则为 -1
+	Generated int64 `json:"generated,omitempty"` //保存时已生成的 id 数
+	Fallbacks int64 `json:"fallbacks,omitempty"` //保存时已生成的降级 id 数
+}
+
+// StateStore 号段状态存储，Load 没有保存过状态时返回 nil, nil
+type StateStore interface {
+	Load() (*State, error)
+	Save(state *State) error
+	Clear() error
+}
+
+// FileStateStore 以 json 文件保存号段状态
+type FileStateStore struct {
+	path string
+}
+
+func NewFileStateStore(path string) *FileStateStore {
+	return &FileStateStore{path: path}
+}
+
+func (s *FileStateStore) Load() (*State, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	state := &State{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (s *FileStateStore) Save(state *State) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *FileStateStore) Clear() error {
+	err := os.Remove(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// restoreState 尽量减少空洞模式下，启动时接着使用上次 Close 时保存的未用完号段
+// 读取后立即清除保存的状态，避免同一份号段被重复使用
+func (usage *RangeUsageInfoStruct) restoreState() error {
+	if usage.cfg.gapPolicy != MinimizeGaps || usage.cfg.stateStore == nil {
+		return nil
+	}
+	state, err := usage.cfg.stateStore.Load()
+	if err != nil || state == nil {
+		return err
+	}
+	if err := usage.cfg.stateStore.Clear(); err != nil {
+		return err
+	}
+	now := usage.cfg.clock.Now()
+	today := usage.periodKey(now)
+	//号段按 appName + bizType 分配，其它应用或业务线保存的号段不能使用
+	if state.AppName != usage.appName || state.BizType != usage.bizType || state.ApplyDay != today || state.MaxId >= state.RangeEnd {
+		usage.logs.Info("{} {} {} 保存的号段不可用，丢弃 {} {} {} {} {}", usage.appName, usage.bizType, usage.prefix, state.AppName, state.BizType, state.ApplyDay, state.MaxId, state.RangeEnd)
+		return nil
+	}
+	usage.usageM.Lock()
+	defer usage.usageM.Unlock()
+	usage.applyDate = now
+	usage.rangeDay = state.RangeDay
+	usage.setRangeLocked(state.RangeStart, state.RangeEnd)
+	atomic.StoreInt64(&usage.currentMaxId, state.MaxId)
+	usage.logs.Info("{} {} {} 恢复上次未用完的号段 {} {} {}", usage.appName, usage.bizType, usage.prefix, state.MaxId, state.RangeEnd, state.RangeDay)
+	return nil
+}
+
+// Close 关闭生成器，之后的生成调用返回 ErrClosed，WithDayChangeCheck、WithNextDayPrewarm 启动的后台协程随之退出
+// 尽量减少空洞模式下会把当前未用完的号段保存到 StateStore，供重启后的实例继续使用；
+// 通过 NewWithClient 创建时把其余未用完的号码归还给号段服务，再关闭 NumbersClient，返回第一个错误
+func (usage *RangeUsageInfoStruct) Close() error {
+	if !atomic.CompareAndSwapInt32(&usage.closed, 0, 1) {
+		return nil
+	}
+	if usage.stopCh != nil {
+		close(usage.stopCh)
+	}
+	usage.releaseNodeID()
+	err := usage.saveState()
+	if usage.cfg.client != nil {
+		usage.releaseRanges()
+		if closeErr := usage.cfg.client.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// saveState 尽量减少空洞模式下把当前未用完的号段保存到 StateStore
+func (usage *RangeUsageInfoStruct) saveState() error {
+	if usage.cfg.gapPolicy != MinimizeGaps || usage.cfg.stateStore == nil {
+		return nil
+	}
+	usage.usageM.Lock()
+	state := &State{
+		AppName:    usage.appName,
+		BizType:    usage.bizType,
+		ApplyDay:   usage.periodKey(usage.applyDate),
+		RangeDay:   usage.rangeDay,
+		RangeStart: usage.currentRangeStart,
+		MaxId:      usage.currentMaxId,
+		RangeEnd:   usage.currentRangeEnd,
+		NodeID:     usage.NodeID(),
+		Generated:  atomic.LoadInt64(&usage.counters.generated),
+		Fallbacks:  atomic.LoadInt64(&usage.counters.fallbacks),
+	}
+	usage.usageM.Unlock()
+	if state.RangeEnd == 0 || state.MaxId >= state.RangeEnd {
+		return nil
+	}
+	usage.logs.Info("{} {} {} 保存未用完的号段 {} {} {}", usage.appName, usage.bizType, usage.prefix, state.MaxId, state.RangeEnd, state.RangeDay)
+	return usage.cfg.stateStore.Save(state)
+}
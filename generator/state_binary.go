@@ -0,0 +1,95 @@
+package generator
+
+import (
+	"encoding"
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	constStateMagic   = "NS" //二进制状态的开头标记
+	constStateVersion = 1    //二进制状态的格式版本
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*State)(nil)
+	_ encoding.BinaryUnmarshaler = (*State)(nil)
+)
+
+// MarshalBinary 把状态编码为紧凑的二进制格式，比 json 更适合高频保存：
+// 开头为标记 NS 和 1 字节格式版本，之后依次为各字段，字符串为长度前缀，整数为 varint。
+// 新增字段只追加在末尾，旧版本解码时忽略末尾不认识的字段；不兼容的调整才升级格式版本
+func (s *State) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, constStateMagic...)
+	buf = append(buf, constStateVersion)
+	for _, str := range []string{s.AppName, s.BizType, s.ApplyDay, s.RangeDay} {
+		buf = binary.AppendUvarint(buf, uint64(len(str)))
+		buf = append(buf, str...)
+	}
+	for _, n := range []int64{s.RangeStart, s.MaxId, s.RangeEnd, int64(s.NodeID), s.Generated, s.Fallbacks} {
+		buf = binary.AppendVarint(buf, n)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary 解码 MarshalBinary 的结果，格式版本不同时返回 ErrStateVersion，数据不完整时返回 ErrInvalidState
+func (s *State) UnmarshalBinary(data []byte) error {
+	if len(data) < len(constStateMagic)+1 || string(data[:len(constStateMagic)]) != constStateMagic {
+		return fmt.Errorf("%w: missing header", ErrInvalidState)
+	}
+	if v := data[len(constStateMagic)]; v != constStateVersion {
+		return fmt.Errorf("%w: got %d, want %d", ErrStateVersion, v, constStateVersion)
+	}
+	r := stateReader{data: data[len(constStateMagic)+1:]}
+	decoded := State{
+		AppName:    r.string(),
+		BizType:    r.string(),
+		ApplyDay:   r.string(),
+		RangeDay:   r.string(),
+		RangeStart: r.varint(),
+		MaxId:      r.varint(),
+		RangeEnd:   r.varint(),
+		NodeID:     int(r.varint()),
+		Generated:  r.varint(),
+		Fallbacks:  r.varint(),
+	}
+	if r.err != nil {
+		return r.err
+	}
+	*s = decoded
+	return nil
+}
+
+// stateReader 按顺序读取二进制状态的字段，出错后后续读取都返回零值
+type stateReader struct {
+	data []byte
+	err  error
+}
+
+func (r *stateReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	n, size := binary.Varint(r.data)
+	if size <= 0 {
+		r.err = fmt.Errorf("%w: truncated integer", ErrInvalidState)
+		return 0
+	}
+	r.data = r.data[size:]
+	return n
+}
+
+func (r *stateReader) string() string {
+	if r.err != nil {
+		return ""
+	}
+	n, size := binary.Uvarint(r.data)
+	if size <= 0 || n > uint64(len(r.data)-size) {
+		r.err = fmt.Errorf("%w: truncated string", ErrInvalidState)
+		return ""
+	}
+	str := string(r.data[size : size+int(n)])
+	r.data = r.data[size+int(n):]
+	return str
+}
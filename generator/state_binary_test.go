@@ -0,0 +1,68 @@
+package generator
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStateBinaryRoundTrip(t *testing.T) {
+	cases := []struct {
+		name  string
+		state State
+	}{
+		{"zero", State{}},
+		{"static node", State{AppName: "app", BizType: "T", ApplyDay: "20240101", RangeDay: "20240101", NodeID: 3}},
+		{"range", State{AppName: "订单", BizType: "ORD", ApplyDay: "20240101", RangeDay: "20240102", RangeStart: 1 << 40, MaxId: 1<<40 + 7, RangeEnd: 1<<41 - 1, NodeID: -1, Generated: 12345, Fallbacks: 6}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := tc.state.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got State
+			if err := got.UnmarshalBinary(data); err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.state {
+				t.Fatalf("decoded %+v, want %+v", got, tc.state)
+			}
+			//新版本追加在末尾的字段被忽略
+			var extended State
+			if err := extended.UnmarshalBinary(append(data, 0x02, 0x04)); err != nil || extended != tc.state {
+				t.Fatalf("decoded %+v (%v) with trailing fields, want %+v", extended, err, tc.state)
+			}
+		})
+	}
+}
+
+func TestStateBinaryInvalid(t *testing.T) {
+	valid, err := (&State{AppName: "app", BizType: "T", RangeStart: 1, MaxId: 5, RangeEnd: 100}).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherVersion := append([]byte(nil), valid...)
+	otherVersion[len(constStateMagic)] = constStateVersion + 1
+	cases := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"empty", nil, ErrInvalidState},
+		{"json", []byte(`{"appName":"app"}`), ErrInvalidState},
+		{"other version", otherVersion, ErrStateVersion},
+		{"truncated string", valid[:5], ErrInvalidState},
+		{"truncated integer", valid[:len(valid)-6], ErrInvalidState},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			state := State{AppName: "keep"}
+			if err := state.UnmarshalBinary(tc.data); !errors.Is(err, tc.want) {
+				t.Fatalf("UnmarshalBinary error %v, want %v", err, tc.want)
+			}
+			if state.AppName != "keep" {
+				t.Fatalf("state changed to %+v on error", state)
+			}
+		})
+	}
+}
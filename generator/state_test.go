@@ -0,0 +1,65 @@
+package generator
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRestoreState(t *testing.T) {
+	cases := []struct {
+		name        string
+		appName     string
+		bizType     string
+		wantRestore bool
+	}{
+		{"same app and biz", "app", "T", true},
+		{"other app", "other", "T", false},
+		{"other biz", "app", "OTHER", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			caller := newMemCaller()
+			clock := newFakeClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local))
+			store := NewFileStateStore(filepath.Join(t.TempDir(), "state.json"))
+			opts := []Option{WithClock(clock), WithStep(100), WithGapPolicy(MinimizeGaps), WithStateStore(store)}
+			first, err := NewWithOptions(caller.apply, testOptions(opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 10; i++ {
+				if _, err := first.GenerateId("app"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := first.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			second, err := NewWithOptions(caller.apply, append(opts, WithAppName(tc.appName), WithPrefix("T"), WithBizType(tc.bizType))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer second.Close()
+			if second.appName != tc.appName {
+				t.Fatalf("app name %q after restore, want %q", second.appName, tc.appName)
+			}
+			id, err := second.GenerateId(tc.appName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parts, err := second.Parse(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			//恢复时继续使用上次的号段，丢弃时从本应用 + 业务线自己的号段开始
+			restored := parts.Sequence == 11
+			if restored != tc.wantRestore {
+				t.Fatalf("%s: restored %v, want %v", id, restored, tc.wantRestore)
+			}
+			if !tc.wantRestore && caller.callCount() != 2 {
+				t.Fatalf("%d range requests, want 2", caller.callCount())
+			}
+		})
+	}
+}
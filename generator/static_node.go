@@ -0,0 +1,74 @@
+package generator
+
+import (
+	"fmt"
+	"sync"
+)
+
+const (
+	constMaxStaticNodes    = 10000 //静态节点数上限，保证节点序号乘以节点数后不会溢出 int64
+	constStaticSeqPerMilli = 10000 //本地分配号段时每毫秒预留的序号数
+)
+
+// staticNodeCaller 静态节点模式下代替号段服务的本地分配器
+// 按周期分配连续的本地序号 k，起始号码不小于 当前周期已过去的毫秒数 * constStaticSeqPerMilli，
+// 重启后从当前时间对应的号码继续分配，只要时钟不回拨、重启前没有提前分配超过重启耗时对应的号码，就不会与重启前的号码重复
+// 每个周期单独记录已分配到的号码，提前申请下一周期（预热、溢出到后一天）后再申请当前周期时不会从头分配；
+// 已经过去的周期没有时间下限，重启后无法保证不重复，因此不支持为过去的日期生成 id，见 checkBackfill
+type staticNodeCaller struct {
+	m      sync.Mutex
+	clock  Clock
+	period Period
+	last   map[string]int64 //各周期已分配的最大本地序号，早于当前周期的记录会被清理
+}
+
+func (c *staticNodeCaller) apply(req *ApplyReq) (*NewRangeResp, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	now := c.clock.Now()
+	today := c.period.key(now)
+	if c.last == nil {
+		c.last = make(map[string]int64)
+	}
+	for day := range c.last {
+		if day < today {
+			delete(c.last, day)
+		}
+	}
+	start := c.last[req.Day] + 1
+	if req.Day == today {
+		if floor := now.Sub(c.period.startOf(now)).Milliseconds() * constStaticSeqPerMilli; floor > start {
+			start = floor
+		}
+	}
+	c.last[req.Day] = start + int64(req.Step) - 1
+	return &NewRangeResp{RangeStart: start, RangeEnd: c.last[req.Day]}, nil
+}
+
+// checkBackfill 静态节点模式下过去的日期没有可持久的时间下限，回填的号码会与之前（包括重启前）回填的号码重复，直接拒绝
+func (usage *RangeUsageInfoStruct) checkBackfill() error {
+	if usage.cfg.staticNodes > 0 {
+		return fmt.Errorf("%w: backfill by date is not supported with static node assignment", ErrInvalidOption)
+	}
+	return nil
+}
+
+// nodeSeq 静态节点模式下把本地序号映射到本节点独占的序号：seq = k * totalNodes + nodeID
+func (usage *RangeUsageInfoStruct) nodeSeq(k int64) int64 {
+	if usage.cfg.staticNodes == 0 {
+		return k
+	}
+	return k*int64(usage.cfg.staticNodes) + int64(usage.cfg.staticNodeID)
+}
+
+// localSeq nodeSeq 的逆变换，不属于本节点的序号返回 false
+func (usage *RangeUsageInfoStruct) localSeq(seq int64) (int64, bool) {
+	if usage.cfg.staticNodes == 0 {
+		return seq, true
+	}
+	total := int64(usage.cfg.staticNodes)
+	if seq%total != int64(usage.cfg.staticNodeID) {
+		return 0, false
+	}
+	return seq / total, true
+}
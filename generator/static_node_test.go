@@ -0,0 +1,49 @@
+package generator
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStaticNodesUnique(t *testing.T) {
+	const perNode = 500
+	cases := []struct {
+		name string
+		opts []Option
+	}{
+		{"plain", nil},
+		{"scramble", []Option{WithScramble(true)}},
+		{"scramble check char", []Option{WithScramble(true), WithCheckChar(true)}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := newFakeClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local))
+			seen := make(map[string]int)
+			for node := 0; node < 3; node++ {
+				opts := testOptions(append(tc.opts, WithClock(clock), WithStep(100), WithStaticNodeAssignment(node, 3))...)
+				usage, err := NewWithOptions(nil, opts...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i := 0; i < perNode; i++ {
+					id, err := usage.GenerateId("app")
+					if err != nil {
+						t.Fatal(err)
+					}
+					if prev, ok := seen[id]; ok {
+						t.Fatalf("%s issued by node %d and node %d", id, prev, node)
+					}
+					seen[id] = node
+					parts, err := usage.Parse(id)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if _, ok := usage.localSeq(parts.Sequence); !ok {
+						t.Fatalf("%s sequence %d does not belong to node %d", id, parts.Sequence, node)
+					}
+				}
+				_ = usage.Close()
+			}
+		})
+	}
+}
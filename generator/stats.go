@@ -0,0 +1,159 @@
+package generator
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// statsCounters 运行计数，全部通过 atomic 读写
+type statsCounters struct {
+	generated     int64 //成功返回的 id 数（含降级 id）
+	fallbacks     int64 //降级随机生成的 id 数
+	rangeRequests int64 //实际发出的号段申请次数
+	rangeErrors   int64 //号段申请失败次数
+	clockBackward int64 //降级路径检测到的时钟回拨次数
+
+	duplicateRanges int64 //号段服务连续返回相同号段的次数
+	coalesced       int64 //合并到其它申请方的号段申请数
+	dayStarts       int64 //拿到新日期第一个号段的次数
+	dayRefreshes    int64 //当前号段日期内切换号段的次数
+	backupRanges    int64 //由备用号段服务提供的号段数
+	backupErrors    int64 //备用号段服务申请失败次数
+	busyRejects     int64 //超过 WithMaxInflight 上限被拒绝的生成调用数
+	duplicateIds    int64 //WithDuplicateGuard 发现的可能重复的 id 数
+	futureDates     int64 //号段申请时间晚于当前时钟超过容忍范围的次数
+	rejectedIds     int64 //被 WithIDValidator 拒绝跳过的 id 数
+	grantedStep     int64 //最近一次正常申请号段时服务端实际分配的步长
+	adaptiveStep    int64 //按服务端实际分配的步长调整后的申请步长，0 表示使用配置的步长
+}
+
+// Stats 生成器运行状态快照
+type Stats struct {
+	Generated     int64
+	Fallbacks     int64
+	RangeRequests int64
+	RangeErrors   int64
+	FallbackRate  int64 //最近一分钟内的降级 id 数
+
+	ClockBackwardEvents int64 //降级路径检测到的时钟回拨次数
+	DuplicateRanges     int64 //号段服务连续返回相同号段的次数
+	CoalescedRequests   int64 //合并到其它申请方、没有单独请求号段服务的号段申请数
+	DayStarts           int64 //拿到新日期第一个号段的次数
+	DayRangeRefreshes   int64 //当前号段日期内切换号段的次数
+
+	PrimaryRanges int64 //由主号段服务提供的号段数
+	BackupRanges  int64 //主号段服务失败后由备用号段服务提供的号段数
+	BackupErrors  int64 //备用号段服务申请失败次数
+
+	Inflight    int64 //进行中的生成调用数
+	BusyRejects int64 //超过 WithMaxInflight 上限被拒绝的生成调用数
+
+	DuplicateIds     int64 //WithDuplicateGuard 发现的可能重复的 id 数，含布隆过滤器的误判
+	FutureApplyDates int64 //号段申请时间晚于当前时钟超过容忍范围的次数，见 WithFutureDatePolicy
+	RejectedIds      int64 //被 WithIDValidator 拒绝跳过的 id 数
+	LastGrantedStep  int64 //最近一次正常申请号段时服务端实际分配的步长，见 NewRangeResp.GrantedStep
+	NextStep         int64 //下一次正常申请号段使用的步长，服务端分配不足时小于配置的步长
+
+	CurrentRangeStart int64
+	CurrentMaxId      int64
+	CurrentRangeEnd   int64
+	RangeDay          string //当前号段所属日期
+	Remaining         int64  //当前号段和预取队列中剩余可用的号码数
+}
+
+// remainingLocked 当前号段和预取队列中剩余可用的号码数，调用方需持有 usageM
+func (usage *RangeUsageInfoStruct) remainingLocked() int64 {
+	var remaining int64
+	if usage.currentRangeEnd > usage.currentMaxId {
+		remaining = usage.currentRangeEnd - usage.currentMaxId
+	}
+	for _, r := range usage.rangeQueue {
+		remaining += r.end - r.start + 1
+	}
+	return remaining
+}
+
+// Stats 返回当前运行状态，计数部分不加锁读取，号段部分在锁内读取
+func (usage *RangeUsageInfoStruct) Stats() Stats {
+	s := Stats{
+		Generated:     atomic.LoadInt64(&usage.counters.generated),
+		Fallbacks:     atomic.LoadInt64(&usage.counters.fallbacks),
+		RangeRequests: atomic.LoadInt64(&usage.counters.rangeRequests),
+		RangeErrors:   atomic.LoadInt64(&usage.counters.rangeErrors),
+		FallbackRate:  usage.fallbackWindow.count(usage.cfg.clock.Now()),
+
+		ClockBackwardEvents: atomic.LoadInt64(&usage.counters.clockBackward),
+		DuplicateRanges:     atomic.LoadInt64(&usage.counters.duplicateRanges),
+		CoalescedRequests:   atomic.LoadInt64(&usage.counters.coalesced),
+		DayStarts:           atomic.LoadInt64(&usage.counters.dayStarts),
+		DayRangeRefreshes:   atomic.LoadInt64(&usage.counters.dayRefreshes),
+		BackupRanges:        atomic.LoadInt64(&usage.counters.backupRanges),
+		BackupErrors:        atomic.LoadInt64(&usage.counters.backupErrors),
+		Inflight:            atomic.LoadInt64(&usage.inflight),
+		BusyRejects:         atomic.LoadInt64(&usage.counters.busyRejects),
+		DuplicateIds:        atomic.LoadInt64(&usage.counters.duplicateIds),
+		FutureApplyDates:    atomic.LoadInt64(&usage.counters.futureDates),
+		RejectedIds:         atomic.LoadInt64(&usage.counters.rejectedIds),
+		LastGrantedStep:     atomic.LoadInt64(&usage.counters.grantedStep),
+		NextStep:            int64(usage.step()),
+	}
+	s.PrimaryRanges = s.RangeRequests - s.RangeErrors
+	usage.usageM.Lock()
+	s.CurrentRangeStart = usage.currentRangeStart
+	s.CurrentMaxId = usage.currentMaxId
+	s.CurrentRangeEnd = usage.currentRangeEnd
+	s.RangeDay = usage.rangeDay
+	s.Remaining = usage.remainingLocked()
+	usage.usageM.Unlock()
+	return s
+}
+
+// minuteWindow 按秒分桶统计最近一分钟内的事件数
+type minuteWindow struct {
+	m       sync.Mutex
+	seconds [60]int64
+	counts  [60]int64
+}
+
+func (w *minuteWindow) add(now time.Time) {
+	sec := now.Unix()
+	idx := sec % 60
+	w.m.Lock()
+	defer w.m.Unlock()
+	if w.seconds[idx] != sec {
+		w.seconds[idx] = sec
+		w.counts[idx] = 0
+	}
+	w.counts[idx]++
+}
+
+func (w *minuteWindow) count(now time.Time) int64 {
+	sec := now.Unix()
+	w.m.Lock()
+	defer w.m.Unlock()
+	var total int64
+	for i := range w.seconds {
+		if sec-w.seconds[i] < 60 {
+			total += w.counts[i]
+		}
+	}
+	return total
+}
+
+// allowFallback 判断是否还能生成降级 id，配置了 WithMaxFallbackRate 且最近一分钟的降级 id 数已达上限时返回 false
+func (usage *RangeUsageInfoStruct) allowFallback(now time.Time) bool {
+	limit := usage.tunables().maxFallbackPerMinute
+	if limit <= 0 {
+		return true
+	}
+	return usage.fallbackWindow.count(now) < int64(limit)
+}
+
+func (usage *RangeUsageInfoStruct) recordFallback(now time.Time) {
+	atomic.AddInt64(&usage.counters.fallbacks, 1)
+	usage.fallbackWindow.add(now)
+	if usage.tunables().maxFallbackPerMinute > 0 {
+		usage.clearFallbackLimited()
+	}
+}
@@ -0,0 +1,30 @@
+package generator
+
+const constStreamBlock = 128 //Stream 每次通过 GenerateIds 预先生成的 id 数量
+
+// Stream 返回一个拉取式的 id 迭代器，每次调用返回下一个 id，号段用完时自动申请新号段，适用于批量导入等场景
+// 迭代器每次通过 GenerateIds 预先生成一批 id 缓存在本地依次返回，不必每个 id 都加锁；
+// 缓存的 id 在生成时已确定日期，跨天时最多还会返回一批前一天的 id，不再调用时未返回的 id 不会再发放，
+// 生成失败时返回错误，下次调用重新生成。迭代器不能并发调用，需要并发时每个协程各自调用 Stream
+// 模块要求 go 1.19，因此不提供 iter.Seq2 形式，用法：
+//
+//	next := gen.Stream(app)
+//	for i := 0; i < n; i++ {
+//		id, err := next()
+//		...
+//	}
+func (usage *RangeUsageInfoStruct) Stream(applicationName string) func() (string, error) {
+	var buf []string
+	return func() (string, error) {
+		if len(buf) == 0 {
+			ids, err := usage.GenerateIds(applicationName, constStreamBlock)
+			if err != nil {
+				return "", err
+			}
+			buf = ids
+		}
+		id := buf[0]
+		buf = buf[1:]
+		return id, nil
+	}
+}
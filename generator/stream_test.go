@@ -0,0 +1,77 @@
+package generator
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestStreamUnique(t *testing.T) {
+	const perStream = 3000
+	caller := newMemCaller()
+	usage, err := NewWithOptions(caller.apply, testOptions(WithStep(500))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+
+	//两个迭代器与直接调用 GenerateId 交替进行，id 互不重复
+	var mu sync.Mutex
+	seen := make(map[string]bool, 3*perStream)
+	record := func(id string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if seen[id] {
+			t.Errorf("duplicate id %s", id)
+		}
+		seen[id] = true
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < 2; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			next := usage.Stream("app")
+			for i := 0; i < perStream; i++ {
+				id, err := next()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				record(id)
+			}
+		}()
+	}
+	for i := 0; i < perStream; i++ {
+		id, err := usage.GenerateId("app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		record(id)
+	}
+	wg.Wait()
+	if len(seen) != 3*perStream {
+		t.Fatalf("%d unique ids, want %d", len(seen), 3*perStream)
+	}
+	if stats := usage.Stats(); stats.Fallbacks != 0 {
+		t.Fatalf("%d fallback ids", stats.Fallbacks)
+	}
+}
+
+func TestStreamError(t *testing.T) {
+	caller := newMemCaller()
+	caller.setFail(true)
+	usage, err := NewWithOptions(caller.apply, testOptions(WithNoFallback())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	next := usage.Stream("app")
+	if _, err := next(); err == nil {
+		t.Fatal("stream returned an id while the numbers service is down")
+	}
+	//服务恢复后继续生成
+	caller.setFail(false)
+	if _, err := next(); err != nil {
+		t.Fatal(err)
+	}
+}
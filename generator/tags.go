@@ -0,0 +1,142 @@
+package generator
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FallbackEvent 生成降级 id 时的回调事件，Tags 为 GenerateIdWithTags 传入的标签
+type FallbackEvent struct {
+	Time    time.Time
+	AppName string
+	BizType string
+	Id      string
+	Tags    map[string]string
+}
+
+// NewRangeEvent 成功申请到号段时的回调事件，Tags 为触发这次申请的 GenerateIdWithTags 调用传入的标签，
+// 后台预取、跨天提前申请等不是由带标签的调用触发的申请为 nil
+type NewRangeEvent struct {
+	Time       time.Time
+	AppName    string
+	BizType    string
+	Day        string
+	RangeStart int64
+	RangeEnd   int64
+	Tags       map[string]string
+}
+
+// GenerateIdWithTags 与 GenerateId 相同，tags（如 trace id、用户 id）会附加在本次调用产生的生成日志末尾，
+// 并传给本次调用触发的 OnFallback、OnNewRange 回调，便于把 id 与请求关联起来；标签不会出现在 id 中，也不会发送给号段服务
+func (usage *RangeUsageInfoStruct) GenerateIdWithTags(applicationName string, tags map[string]string) (string, error) {
+	return usage.GenerateIdWithTagsCtx(context.Background(), applicationName, tags)
+}
+
+// GenerateIdWithTagsCtx 与 GenerateIdWithTags 相同，ctx 的处理见 GenerateIdCtx
+func (usage *RangeUsageInfoStruct) GenerateIdWithTagsCtx(ctx context.Context, applicationName string, tags map[string]string) (string, error) {
+	var copied map[string]string
+	if len(tags) > 0 {
+		copied = make(map[string]string, len(tags))
+		for k, v := range tags {
+			copied[k] = v
+		}
+	}
+	return usage.generateIdOfType(ctx, applicationName, "", usage.cfg.typeFlag, copied)
+}
+
+// requestTags 随号段申请传递的调用方标签
+type requestTags struct {
+	m map[string]string
+}
+
+func newRequestTags(tags map[string]string) *requestTags {
+	if len(tags) == 0 {
+		return nil
+	}
+	return &requestTags{m: tags}
+}
+
+// taggedLogger 在每条日志末尾附加调用方的标签
+type taggedLogger struct {
+	logs LogInterface
+	tags string
+}
+
+func (l taggedLogger) Debug(format string, v ...any) {
+	l.logs.Debug(format+" {}", append(v, l.tags)...)
+}
+
+func (l taggedLogger) Info(format string, v ...any) {
+	l.logs.Info(format+" {}", append(v, l.tags)...)
+}
+
+func (l taggedLogger) Warn(format string, v ...any) {
+	l.logs.Warn(format+" {}", append(v, l.tags)...)
+}
+
+func (l taggedLogger) Error(format string, v ...any) {
+	l.logs.Error(format+" {}", append(v, l.tags)...)
+}
+
+// callLogs 本次调用使用的日志，没有标签时为实例的日志
+func (usage *RangeUsageInfoStruct) callLogs(tags map[string]string) LogInterface {
+	if len(tags) == 0 {
+		return usage.logs
+	}
+	return taggedLogger{logs: usage.logs, tags: formatTags(tags)}
+}
+
+// formatTags 按键排序输出为 [k1=v1 k2=v2]
+func formatTags(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(tags[k])
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+func (usage *RangeUsageInfoStruct) emitFallback(id string, tags map[string]string) {
+	if usage.cfg.onFallback == nil {
+		return
+	}
+	usage.cfg.onFallback(FallbackEvent{
+		Time:    usage.cfg.clock.Now(),
+		AppName: usage.appName,
+		BizType: usage.bizType,
+		Id:      id,
+		Tags:    tags,
+	})
+}
+
+func (usage *RangeUsageInfoStruct) emitNewRange(req *ApplyReq, resp *NewRangeResp) {
+	if usage.cfg.onNewRange == nil {
+		return
+	}
+	var tags map[string]string
+	if req.tags != nil {
+		tags = req.tags.m
+	}
+	usage.cfg.onNewRange(NewRangeEvent{
+		Time:       usage.cfg.clock.Now(),
+		AppName:    req.AppName,
+		BizType:    req.BizType,
+		Day:        req.Day,
+		RangeStart: resp.RangeStart,
+		RangeEnd:   resp.RangeEnd,
+		Tags:       tags,
+	})
+}
@@ -0,0 +1,114 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// captureLogger 按 {} 占位符展开并记录所有日志
+type captureLogger struct {
+	m     sync.Mutex
+	lines []string
+}
+
+func (l *captureLogger) add(format string, v []any) {
+	line := format
+	for _, arg := range v {
+		line = strings.Replace(line, "{}", fmt.Sprint(arg), 1)
+	}
+	l.m.Lock()
+	l.lines = append(l.lines, line)
+	l.m.Unlock()
+}
+
+func (l *captureLogger) Debug(format string, v ...any) { l.add(format, v) }
+func (l *captureLogger) Info(format string, v ...any)  { l.add(format, v) }
+func (l *captureLogger) Warn(format string, v ...any)  { l.add(format, v) }
+func (l *captureLogger) Error(format string, v ...any) { l.add(format, v) }
+
+// containing 返回同时包含所有 substrs 的日志行
+func (l *captureLogger) containing(substrs ...string) []string {
+	l.m.Lock()
+	defer l.m.Unlock()
+	var found []string
+	for _, line := range l.lines {
+		matched := true
+		for _, s := range substrs {
+			if !strings.Contains(line, s) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			found = append(found, line)
+		}
+	}
+	return found
+}
+
+// TestGenerateIdWithTags 标签出现在本次调用的日志和 OnNewRange、OnFallback 回调中，不出现在 id 中
+func TestGenerateIdWithTags(t *testing.T) {
+	const wantTags = "[trace=t-42 user=u9]"
+	tags := map[string]string{"user": "u9", "trace": "t-42"}
+	caller := newMemCaller()
+	logs := &captureLogger{}
+	var m sync.Mutex
+	var ranges []NewRangeEvent
+	var fallbacks []FallbackEvent
+	usage, err := NewWithOptions(caller.apply, testOptions(WithLogger(logs), OnNewRange(func(e NewRangeEvent) {
+		m.Lock()
+		ranges = append(ranges, e)
+		m.Unlock()
+	}), OnFallback(func(e FallbackEvent) {
+		m.Lock()
+		fallbacks = append(fallbacks, e)
+		m.Unlock()
+	}))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+
+	id, err := usage.GenerateIdWithTags("app", tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(id, "t-42") || strings.Contains(id, "u9") {
+		t.Fatalf("tags leaked into id %s", id)
+	}
+	if lines := logs.containing("请求新的id", wantTags); len(lines) == 0 {
+		t.Fatalf("no tagged generate log, got %q", logs.lines)
+	}
+	m.Lock()
+	if len(ranges) != 1 || ranges[0].Tags["trace"] != "t-42" || ranges[0].Tags["user"] != "u9" {
+		t.Fatalf("OnNewRange events %+v, want one with the call's tags", ranges)
+	}
+	m.Unlock()
+
+	//不带标签的调用不附加标签
+	if _, err := usage.GenerateId("app"); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(logs.containing("请求新的id")); n != len(logs.containing("请求新的id", wantTags))+1 {
+		t.Fatal("untagged GenerateId logged with tags")
+	}
+
+	caller.setFail(true)
+	usage.usageM.Lock()
+	usage.currentMaxId = usage.currentRangeEnd
+	usage.usageM.Unlock()
+	fallbackId, err := usage.GenerateIdWithTags("app", tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := logs.containing("降级", wantTags); len(lines) == 0 {
+		t.Fatalf("no tagged fallback log, got %q", logs.lines)
+	}
+	m.Lock()
+	defer m.Unlock()
+	if len(fallbacks) != 1 || fallbacks[0].Id != fallbackId || fallbacks[0].Tags["trace"] != "t-42" || fallbacks[0].AppName != "app" {
+		t.Fatalf("OnFallback events %+v, want one for %s with the call's tags", fallbacks, fallbackId)
+	}
+}
@@ -0,0 +1,260 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// templateToken id 模板中的组成部分
+type templateToken int
+
+const (
+	tokenLiteral templateToken = iota //固定的分隔字符
+	tokenPrefix                       //{prefix}，含追加前缀和业务线代码
+	tokenDate                         //{date}，周期标识
+	tokenSeq                          //{seq}，类型标识、序号、实例标识和分片字符，降级 id 为随机后缀
+)
+
+var templateTokens = map[string]templateToken{
+	"{prefix}": tokenPrefix,
+	"{date}":   tokenDate,
+	"{seq}":    tokenSeq,
+}
+
+type templateSegment struct {
+	token templateToken
+	text  string //tokenLiteral 的内容
+}
+
+// idTemplate WithFormat 解析后的模板，empty 为前缀为空时省略 {prefix} 及其相邻分隔符后的模板
+type idTemplate struct {
+	full  []templateSegment
+	empty []templateSegment
+}
+
+// parseTemplate 解析 id 模板：{prefix}、{seq} 必须且只能出现一次，{date} 最多出现一次，
+// 固定字符只能是 -、_、.，{prefix} 与 {seq} 之间必须有 {date} 或固定字符，否则无法拆分
+func parseTemplate(format string) (*idTemplate, error) {
+	var segs []templateSegment
+	counts := make(map[templateToken]int, 3)
+	for rest := format; rest != ""; {
+		if rest[0] == '{' {
+			end := strings.IndexByte(rest, '}')
+			if end < 0 {
+				return nil, fmt.Errorf("format %q has an unclosed placeholder", format)
+			}
+			token, ok := templateTokens[rest[:end+1]]
+			if !ok {
+				return nil, fmt.Errorf("format %q has unknown placeholder %s", format, rest[:end+1])
+			}
+			counts[token]++
+			segs = append(segs, templateSegment{token: token})
+			rest = rest[end+1:]
+			continue
+		}
+		if !strings.ContainsRune(constDateSeparators, rune(rest[0])) {
+			return nil, fmt.Errorf("format %q literal %q must be one of %q", format, rest[0], constDateSeparators)
+		}
+		if n := len(segs); n > 0 && segs[n-1].token == tokenLiteral {
+			segs[n-1].text += rest[:1]
+		} else {
+			segs = append(segs, templateSegment{token: tokenLiteral, text: rest[:1]})
+		}
+		rest = rest[1:]
+	}
+	if counts[tokenPrefix] != 1 || counts[tokenSeq] != 1 || counts[tokenDate] > 1 {
+		return nil, fmt.Errorf("format %q needs exactly one {prefix} and {seq} and at most one {date}", format)
+	}
+	for i := 1; i < len(segs); i++ {
+		a, b := segs[i-1].token, segs[i].token
+		if (a == tokenPrefix && b == tokenSeq) || (a == tokenSeq && b == tokenPrefix) {
+			return nil, fmt.Errorf("format %q has {prefix} glued to {seq}", format)
+		}
+	}
+	return &idTemplate{full: segs, empty: withoutPrefix(segs)}, nil
+}
+
+// withoutPrefix 去掉 {prefix} 及其后的分隔符，{prefix} 在末尾时去掉其前的分隔符
+func withoutPrefix(segs []templateSegment) []templateSegment {
+	out := make([]templateSegment, 0, len(segs))
+	for i := 0; i < len(segs); i++ {
+		if segs[i].token != tokenPrefix {
+			out = append(out, segs[i])
+			continue
+		}
+		if i+1 < len(segs) && segs[i+1].token == tokenLiteral {
+			i++
+		} else if n := len(out); n > 0 && out[n-1].token == tokenLiteral {
+			out = out[:n-1]
+		}
+	}
+	return out
+}
+
+func (t *idTemplate) segments(prefix string) []templateSegment {
+	if prefix == "" {
+		return t.empty
+	}
+	return t.full
+}
+
+// hasDate 模板是否包含 {date}
+func (t *idTemplate) hasDate() bool {
+	for _, seg := range t.full {
+		if seg.token == tokenDate {
+			return true
+		}
+	}
+	return false
+}
+
+// dateGlued {date} 与 {seq} 是否直接相连，相连时序号字符不能是数字
+func (t *idTemplate) dateGlued() bool {
+	for i := 1; i < len(t.full); i++ {
+		a, b := t.full[i-1].token, t.full[i].token
+		if (a == tokenDate && b == tokenSeq) || (a == tokenSeq && b == tokenDate) {
+			return true
+		}
+	}
+	return false
+}
+
+// literals 模板中出现的所有固定字符
+func (t *idTemplate) literals() string {
+	var sb strings.Builder
+	for _, seg := range t.full {
+		sb.WriteString(seg.text)
+	}
+	return sb.String()
+}
+
+// literalLen 按前缀是否为空计算固定字符的长度
+func (t *idTemplate) literalLen(prefix string) int {
+	n := 0
+	for _, seg := range t.segments(prefix) {
+		n += len(seg.text)
+	}
+	return n
+}
+
+// render 按模板拼出 id
+func (t *idTemplate) render(prefix string, day string, suffix string) string {
+	var sb strings.Builder
+	for _, seg := range t.segments(prefix) {
+		switch seg.token {
+		case tokenPrefix:
+			sb.WriteString(prefix)
+		case tokenDate:
+			sb.WriteString(day)
+		case tokenSeq:
+			sb.WriteString(suffix)
+		default:
+			sb.WriteString(seg.text)
+		}
+	}
+	return sb.String()
+}
+
+// split 按模板把 id 拆成前缀、周期标识和 {seq} 部分：先按带前缀的模板匹配，前缀尽量长，不匹配时再按前缀为空的模板匹配
+func (t *idTemplate) split(id string, dates dateCodec) (string, string, string, error) {
+	for _, segs := range [][]templateSegment{t.full, t.empty} {
+		var m templateMatch
+		if m.match(id, segs, dates) {
+			return m.prefix, m.day, m.rest, nil
+		}
+	}
+	return "", "", "", fmt.Errorf("%w: %s does not match format", ErrInvalidId, id)
+}
+
+type templateMatch struct {
+	prefix, day, rest string
+}
+
+// match 回溯匹配，{date} 按日期格式定长匹配，{prefix} 和 {seq} 至少一个字符，从长到短尝试
+func (m *templateMatch) match(s string, segs []templateSegment, dates dateCodec) bool {
+	if len(segs) == 0 {
+		return s == ""
+	}
+	seg := segs[0]
+	switch seg.token {
+	case tokenLiteral:
+		return strings.HasPrefix(s, seg.text) && m.match(s[len(seg.text):], segs[1:], dates)
+	case tokenDate:
+		n := dates.width()
+		if len(s) < n {
+			return false
+		}
+		day, ok := dates.parse(s[:n])
+		if !ok {
+			return false
+		}
+		m.day = day
+		return m.match(s[n:], segs[1:], dates)
+	}
+	longest := len(s)
+	if seg.token == tokenSeq {
+		//{seq} 只由字母和数字组成，不会跨过分隔字符
+		if pos := strings.IndexAny(s, constDateSeparators); pos >= 0 {
+			longest = pos
+		}
+	}
+	for n := longest; n >= 1; n-- {
+		if seg.token == tokenPrefix {
+			m.prefix = s[:n]
+		} else {
+			m.rest = s[:n]
+		}
+		if m.match(s[n:], segs[1:], dates) {
+			return true
+		}
+	}
+	return false
+}
+
+// keySeparators 数字映射结果不能使用的分隔字符：配置了模板时为模板中的固定字符，否则为日期分隔符
+func (c *config) keySeparators() string {
+	if c.template != nil {
+		return c.template.literals()
+	}
+	return c.dateSeparator
+}
+
+// dateGlued 日期与序号是否直接相连
+func (c *config) dateGlued() bool {
+	if c.template != nil {
+		return c.template.dateGlued()
+	}
+	return c.dateSeparator == "" && !c.dateless
+}
+
+// defaultTemplate 默认格式对应的模板，设置了日期格式但没有设置模板时按该模板拼接和拆分
+func defaultTemplate(dateSeparator string, dateless bool) string {
+	if dateless {
+		return "{prefix}-{seq}"
+	}
+	return "{prefix}-{date}" + dateSeparator + "{seq}"
+}
+
+// validateFormat 解析并校验 WithFormat 设置的模板，不能与日期分隔符同时使用，无日期模式下不能包含 {date}，否则必须包含；
+// 没有设置模板但设置了日期格式时使用默认格式对应的模板
+func (c *config) validateFormat() error {
+	c.template = nil
+	if c.format == "" {
+		if c.dateLayout != "" {
+			c.template, _ = parseTemplate(defaultTemplate(c.dateSeparator, c.dateless))
+		}
+		return nil
+	}
+	t, err := parseTemplate(c.format)
+	if err != nil {
+		return err
+	}
+	if c.dateSeparator != "" {
+		return fmt.Errorf("format %q conflicts with date separator, put the separator in the format", c.format)
+	}
+	if t.hasDate() == c.dateless {
+		return fmt.Errorf("format %q must contain {date} unless dateless mode is on, and must not otherwise", c.format)
+	}
+	c.template = t
+	return nil
+}
@@ -0,0 +1,30 @@
+package generator
+
+import (
+	"testing"
+	"time"
+)
+
+// TestGenerateIdTimed 需要同步申请号段的调用耗时包含申请号段的时间，号段内取号的调用明显更快
+func TestGenerateIdTimed(t *testing.T) {
+	const delay = 20 * time.Millisecond
+	usage, err := NewWithOptions(slowCaller(delay), testOptions(WithStep(100))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	_, fetched, err := usage.GenerateIdTimed("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, inRange, err := usage.GenerateIdTimed("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fetched < delay {
+		t.Fatalf("latency %s with a range fetch, want at least %s", fetched, delay)
+	}
+	if inRange <= 0 || inRange >= fetched {
+		t.Fatalf("latency %s within the range, want positive and below %s", inRange, fetched)
+	}
+}
@@ -0,0 +1,57 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+)
+
+// validateTypeFlag 类型标识只能是字母或数字，且不能与降级标记、数字映射结果、分隔符冲突，
+// 日期与序号直接相连时不能是数字；配置了校验函数时还需通过校验
+func (c *config) validateTypeFlag(flag byte) error {
+	if !(flag >= 'A' && flag <= 'Z') && !(flag >= 'a' && flag <= 'z') && !(flag >= '0' && flag <= '9') {
+		return fmt.Errorf("%w: type flag %q must be a letter or digit", ErrInvalidTypeFlag, flag)
+	}
+	if flag == constFallbackMarker {
+		return fmt.Errorf("%w: type flag %q is the fallback marker", ErrInvalidTypeFlag, flag)
+	}
+	for _, ch := range c.keyMap {
+		if ch == flag {
+			return fmt.Errorf("%w: type flag %q collides with key map output", ErrInvalidTypeFlag, flag)
+		}
+	}
+	if c.dateGlued() && flag >= '0' && flag <= '9' {
+		return fmt.Errorf("%w: type flag %q is a digit glued to the date", ErrInvalidTypeFlag, flag)
+	}
+	if c.typeFlagValidate != nil && !c.typeFlagValidate(flag) {
+		return fmt.Errorf("%w: type flag %q rejected by validator", ErrInvalidTypeFlag, flag)
+	}
+	return nil
+}
+
+// GenerateIdOfType 生成以 flag 为类型标识的 id，类型标识位于日期之后、序号之前，需先通过 WithTypeFlag 开启
+func (usage *RangeUsageInfoStruct) GenerateIdOfType(applicationName string, flag byte) (string, error) {
+	return usage.GenerateIdOfTypeCtx(context.Background(), applicationName, flag)
+}
+
+// GenerateIdOfTypeCtx 与 GenerateIdOfType 相同，ctx 的处理见 GenerateIdCtx
+func (usage *RangeUsageInfoStruct) GenerateIdOfTypeCtx(ctx context.Context, applicationName string, flag byte) (string, error) {
+	if usage.cfg.typeFlag == 0 {
+		return "", fmt.Errorf("%w: type flag not enabled", ErrInvalidTypeFlag)
+	}
+	if err := usage.cfg.validateTypeFlag(flag); err != nil {
+		return "", err
+	}
+	return usage.generateIdOfType(ctx, applicationName, "", flag, nil)
+}
+
+// TypeFlagOf 返回 id 中的类型标识，未开启 WithTypeFlag 时返回 ErrInvalidTypeFlag
+func (usage *RangeUsageInfoStruct) TypeFlagOf(id string) (byte, error) {
+	if usage.cfg.typeFlag == 0 {
+		return 0, fmt.Errorf("%w: type flag not enabled", ErrInvalidTypeFlag)
+	}
+	parts, err := usage.Parse(id)
+	if err != nil {
+		return 0, err
+	}
+	return parts.TypeFlag, nil
+}
@@ -0,0 +1,85 @@
+package generator
+
+import (
+	"errors"
+	"testing"
+)
+
+func paymentOrRefund(flag byte) bool {
+	return flag == 'P' || flag == 'K'
+}
+
+// TestGenerateIdOfType 两种类型的 id 各不相同，类型标识能从正常 id 和降级 id 中还原
+func TestGenerateIdOfType(t *testing.T) {
+	caller := newMemCaller()
+	usage, err := NewWithOptions(caller.apply, testOptions(WithTypeFlag('P', paymentOrRefund), WithStep(20))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+
+	seen := make(map[string]bool)
+	check := func(id string, want byte, fallback bool) {
+		t.Helper()
+		if seen[id] {
+			t.Fatalf("duplicate id %s", id)
+		}
+		seen[id] = true
+		flag, err := usage.TypeFlagOf(id)
+		if err != nil || flag != want {
+			t.Fatalf("TypeFlagOf(%s) = %q, %v, want %q", id, flag, err, want)
+		}
+		parts, err := usage.Parse(id)
+		if err != nil || parts.TypeFlag != want || parts.Fallback != fallback {
+			t.Fatalf("Parse(%s) = %+v, %v, want type flag %q fallback %v", id, parts, err, want, fallback)
+		}
+	}
+	for i := 0; i < 30; i++ {
+		payment, err := usage.GenerateId("app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(payment, 'P', false)
+		refund, err := usage.GenerateIdOfType("app", 'K')
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(refund, 'K', false)
+	}
+
+	caller.setFail(true)
+	usage.usageM.Lock()
+	usage.currentMaxId = usage.currentRangeEnd
+	usage.usageM.Unlock()
+	refund, err := usage.GenerateIdOfType("app", 'K')
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(refund, 'K', true)
+}
+
+func TestGenerateIdOfTypeRejected(t *testing.T) {
+	usage, err := NewWithOptions(newMemCaller().apply, testOptions(WithTypeFlag('P', paymentOrRefund))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	for _, flag := range []byte{constFallbackMarker, 'R', '5', '-', 'Z'} {
+		if _, err := usage.GenerateIdOfType("app", flag); !errors.Is(err, ErrInvalidTypeFlag) {
+			t.Fatalf("flag %q: got %v, want ErrInvalidTypeFlag", flag, err)
+		}
+	}
+
+	if _, err := NewWithOptions(newMemCaller().apply, testOptions(WithTypeFlag(constFallbackMarker, nil))...); !errors.Is(err, ErrInvalidOption) {
+		t.Fatalf("fallback marker as default flag: got %v, want ErrInvalidOption", err)
+	}
+
+	plain, err := NewWithOptions(newMemCaller().apply, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.Close()
+	if _, err := plain.GenerateIdOfType("app", 'P'); !errors.Is(err, ErrInvalidTypeFlag) {
+		t.Fatalf("type flag not enabled: got %v, want ErrInvalidTypeFlag", err)
+	}
+}
@@ -0,0 +1,10 @@
+package generator
+
+import "github.com/google/uuid"
+
+// toUUID UUID 输出模式下把 id 转换为以 namespace 为命名空间的 v5 UUID
+// 转换是确定且单向的：相同的 id 总是得到相同的 UUID，不同的 id 得到不同的 UUID（SHA-1 碰撞概率可以忽略），
+// 但无法从 UUID 还原前缀、日期和序号
+func (usage *RangeUsageInfoStruct) toUUID(id string) string {
+	return uuid.NewSHA1(*usage.cfg.uuidNamespace, []byte(id)).String()
+}
@@ -0,0 +1,66 @@
+package generator
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestUUIDOutput(t *testing.T) {
+	namespace := uuid.MustParse("6ba7b811-9dad-11d1-80b4-00c04fd430c8")
+	clock := newFakeClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local))
+	newUsage := func() *RangeUsageInfoStruct {
+		usage, err := NewWithOptions(newMemCaller().apply, testOptions(WithClock(clock), WithUUIDOutput(namespace))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return usage
+	}
+	first, second := newUsage(), newUsage()
+	defer first.Close()
+	defer second.Close()
+
+	seen := make(map[string]bool)
+	for i := 0; i < 200; i++ {
+		id, err := first.GenerateId("app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		u, err := uuid.Parse(id)
+		if err != nil || u.Version() != 5 {
+			t.Fatalf("id %s is not a v5 uuid (%v)", id, err)
+		}
+		if seen[id] {
+			t.Fatalf("duplicate uuid %s", id)
+		}
+		seen[id] = true
+		//同一序号总是得到同一个 UUID
+		again, err := second.GenerateId("app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again != id {
+			t.Fatalf("sequence %d gave %s and %s", i+1, id, again)
+		}
+		key, err := first.GenerateKey(int64(i+1), "T", "20240101")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := uuid.NewSHA1(namespace, []byte(key)).String(); id != want {
+			t.Fatalf("uuid %s, want the v5 uuid of %s = %s", id, key, want)
+		}
+	}
+
+	id, err := first.GenerateId("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := first.Parse(id); !errors.Is(err, ErrNotDecodable) {
+		t.Fatalf("Parse in uuid mode error %v, want ErrNotDecodable", err)
+	}
+	if _, err := first.DecodeKey("ABC"); !errors.Is(err, ErrNotDecodable) {
+		t.Fatalf("DecodeKey in uuid mode error %v, want ErrNotDecodable", err)
+	}
+}
@@ -0,0 +1,64 @@
+package generator
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestWillRefresh 覆盖号段充足、即将用完和跨天三种状态
+func TestWillRefresh(t *testing.T) {
+	caller := newMemCaller()
+	clock := newFakeClock(time.Date(2024, 1, 1, 23, 0, 0, 0, time.Local))
+	usage, err := NewWithOptions(caller.apply, testOptions(WithClock(clock), WithStep(100), WithThreshold(10))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usage.Close()
+	if !usage.WillRefresh() {
+		t.Fatal("WillRefresh false before the first range")
+	}
+	if _, err := usage.GenerateId("app"); err != nil {
+		t.Fatal(err)
+	}
+	if usage.WillRefresh() {
+		t.Fatal("WillRefresh true with 99 numbers left in range")
+	}
+
+	//号段即将用完且没有进行中的申请时会触发申请，已有进行中的申请时剩余的号码仍可直接发放
+	usage.usageM.Lock()
+	usage.currentMaxId = usage.currentRangeEnd - 5
+	usage.usageM.Unlock()
+	if !usage.WillRefresh() {
+		t.Fatal("WillRefresh false near exhaustion")
+	}
+	atomic.StoreInt32(&usage.gettingIdRangeCounter, 1)
+	if usage.WillRefresh() {
+		t.Fatal("WillRefresh true near exhaustion with a refresh in flight")
+	}
+	usage.usageM.Lock()
+	usage.currentMaxId = usage.currentRangeEnd
+	usage.usageM.Unlock()
+	if !usage.WillRefresh() {
+		t.Fatal("WillRefresh false with the range used up")
+	}
+	atomic.StoreInt32(&usage.gettingIdRangeCounter, 0)
+	usage.usageM.Lock()
+	usage.currentMaxId = usage.currentRangeEnd - 50
+	usage.usageM.Unlock()
+
+	clock.Add(2 * time.Hour)
+	if !usage.WillRefresh() {
+		t.Fatal("WillRefresh false after the day changed")
+	}
+	calls := caller.callCount()
+	if _, err := usage.GenerateId("app"); err != nil {
+		t.Fatal(err)
+	}
+	if caller.callCount() == calls {
+		t.Fatal("GenerateId after the day changed did not request a range")
+	}
+	if usage.WillRefresh() {
+		t.Fatal("WillRefresh true right after the new day's range")
+	}
+}
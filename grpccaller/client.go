@@ -0,0 +1,175 @@
+// Package grpccaller 通过 gRPC 访问号段服务，协议定义见 numberspb/numbers.proto；
+// Client 实现 generator.NumbersClient，NewServer 把任意 NumbersClient 包装成 gRPC 服务端
+package grpccaller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/betwins/numbers-apply/generator"
+	"github.com/betwins/numbers-apply/grpccaller/numberspb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Client 通过 gRPC 申请、归还号段，可直接传给 generator.NewWithClient，多个协程并发使用是安全的
+type Client struct {
+	cfg    config
+	client numberspb.NumbersServiceClient
+	conn   *grpc.ClientConn //Dial 创建的连接，Close 时关闭；New 传入的连接由调用方关闭
+
+	m      sync.Mutex
+	seen   map[bizKey]struct{} //申请过号段的 appName + bizType，用于上报心跳
+	stopCh chan struct{}
+	once   sync.Once
+}
+
+type bizKey struct {
+	appName string
+	bizType string
+}
+
+// New 使用调用方建立的连接创建客户端，Close 不会关闭 cc
+func New(cc grpc.ClientConnInterface, opts ...Option) (*Client, error) {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	c := &Client{
+		cfg:    cfg,
+		client: numberspb.NewNumbersServiceClient(cc),
+		seen:   make(map[bizKey]struct{}),
+		stopCh: make(chan struct{}),
+	}
+	if cfg.heartbeatInterval > 0 {
+		go c.heartbeatLoop(cfg.heartbeatInterval)
+	}
+	return c, nil
+}
+
+// Dial 连接 target 并创建客户端，WithDialOptions 必须设置传输安全，Close 时关闭连接
+func Dial(target string, opts ...Option) (*Client, error) {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+	conn, err := grpc.Dial(target, cfg.dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	c, err := New(conn, opts...)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	c.conn = conn
+	return c, nil
+}
+
+// Apply 调用 ApplyRange 申请号段
+func (c *Client) Apply(ctx context.Context, req *generator.ApplyReq) (*generator.NewRangeResp, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.timeout)
+	defer cancel()
+	resp, err := c.client.ApplyRange(ctx, &numberspb.ApplyRangeRequest{
+		AppName: req.AppName,
+		BizType: req.BizType,
+		Day:     req.Day,
+		Step:    int32(req.Step),
+	}, c.cfg.callOpts...)
+	if err != nil {
+		return nil, err
+	}
+	if c.cfg.heartbeatInterval > 0 {
+		c.m.Lock()
+		c.seen[bizKey{appName: req.AppName, bizType: req.BizType}] = struct{}{}
+		c.m.Unlock()
+	}
+	return &generator.NewRangeResp{
+		RangeStart:  resp.RangeStart,
+		RangeEnd:    resp.RangeEnd,
+		Day:         resp.Day,
+		GrantedStep: resp.GrantedStep,
+	}, nil
+}
+
+// Release 调用 ReleaseRange 归还号码，服务端返回 UNIMPLEMENTED 时视为成功
+func (c *Client) Release(ctx context.Context, req *generator.ReleaseReq) error {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.timeout)
+	defer cancel()
+	_, err := c.client.ReleaseRange(ctx, &numberspb.ReleaseRangeRequest{
+		AppName:    req.AppName,
+		BizType:    req.BizType,
+		Day:        req.Day,
+		RangeStart: req.RangeStart,
+		RangeEnd:   req.RangeEnd,
+	}, c.cfg.callOpts...)
+	if status.Code(err) == codes.Unimplemented {
+		return nil
+	}
+	return err
+}
+
+// Heartbeat 为 appName + bizType 上报一次心跳，返回服务端时间
+func (c *Client) Heartbeat(ctx context.Context, appName, bizType string) (time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.timeout)
+	defer cancel()
+	resp, err := c.client.Heartbeat(ctx, &numberspb.HeartbeatRequest{
+		AppName:  appName,
+		BizType:  bizType,
+		Instance: c.cfg.instance,
+	}, c.cfg.callOpts...)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(resp.ServerTimeUnixMilli), nil
+}
+
+// Close 停止心跳，Dial 创建的客户端同时关闭连接；重复调用是安全的
+func (c *Client) Close() error {
+	var err error
+	c.once.Do(func() {
+		close(c.stopCh)
+		if c.conn != nil {
+			err = c.conn.Close()
+		}
+	})
+	return err
+}
+
+// Caller 返回可传给 generator.New 等只接受 NumbersReqFunc 的构造函数的申请函数，不支持归还号段
+func (c *Client) Caller() generator.NumbersReqFunc {
+	return func(req *generator.ApplyReq) (*generator.NewRangeResp, error) {
+		return c.Apply(req.Context(), req)
+	}
+}
+
+// heartbeatLoop 定期为申请过号段的 appName + bizType 上报心跳，上报失败等下一次重试
+func (c *Client) heartbeatLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stopCh:
+			return
+		case <-ticker.C:
+		}
+		c.m.Lock()
+		keys := make([]bizKey, 0, len(c.seen))
+		for key := range c.seen {
+			keys = append(keys, key)
+		}
+		c.m.Unlock()
+		for _, key := range keys {
+			_, _ = c.Heartbeat(context.Background(), key.appName, key.bizType)
+		}
+	}
+}
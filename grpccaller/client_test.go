@@ -0,0 +1,217 @@
+package grpccaller
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/betwins/numbers-apply/callertest"
+	"github.com/betwins/numbers-apply/generator"
+	"github.com/betwins/numbers-apply/grpccaller/numberspb"
+	"github.com/betwins/numbers-apply/testsupport"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// heartbeatRecorder 记录收到的心跳，其余调用转发给 NewServer 返回的服务端
+type heartbeatRecorder struct {
+	numberspb.NumbersServiceServer
+	m     sync.Mutex
+	beats []*numberspb.HeartbeatRequest
+}
+
+func (s *heartbeatRecorder) Heartbeat(ctx context.Context, in *numberspb.HeartbeatRequest) (*numberspb.HeartbeatResponse, error) {
+	s.m.Lock()
+	s.beats = append(s.beats, in)
+	s.m.Unlock()
+	return s.NumbersServiceServer.Heartbeat(ctx, in)
+}
+
+func (s *heartbeatRecorder) received() []*numberspb.HeartbeatRequest {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return append([]*numberspb.HeartbeatRequest(nil), s.beats...)
+}
+
+// failingBackend 申请、归还都返回 err
+type failingBackend struct {
+	err error
+}
+
+func (b failingBackend) Apply(context.Context, *generator.ApplyReq) (*generator.NewRangeResp, error) {
+	return nil, b.err
+}
+
+func (b failingBackend) Release(context.Context, *generator.ReleaseReq) error {
+	return b.err
+}
+
+func (b failingBackend) Close() error {
+	return nil
+}
+
+// dialServer 在内存连接上启动 srv，返回连接到它的客户端
+func dialServer(t *testing.T, srv numberspb.NumbersServiceServer, opts ...Option) *Client {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	numberspb.RegisterNumbersServiceServer(gs, srv)
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	dialer := func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }
+	opts = append([]Option{WithDialOptions(grpc.WithContextDialer(dialer), grpc.WithTransportCredentials(insecure.NewCredentials()))}, opts...)
+	c, err := Dial("bufnet", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestConformance(t *testing.T) {
+	callertest.CallerConformanceTest(t, func() generator.NumbersReqFunc {
+		return dialServer(t, NewServer(testsupport.NewMemoryCaller().Caller())).Caller()
+	})
+}
+
+func TestApplyErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		backend generator.NumbersClient
+		req     generator.ApplyReq
+		want    codes.Code
+	}{
+		{"missing day", testsupport.NewMemoryCaller().Caller(), generator.ApplyReq{AppName: "app", BizType: "T", Step: 10}, codes.InvalidArgument},
+		{"zero step", testsupport.NewMemoryCaller().Caller(), generator.ApplyReq{AppName: "app", BizType: "T", Day: "20240101"}, codes.InvalidArgument},
+		{"backend error", failingBackend{err: errors.New("down")}, generator.ApplyReq{AppName: "app", BizType: "T", Day: "20240101", Step: 10}, codes.Unavailable},
+		{"backend status", failingBackend{err: status.Error(codes.ResourceExhausted, "quota")}, generator.ApplyReq{AppName: "app", BizType: "T", Day: "20240101", Step: 10}, codes.ResourceExhausted},
+		{"backend nil response", generator.NumbersReqFunc(func(*generator.ApplyReq) (*generator.NewRangeResp, error) { return nil, nil }), generator.ApplyReq{AppName: "app", BizType: "T", Day: "20240101", Step: 10}, codes.Internal},
+		{"backend deadline", failingBackend{err: context.DeadlineExceeded}, generator.ApplyReq{AppName: "app", BizType: "T", Day: "20240101", Step: 10}, codes.DeadlineExceeded},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := dialServer(t, NewServer(tc.backend))
+			if _, err := c.Apply(context.Background(), &tc.req); status.Code(err) != tc.want {
+				t.Fatalf("Apply error %v, want %s", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestApplyGrantedStep(t *testing.T) {
+	backend := generator.NumbersReqFunc(func(req *generator.ApplyReq) (*generator.NewRangeResp, error) {
+		return &generator.NewRangeResp{RangeStart: 1, RangeEnd: 5, Day: req.Day, GrantedStep: 5}, nil
+	})
+	c := dialServer(t, NewServer(backend))
+	resp, err := c.Apply(context.Background(), &generator.ApplyReq{AppName: "app", BizType: "T", Day: "20240101", Step: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *resp != (generator.NewRangeResp{RangeStart: 1, RangeEnd: 5, Day: "20240101", GrantedStep: 5}) {
+		t.Fatalf("Apply %+v", resp)
+	}
+}
+
+// TestApplyContext 客户端的超时通过 gRPC 传到服务端，NumbersReqFunc 通过 req.Context 获取
+func TestApplyContext(t *testing.T) {
+	deadlines := make(chan bool, 1)
+	backend := generator.NumbersReqFunc(func(req *generator.ApplyReq) (*generator.NewRangeResp, error) {
+		_, ok := req.Context().Deadline()
+		deadlines <- ok
+		return &generator.NewRangeResp{RangeStart: 1, RangeEnd: int64(req.Step)}, nil
+	})
+	c := dialServer(t, NewServer(backend))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if _, err := c.Apply(ctx, &generator.ApplyReq{AppName: "app", BizType: "T", Day: "20240101", Step: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if !<-deadlines {
+		t.Fatal("backend request has no deadline")
+	}
+}
+
+func TestRelease(t *testing.T) {
+	cases := []struct {
+		name    string
+		backend generator.NumbersClient
+		req     generator.ReleaseReq
+		wantErr bool
+	}{
+		{"ok", testsupport.NewMemoryCaller().Caller(), generator.ReleaseReq{AppName: "app", BizType: "T", Day: "20240101", RangeStart: 5, RangeEnd: 10}, false},
+		{"unimplemented", failingBackend{err: status.Error(codes.Unimplemented, "release")}, generator.ReleaseReq{AppName: "app", BizType: "T", Day: "20240101", RangeStart: 5, RangeEnd: 10}, false},
+		{"invalid range", testsupport.NewMemoryCaller().Caller(), generator.ReleaseReq{AppName: "app", BizType: "T", Day: "20240101", RangeStart: 10, RangeEnd: 5}, true},
+		{"backend error", failingBackend{err: errors.New("down")}, generator.ReleaseReq{AppName: "app", BizType: "T", Day: "20240101", RangeStart: 5, RangeEnd: 10}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := dialServer(t, NewServer(tc.backend))
+			if err := c.Release(context.Background(), &tc.req); (err != nil) != tc.wantErr {
+				t.Fatalf("Release error %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	srv := &heartbeatRecorder{NumbersServiceServer: NewServer(testsupport.NewMemoryCaller().Caller())}
+	c := dialServer(t, srv, WithHeartbeat(10*time.Millisecond), WithInstance("pod-a"))
+	serverTime, err := c.Heartbeat(context.Background(), "app", "T")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(serverTime); d < -time.Second || d > time.Second {
+		t.Fatalf("server time %s is off by %s", serverTime, d)
+	}
+
+	//申请过号段的 appName + bizType 由后台协程定期上报心跳
+	before := len(srv.received())
+	if _, err := c.Apply(context.Background(), &generator.ApplyReq{AppName: "app", BizType: "ORD", Day: "20240101", Step: 10}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		beats := srv.received()
+		if len(beats) > before {
+			last := beats[len(beats)-1]
+			if last.AppName != "app" || last.BizType != "ORD" || last.Instance != "pod-a" {
+				t.Fatalf("heartbeat %v, want app ORD pod-a", last)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no heartbeat after applying a range")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+}
+
+func TestNewRejectsInvalidOptions(t *testing.T) {
+	cases := []struct {
+		name string
+		opt  Option
+	}{
+		{"zero timeout", WithTimeout(0)},
+		{"negative heartbeat", WithHeartbeat(-time.Second)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := New(nil, tc.opt); !errors.Is(err, generator.ErrInvalidOption) {
+				t.Fatalf("New error %v, want ErrInvalidOption", err)
+			}
+		})
+	}
+}
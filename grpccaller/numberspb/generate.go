@@ -0,0 +1,4 @@
+// Package numberspb 号段申请协议 numbers.proto 生成的代码，修改 numbers.proto 后重新生成
+package numberspb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative numbers.proto
@@ -0,0 +1,192 @@
+// 号段申请协议，与 generator.ApplyReq、generator.NewRangeResp、generator.ReleaseReq 一一对应，
+// 供不同语言的服务共用同一个号段服务
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.24.4
+// source: numbers.proto
+
+package numberspb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	NumbersService_ApplyRange_FullMethodName   = "/numbers.apply.v1.NumbersService/ApplyRange"
+	NumbersService_ReleaseRange_FullMethodName = "/numbers.apply.v1.NumbersService/ReleaseRange"
+	NumbersService_Heartbeat_FullMethodName    = "/numbers.apply.v1.NumbersService/Heartbeat"
+)
+
+// NumbersServiceClient is the client API for NumbersService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type NumbersServiceClient interface {
+	// 申请号段，同一 app_name + biz_type + day 下返回的号段单调递增且互不重叠
+	ApplyRange(ctx context.Context, in *ApplyRangeRequest, opts ...grpc.CallOption) (*ApplyRangeResponse, error)
+	// 归还申请到但没有使用的号码，不支持归还的服务可以返回 UNIMPLEMENTED
+	ReleaseRange(ctx context.Context, in *ReleaseRangeRequest, opts ...grpc.CallOption) (*ReleaseRangeResponse, error)
+	// 客户端定期上报存活，服务端可据此回收已经下线的实例持有的号段
+	Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error)
+}
+
+type numbersServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewNumbersServiceClient(cc grpc.ClientConnInterface) NumbersServiceClient {
+	return &numbersServiceClient{cc}
+}
+
+func (c *numbersServiceClient) ApplyRange(ctx context.Context, in *ApplyRangeRequest, opts ...grpc.CallOption) (*ApplyRangeResponse, error) {
+	out := new(ApplyRangeResponse)
+	err := c.cc.Invoke(ctx, NumbersService_ApplyRange_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *numbersServiceClient) ReleaseRange(ctx context.Context, in *ReleaseRangeRequest, opts ...grpc.CallOption) (*ReleaseRangeResponse, error) {
+	out := new(ReleaseRangeResponse)
+	err := c.cc.Invoke(ctx, NumbersService_ReleaseRange_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *numbersServiceClient) Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error) {
+	out := new(HeartbeatResponse)
+	err := c.cc.Invoke(ctx, NumbersService_Heartbeat_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// NumbersServiceServer is the server API for NumbersService service.
+// All implementations must embed UnimplementedNumbersServiceServer
+// for forward compatibility
+type NumbersServiceServer interface {
+	// 申请号段，同一 app_name + biz_type + day 下返回的号段单调递增且互不重叠
+	ApplyRange(context.Context, *ApplyRangeRequest) (*ApplyRangeResponse, error)
+	// 归还申请到但没有使用的号码，不支持归还的服务可以返回 UNIMPLEMENTED
+	ReleaseRange(context.Context, *ReleaseRangeRequest) (*ReleaseRangeResponse, error)
+	// 客户端定期上报存活，服务端可据此回收已经下线的实例持有的号段
+	Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error)
+	mustEmbedUnimplementedNumbersServiceServer()
+}
+
+// UnimplementedNumbersServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedNumbersServiceServer struct {
+}
+
+func (UnimplementedNumbersServiceServer) ApplyRange(context.Context, *ApplyRangeRequest) (*ApplyRangeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ApplyRange not implemented")
+}
+func (UnimplementedNumbersServiceServer) ReleaseRange(context.Context, *ReleaseRangeRequest) (*ReleaseRangeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseRange not implemented")
+}
+func (UnimplementedNumbersServiceServer) Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Heartbeat not implemented")
+}
+func (UnimplementedNumbersServiceServer) mustEmbedUnimplementedNumbersServiceServer() {}
+
+// UnsafeNumbersServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to NumbersServiceServer will
+// result in compilation errors.
+type UnsafeNumbersServiceServer interface {
+	mustEmbedUnimplementedNumbersServiceServer()
+}
+
+func RegisterNumbersServiceServer(s grpc.ServiceRegistrar, srv NumbersServiceServer) {
+	s.RegisterService(&NumbersService_ServiceDesc, srv)
+}
+
+func _NumbersService_ApplyRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ApplyRangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NumbersServiceServer).ApplyRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NumbersService_ApplyRange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NumbersServiceServer).ApplyRange(ctx, req.(*ApplyRangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NumbersService_ReleaseRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReleaseRangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NumbersServiceServer).ReleaseRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NumbersService_ReleaseRange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NumbersServiceServer).ReleaseRange(ctx, req.(*ReleaseRangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NumbersService_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HeartbeatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NumbersServiceServer).Heartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NumbersService_Heartbeat_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NumbersServiceServer).Heartbeat(ctx, req.(*HeartbeatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// NumbersService_ServiceDesc is the grpc.ServiceDesc for NumbersService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var NumbersService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "numbers.apply.v1.NumbersService",
+	HandlerType: (*NumbersServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ApplyRange",
+			Handler:    _NumbersService_ApplyRange_Handler,
+		},
+		{
+			MethodName: "ReleaseRange",
+			Handler:    _NumbersService_ReleaseRange_Handler,
+		},
+		{
+			MethodName: "Heartbeat",
+			Handler:    _NumbersService_Heartbeat_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "numbers.proto",
+}
@@ -0,0 +1,75 @@
+package grpccaller
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/betwins/numbers-apply/generator"
+	"google.golang.org/grpc"
+)
+
+type config struct {
+	timeout           time.Duration     //单次调用的超时时间
+	heartbeatInterval time.Duration     //上报心跳的间隔，0 表示不上报
+	instance          string            //心跳中的实例标识
+	dialOpts          []grpc.DialOption //Dial 建立连接时使用
+	callOpts          []grpc.CallOption //每次调用时使用
+}
+
+type Option func(*config)
+
+const constTimeout = 3 * time.Second
+
+func defaultConfig() config {
+	instance, _ := os.Hostname()
+	return config{
+		timeout:  constTimeout,
+		instance: instance,
+	}
+}
+
+func (c *config) validate() error {
+	if c.timeout <= 0 {
+		return fmt.Errorf("%w: timeout must be positive, got %s", generator.ErrInvalidOption, c.timeout)
+	}
+	if c.heartbeatInterval < 0 {
+		return fmt.Errorf("%w: heartbeat interval must not be negative, got %s", generator.ErrInvalidOption, c.heartbeatInterval)
+	}
+	return nil
+}
+
+// WithTimeout 设置单次调用的超时时间，默认 3 秒；调用方 ctx 的截止时间更早时以 ctx 为准
+func WithTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.timeout = d
+	}
+}
+
+// WithHeartbeat 每隔 interval 为申请过号段的每个 appName + bizType 调用一次 Heartbeat，Close 时停止；默认不上报
+func WithHeartbeat(interval time.Duration) Option {
+	return func(c *config) {
+		c.heartbeatInterval = interval
+	}
+}
+
+// WithInstance 设置心跳中的实例标识，默认为主机名
+func WithInstance(instance string) Option {
+	return func(c *config) {
+		c.instance = instance
+	}
+}
+
+// WithDialOptions 设置 Dial 建立连接时的选项，必须包含传输安全设置（如 grpc.WithTransportCredentials），New 时不生效
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(c *config) {
+		c.dialOpts = append(c.dialOpts, opts...)
+	}
+}
+
+// WithCallOptions 设置每次调用时的选项，如 grpc.WaitForReady
+func WithCallOptions(opts ...grpc.CallOption) Option {
+	return func(c *config) {
+		c.callOpts = append(c.callOpts, opts...)
+	}
+}
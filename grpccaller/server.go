@@ -0,0 +1,82 @@
+package grpccaller
+
+import (
+	"context"
+	"time"
+
+	"github.com/betwins/numbers-apply/generator"
+	"github.com/betwins/numbers-apply/grpccaller/numberspb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// server 把 generator.NumbersClient 包装成 NumbersService 服务端
+type server struct {
+	numberspb.UnimplementedNumbersServiceServer
+	backend generator.NumbersClient
+}
+
+// NewServer 返回转发到 backend 的 NumbersService 服务端，用 numberspb.RegisterNumbersServiceServer 注册；
+// backend 可以是号段服务自己的分配逻辑，也可以是 NumbersReqFunc，用于测试或把已有的号段申请方式暴露为 gRPC 服务。
+// Heartbeat 只返回服务端时间，需要据此回收号段的服务可以嵌入 numberspb.UnimplementedNumbersServiceServer 自行实现
+func NewServer(backend generator.NumbersClient) numberspb.NumbersServiceServer {
+	return &server{backend: backend}
+}
+
+func (s *server) ApplyRange(ctx context.Context, in *numberspb.ApplyRangeRequest) (*numberspb.ApplyRangeResponse, error) {
+	if in.AppName == "" || in.BizType == "" || in.Day == "" || in.Step <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "app_name, biz_type, day and a positive step are required")
+	}
+	//NumbersReqFunc 只能通过 req.Context 获取 ctx，客户端取消或超时后 backend 可以尽快返回
+	resp, err := s.backend.Apply(ctx, generator.ApplyReqWithContext(ctx, generator.ApplyReq{
+		AppName: in.AppName,
+		BizType: in.BizType,
+		Day:     in.Day,
+		Step:    int(in.Step),
+	}))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if resp == nil {
+		//grpc 不会恢复处理函数的 panic，backend 的 bug 不能让整个服务退出
+		return nil, status.Error(codes.Internal, "backend returned nil response")
+	}
+	return &numberspb.ApplyRangeResponse{
+		RangeStart:  resp.RangeStart,
+		RangeEnd:    resp.RangeEnd,
+		Day:         resp.Day,
+		GrantedStep: resp.GrantedStep,
+	}, nil
+}
+
+func (s *server) ReleaseRange(ctx context.Context, in *numberspb.ReleaseRangeRequest) (*numberspb.ReleaseRangeResponse, error) {
+	if in.RangeStart <= 0 || in.RangeEnd < in.RangeStart {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid range %d-%d", in.RangeStart, in.RangeEnd)
+	}
+	err := s.backend.Release(ctx, &generator.ReleaseReq{
+		AppName:    in.AppName,
+		BizType:    in.BizType,
+		Day:        in.Day,
+		RangeStart: in.RangeStart,
+		RangeEnd:   in.RangeEnd,
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &numberspb.ReleaseRangeResponse{}, nil
+}
+
+func (s *server) Heartbeat(context.Context, *numberspb.HeartbeatRequest) (*numberspb.HeartbeatResponse, error) {
+	return &numberspb.HeartbeatResponse{ServerTimeUnixMilli: time.Now().UnixMilli()}, nil
+}
+
+// toStatus 保留 backend 返回的 gRPC 状态，ctx 结束映射为对应的状态码，其它错误按 UNAVAILABLE 返回，客户端可以重试
+func toStatus(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if s := status.FromContextError(err); s.Code() != codes.Unknown {
+		return s.Err()
+	}
+	return status.Error(codes.Unavailable, err.Error())
+}
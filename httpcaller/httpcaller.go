@@ -0,0 +1,175 @@
+package httpcaller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/betwins/numbers-apply/generator"
+)
+
+const constMaxBodySize = 1 << 20 //响应体的最大长度，超过时按错误处理
+
+// Client 通过 HTTP 向号段服务申请号段，实现 generator.NumbersClient，可直接传给 generator.NewWithClient；
+// 请求体为 ApplyReq / ReleaseReq 的 json，响应体默认按 NewRangeResp 的 json 解析，多个协程并发使用是安全的
+type Client struct {
+	baseURL    string
+	cfg        config
+	httpClient *http.Client
+	transport  *http.Transport //内部创建的连接池，使用 WithHTTPClient 时为 nil
+}
+
+// StatusError 号段服务返回了非 2xx 的状态码
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("numbers service returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// New 创建访问 baseURL 的号段服务客户端，申请号段时 POST baseURL + WithApplyPath 设置的路径
+func New(baseURL string, opts ...Option) (*Client, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("%w: base url is empty", generator.ErrInvalidOption)
+	}
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		cfg:        cfg,
+		httpClient: cfg.httpClient,
+	}
+	if c.httpClient == nil {
+		c.transport = http.DefaultTransport.(*http.Transport).Clone()
+		c.transport.MaxIdleConns = cfg.maxIdleConns
+		c.transport.MaxIdleConnsPerHost = cfg.maxIdleConns
+		c.httpClient = &http.Client{Transport: c.transport}
+	}
+	return c, nil
+}
+
+// Apply 申请号段，网络错误、5xx、429 时按 WithRetries 重试
+func (c *Client) Apply(ctx context.Context, req *generator.ApplyReq) (*generator.NewRangeResp, error) {
+	body, err := c.post(ctx, c.cfg.applyPath, req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.cfg.decode(body)
+	if err != nil {
+		return nil, fmt.Errorf("decode apply response: %w", err)
+	}
+	if resp == nil {
+		return nil, errors.New("decode apply response: empty range")
+	}
+	return resp, nil
+}
+
+// Release 归还未使用的号码，没有设置归还路径或号段服务返回 404、405（不支持归还）时直接返回 nil
+func (c *Client) Release(ctx context.Context, req *generator.ReleaseReq) error {
+	if c.cfg.releasePath == "" {
+		return nil
+	}
+	_, err := c.post(ctx, c.cfg.releasePath, req)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusMethodNotAllowed) {
+		return nil
+	}
+	return err
+}
+
+// Close 关闭内部连接池的空闲连接，使用 WithHTTPClient 传入的 http.Client 时不做处理
+func (c *Client) Close() error {
+	if c.transport != nil {
+		c.transport.CloseIdleConnections()
+	}
+	return nil
+}
+
+// Caller 返回可传给 generator.New 等只接受 NumbersReqFunc 的构造函数的申请函数，不支持归还号段
+func (c *Client) Caller() generator.NumbersReqFunc {
+	return func(req *generator.ApplyReq) (*generator.NewRangeResp, error) {
+		return c.Apply(req.Context(), req)
+	}
+}
+
+// post 把 v 序列化为 json 发送到 path，返回 2xx 响应的响应体
+func (c *Client) post(ctx context.Context, path string, v interface{}) ([]byte, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	url := c.baseURL + path
+	backoff := c.cfg.retryBackoff
+	for attempt := 0; ; attempt++ {
+		body, retry, err := c.do(ctx, url, payload)
+		//调用方 ctx 已经结束时不再重试，单次请求超时可以重试
+		if err == nil || !retry || attempt >= c.cfg.retries || ctx.Err() != nil {
+			return body, err
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// do 发送一次请求，retry 表示失败原因是网络错误、5xx 或 429，可以重试
+func (c *Client) do(ctx context.Context, url string, payload []byte) ([]byte, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.timeout)
+	defer cancel()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, false, err
+	}
+	for key, values := range c.cfg.header {
+		httpReq.Header[key] = values
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, true, err
+	}
+	defer httpResp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(httpResp.Body, constMaxBodySize+1))
+	if err != nil {
+		return nil, true, err
+	}
+	if len(body) > constMaxBodySize {
+		return nil, false, fmt.Errorf("numbers service response exceeds %d bytes", constMaxBodySize)
+	}
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		retry := httpResp.StatusCode >= 500 || httpResp.StatusCode == http.StatusTooManyRequests
+		return nil, retry, &StatusError{StatusCode: httpResp.StatusCode, Body: string(body)}
+	}
+	return body, false, nil
+}
+
+// decodeRangeResp 按 generator.NewRangeResp 的 json 格式解析响应体
+func decodeRangeResp(body []byte) (*generator.NewRangeResp, error) {
+	var resp generator.NewRangeResp
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,212 @@
+package httpcaller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/betwins/numbers-apply/callertest"
+	"github.com/betwins/numbers-apply/generator"
+	"github.com/betwins/numbers-apply/testsupport"
+)
+
+// newRangeServer 用 MemoryCaller 实现 /apply 的号段服务，handle 不为 nil 时先交给它处理，返回 true 表示已经响应
+func newRangeServer(t *testing.T, handle func(w http.ResponseWriter, r *http.Request) bool) *httptest.Server {
+	mem := testsupport.NewMemoryCaller()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if handle != nil && handle(w, r) {
+			return
+		}
+		if r.URL.Path != constApplyPath {
+			http.NotFound(w, r)
+			return
+		}
+		var req generator.ApplyReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp, err := mem.Apply(&req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newClient(t *testing.T, baseURL string, opts ...Option) *Client {
+	t.Helper()
+	c, err := New(baseURL, append([]Option{WithRetryBackoff(time.Millisecond)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestConformance(t *testing.T) {
+	callertest.CallerConformanceTest(t, func() generator.NumbersReqFunc {
+		return newClient(t, newRangeServer(t, nil).URL).Caller()
+	})
+}
+
+func TestApplyRetries(t *testing.T) {
+	cases := []struct {
+		name      string
+		status    int //前两次请求返回的状态码
+		retries   int
+		wantErr   bool
+		wantCalls int32
+	}{
+		{"retry 5xx", http.StatusServiceUnavailable, 2, false, 3},
+		{"retry 429", http.StatusTooManyRequests, 2, false, 3},
+		{"retries exhausted", http.StatusBadGateway, 1, true, 2},
+		{"no retry on 4xx", http.StatusBadRequest, 2, true, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls int32
+			srv := newRangeServer(t, func(w http.ResponseWriter, r *http.Request) bool {
+				if atomic.AddInt32(&calls, 1) <= 2 {
+					http.Error(w, "busy", tc.status)
+					return true
+				}
+				return false
+			})
+			c := newClient(t, srv.URL, WithRetries(tc.retries))
+			resp, err := c.Apply(context.Background(), &generator.ApplyReq{AppName: "app", BizType: "T", Day: "20240101", Step: 10})
+			if tc.wantErr {
+				var statusErr *StatusError
+				if !errors.As(err, &statusErr) || statusErr.StatusCode != tc.status {
+					t.Fatalf("Apply error %v, want status %d", err, tc.status)
+				}
+			} else if err != nil || resp.RangeStart != 1 || resp.RangeEnd != 10 {
+				t.Fatalf("Apply %+v %v, want range 1-10", resp, err)
+			}
+			if got := atomic.LoadInt32(&calls); got != tc.wantCalls {
+				t.Fatalf("%d requests, want %d", got, tc.wantCalls)
+			}
+		})
+	}
+}
+
+func TestApplyStopsRetryingWhenCancelled(t *testing.T) {
+	var calls int32
+	srv := newRangeServer(t, func(w http.ResponseWriter, r *http.Request) bool {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+		return true
+	})
+	c := newClient(t, srv.URL, WithRetries(10), WithRetryBackoff(time.Hour))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := c.Apply(ctx, &generator.ApplyReq{AppName: "app", BizType: "T", Day: "20240101", Step: 10}); err == nil {
+		t.Fatal("Apply succeeded against a failing service")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Apply waited %s after the ctx ended", elapsed)
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("%d requests, want 1", got)
+	}
+}
+
+func TestRequestHeaders(t *testing.T) {
+	var auth, custom, contentType atomic.Value
+	srv := newRangeServer(t, func(w http.ResponseWriter, r *http.Request) bool {
+		auth.Store(r.Header.Get("Authorization"))
+		custom.Store(r.Header.Get("X-Tenant"))
+		contentType.Store(r.Header.Get("Content-Type"))
+		return false
+	})
+	c := newClient(t, srv.URL, WithBearerToken("secret"), WithHeader("X-Tenant", "t1"))
+	if _, err := c.Apply(context.Background(), &generator.ApplyReq{AppName: "app", BizType: "T", Day: "20240101", Step: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if auth.Load() != "Bearer secret" || custom.Load() != "t1" || contentType.Load() != "application/json" {
+		t.Fatalf("headers Authorization %q, X-Tenant %q, Content-Type %q", auth.Load(), custom.Load(), contentType.Load())
+	}
+}
+
+func TestRelease(t *testing.T) {
+	cases := []struct {
+		name        string
+		status      int
+		releasePath string
+		wantErr     bool
+		wantCalls   int32
+	}{
+		{"ok", http.StatusOK, constReleasePath, false, 1},
+		{"not supported", http.StatusNotFound, constReleasePath, false, 1},
+		{"method not allowed", http.StatusMethodNotAllowed, constReleasePath, false, 1},
+		{"bad request", http.StatusBadRequest, constReleasePath, true, 1},
+		{"release disabled", http.StatusOK, "", false, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls int32
+			srv := newRangeServer(t, func(w http.ResponseWriter, r *http.Request) bool {
+				atomic.AddInt32(&calls, 1)
+				w.WriteHeader(tc.status)
+				return true
+			})
+			c := newClient(t, srv.URL, WithReleasePath(tc.releasePath))
+			err := c.Release(context.Background(), &generator.ReleaseReq{AppName: "app", BizType: "T", Day: "20240101", RangeStart: 5, RangeEnd: 10})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Release error %v, want error %v", err, tc.wantErr)
+			}
+			if got := atomic.LoadInt32(&calls); got != tc.wantCalls {
+				t.Fatalf("%d requests, want %d", got, tc.wantCalls)
+			}
+		})
+	}
+}
+
+func TestResponseDecoder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"code":0,"data":{"rangeStart":101,"rangeEnd":200}}`))
+	}))
+	defer srv.Close()
+	c := newClient(t, srv.URL, WithResponseDecoder(func(body []byte) (*generator.NewRangeResp, error) {
+		var wrapped struct {
+			Data generator.NewRangeResp `json:"data"`
+		}
+		err := json.Unmarshal(body, &wrapped)
+		return &wrapped.Data, err
+	}))
+	resp, err := c.Apply(context.Background(), &generator.ApplyReq{AppName: "app", BizType: "T", Day: "20240101", Step: 100})
+	if err != nil || resp.RangeStart != 101 || resp.RangeEnd != 200 {
+		t.Fatalf("Apply %+v %v, want range 101-200", resp, err)
+	}
+}
+
+func TestNewRejectsInvalidOptions(t *testing.T) {
+	cases := []struct {
+		name    string
+		baseURL string
+		opts    []Option
+	}{
+		{"empty base url", "", nil},
+		{"zero timeout", "http://numbers", []Option{WithTimeout(0)}},
+		{"negative retries", "http://numbers", []Option{WithRetries(-1)}},
+		{"negative backoff", "http://numbers", []Option{WithRetryBackoff(-time.Second)}},
+		{"zero idle conns", "http://numbers", []Option{WithMaxIdleConns(0)}},
+		{"nil decoder", "http://numbers", []Option{WithResponseDecoder(nil)}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := New(tc.baseURL, tc.opts...); !errors.Is(err, generator.ErrInvalidOption) {
+				t.Fatalf("New error %v, want ErrInvalidOption", err)
+			}
+		})
+	}
+}
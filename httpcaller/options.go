@@ -0,0 +1,137 @@
+package httpcaller
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/betwins/numbers-apply/generator"
+)
+
+type config struct {
+	applyPath   string //申请号段的路径，拼接在 baseURL 之后
+	releasePath string //归还号段的路径，为空时不归还
+
+	timeout      time.Duration //单次请求的超时时间，不含重试
+	retries      int           //网络错误、5xx、429 时的重试次数
+	retryBackoff time.Duration //第一次重试前的等待时间，之后每次翻倍
+
+	header http.Header //每个请求都带上的请求头，如鉴权头
+
+	httpClient   *http.Client //为 nil 时使用内部创建的连接池
+	maxIdleConns int          //内部连接池每个主机保持的空闲连接数
+
+	decode func(body []byte) (*generator.NewRangeResp, error) //解析申请号段的响应体
+}
+
+type Option func(*config)
+
+const (
+	constApplyPath    = "/apply"
+	constReleasePath  = "/release"
+	constTimeout      = 3 * time.Second
+	constRetries      = 2
+	constRetryBackoff = 100 * time.Millisecond
+	constMaxIdleConns = 16
+)
+
+func defaultConfig() config {
+	return config{
+		applyPath:    constApplyPath,
+		releasePath:  constReleasePath,
+		timeout:      constTimeout,
+		retries:      constRetries,
+		retryBackoff: constRetryBackoff,
+		header:       make(http.Header),
+		maxIdleConns: constMaxIdleConns,
+		decode:       decodeRangeResp,
+	}
+}
+
+func (c *config) validate() error {
+	if c.timeout <= 0 {
+		return fmt.Errorf("%w: timeout must be positive, got %s", generator.ErrInvalidOption, c.timeout)
+	}
+	if c.retries < 0 {
+		return fmt.Errorf("%w: retries must not be negative, got %d", generator.ErrInvalidOption, c.retries)
+	}
+	if c.retryBackoff < 0 {
+		return fmt.Errorf("%w: retry backoff must not be negative, got %s", generator.ErrInvalidOption, c.retryBackoff)
+	}
+	if c.maxIdleConns <= 0 {
+		return fmt.Errorf("%w: max idle conns must be positive, got %d", generator.ErrInvalidOption, c.maxIdleConns)
+	}
+	if c.decode == nil {
+		return fmt.Errorf("%w: response decoder is nil", generator.ErrInvalidOption)
+	}
+	return nil
+}
+
+// WithApplyPath 设置申请号段的路径，默认 /apply
+func WithApplyPath(path string) Option {
+	return func(c *config) {
+		c.applyPath = path
+	}
+}
+
+// WithReleasePath 设置归还号段的路径，默认 /release；设置为空串时 Release 直接返回，不发送请求
+func WithReleasePath(path string) Option {
+	return func(c *config) {
+		c.releasePath = path
+	}
+}
+
+// WithTimeout 设置单次请求的超时时间，默认 3 秒；调用方 ctx 的截止时间更早时以 ctx 为准
+func WithTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.timeout = d
+	}
+}
+
+// WithRetries 设置网络错误、5xx、429 时的重试次数，默认 2 次，0 表示不重试；4xx 等其它错误不重试
+func WithRetries(n int) Option {
+	return func(c *config) {
+		c.retries = n
+	}
+}
+
+// WithRetryBackoff 设置第一次重试前的等待时间，默认 100ms，之后每次翻倍
+func WithRetryBackoff(d time.Duration) Option {
+	return func(c *config) {
+		c.retryBackoff = d
+	}
+}
+
+// WithHeader 为每个请求设置请求头，重复设置同一个 key 时以最后一次为准
+func WithHeader(key, value string) Option {
+	return func(c *config) {
+		c.header.Set(key, value)
+	}
+}
+
+// WithBearerToken 设置 Authorization: Bearer token 鉴权头
+func WithBearerToken(token string) Option {
+	return WithHeader("Authorization", "Bearer "+token)
+}
+
+// WithHTTPClient 使用调用方的 http.Client 发送请求，此时 WithMaxIdleConns 不生效，Close 也不会关闭其空闲连接
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *config) {
+		c.httpClient = client
+	}
+}
+
+// WithMaxIdleConns 设置内部连接池每个主机保持的空闲连接数，默认 16，生成器并发申请号段较多时可以调大
+func WithMaxIdleConns(n int) Option {
+	return func(c *config) {
+		c.maxIdleConns = n
+	}
+}
+
+// WithResponseDecoder 设置申请号段响应体的解析函数，号段服务把结果包在 {code, msg, data} 等结构中时使用；
+// 默认直接按 generator.NewRangeResp 的 json 格式解析
+func WithResponseDecoder(decode func(body []byte) (*generator.NewRangeResp, error)) Option {
+	return func(c *config) {
+		c.decode = decode
+	}
+}
@@ -0,0 +1,65 @@
+package testsupport
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/betwins/numbers-apply/generator"
+)
+
+const constUniqueAppName = "unique-test"
+
+// GenerateUnique 用 MemoryCaller 创建一个生成器，以 parallelism 个协程合计生成 n 个 id，
+// 返回其中不重复的 id 数和生成耗时（不含去重统计），可同时作为正确性检查和吞吐量基准，
+// opts 为待验证的生成器配置；出现生成错误时返回第一个错误，uniqueCount 只统计成功生成的 id
+func GenerateUnique(n int, parallelism int, opts ...generator.Option) (uniqueCount int, duration time.Duration, err error) {
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+	gen := generator.New(NewMemoryCaller().Caller(), append([]generator.Option{generator.WithAppName(constUniqueAppName), generator.WithPrefix("UNQ")}, opts...)...)
+	defer gen.Close()
+
+	batches := make([][]string, parallelism)
+	errs := make([]error, parallelism)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < parallelism; w++ {
+		count := n / parallelism
+		if w < n%parallelism {
+			count++
+		}
+		wg.Add(1)
+		go func(w, count int) {
+			defer wg.Done()
+			ids := make([]string, 0, count)
+			for k := 0; k < count; k++ {
+				id, err := gen.GenerateId(constUniqueAppName)
+				if err != nil {
+					if errs[w] == nil {
+						errs[w] = fmt.Errorf("worker %d: %w", w, err)
+					}
+					continue
+				}
+				ids = append(ids, id)
+			}
+			batches[w] = ids
+		}(w, count)
+	}
+	wg.Wait()
+	duration = time.Since(start)
+
+	seen := make(map[string]struct{}, n)
+	for _, ids := range batches {
+		for _, id := range ids {
+			seen[id] = struct{}{}
+		}
+	}
+	for _, e := range errs {
+		if e != nil {
+			err = e
+			break
+		}
+	}
+	return len(seen), duration, err
+}
@@ -0,0 +1,47 @@
+package testsupport
+
+import (
+	"testing"
+
+	"github.com/betwins/numbers-apply/generator"
+)
+
+func TestGenerateUnique(t *testing.T) {
+	cases := []struct {
+		name        string
+		n           int
+		parallelism int
+		opts        []generator.Option
+	}{
+		{"default config", 10000, 8, nil},
+		{"single goroutine", 1000, 1, nil},
+		{"uneven split", 1001, 7, nil},
+		{"small step", 5000, 16, []generator.Option{generator.WithStep(13)}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			unique, duration, err := GenerateUnique(tc.n, tc.parallelism, tc.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if unique != tc.n {
+				t.Fatalf("%d unique ids of %d", unique, tc.n)
+			}
+			if duration <= 0 {
+				t.Fatalf("duration %s", duration)
+			}
+		})
+	}
+}
+
+// BenchmarkGenerateUnique 每次迭代生成一个 id，同时检查没有重复
+func BenchmarkGenerateUnique(b *testing.B) {
+	unique, duration, err := GenerateUnique(b.N, 8)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if unique != b.N {
+		b.Fatalf("%d unique ids of %d", unique, b.N)
+	}
+	b.ReportMetric(float64(duration.Nanoseconds())/float64(b.N), "gen-ns/op")
+}
@@ -0,0 +1,49 @@
+package testsupport
+
+import (
+	"sync"
+
+	"github.com/betwins/numbers-apply/generator"
+)
+
+// MemoryCaller 进程内的号段分配器，按 appName + bizType + day 独立递增，用于测试和本地调试
+type MemoryCaller struct {
+	mu       sync.Mutex
+	maxIds   map[string]int64
+	reqCount int64
+}
+
+func NewMemoryCaller() *MemoryCaller {
+	return &MemoryCaller{maxIds: make(map[string]int64)}
+}
+
+// Apply 实现 generator.NumbersReqFunc
+func (c *MemoryCaller) Apply(req *generator.ApplyReq) (*generator.NewRangeResp, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reqCount++
+	key := req.AppName + "|" + req.BizType + "|" + req.Day
+	start := c.maxIds[key] + 1
+	c.maxIds[key] += int64(req.Step)
+	return &generator.NewRangeResp{RangeStart: start, RangeEnd: c.maxIds[key]}, nil
+}
+
+// Caller 返回可直接传给 generator.New 的申请函数
+func (c *MemoryCaller) Caller() generator.NumbersReqFunc {
+	return c.Apply
+}
+
+// RequestCount 返回累计收到的号段申请次数
+func (c *MemoryCaller) RequestCount() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reqCount
+}
+
+// NopLogger 丢弃所有日志
+type NopLogger struct{}
+
+func (NopLogger) Debug(format string, v ...any) {}
+func (NopLogger) Info(format string, v ...any)  {}
+func (NopLogger) Warn(format string, v ...any)  {}
+func (NopLogger) Error(format string, v ...any) {}
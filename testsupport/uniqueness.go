@@ -0,0 +1,129 @@
+package testsupport
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/betwins/numbers-apply/generator"
+)
+
+// UniquenessConfig 描述一次多实例唯一性校验：Instances 个生成器共享同一个 Caller，
+// 每个实例用 Concurrency 个协程合计生成 PerInstance 个 id
+type UniquenessConfig struct {
+	Caller      generator.NumbersReqFunc
+	Instances   int
+	PerInstance int
+	Concurrency int //每个实例的并发协程数，默认 1
+	AppName     string
+	Prefix      string
+	Options     []generator.Option
+	Logs        generator.LogInterface //默认 NopLogger
+}
+
+// Collision 一个重复的 id 以及生成过它的实例序号（同一实例重复生成时序号会重复出现）
+type Collision struct {
+	ID        string
+	Instances []int
+}
+
+// UniquenessReport 校验结果
+type UniquenessReport struct {
+	Total      int
+	Unique     int
+	Errors     []error
+	Collisions []Collision
+}
+
+func (r *UniquenessReport) OK() bool {
+	return len(r.Collisions) == 0 && len(r.Errors) == 0
+}
+
+func (r *UniquenessReport) String() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "total %d, unique %d, errors %d, collisions %d", r.Total, r.Unique, len(r.Errors), len(r.Collisions))
+	for _, c := range r.Collisions {
+		fmt.Fprintf(b, "\n  %s generated by instances %v", c.ID, c.Instances)
+	}
+	for _, err := range r.Errors {
+		fmt.Fprintf(b, "\n  error: %s", err.Error())
+	}
+	return b.String()
+}
+
+// CheckUniqueness 按配置启动多个实例并发生成 id，返回包含所有重复 id 的报告，生成结束后关闭所有实例
+func CheckUniqueness(cfg UniquenessConfig) *UniquenessReport {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.Logs == nil {
+		cfg.Logs = NopLogger{}
+	}
+
+	owners := make(map[string][]int, cfg.Instances*cfg.PerInstance)
+	report := &UniquenessReport{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	gens := make([]*generator.RangeUsageInfoStruct, 0, cfg.Instances)
+	for i := 0; i < cfg.Instances; i++ {
+		gen := generator.New(cfg.Caller, append([]generator.Option{generator.WithLogger(cfg.Logs), generator.WithAppName(cfg.AppName), generator.WithPrefix(cfg.Prefix)}, cfg.Options...)...)
+		gens = append(gens, gen)
+		for w := 0; w < cfg.Concurrency; w++ {
+			n := cfg.PerInstance / cfg.Concurrency
+			if w < cfg.PerInstance%cfg.Concurrency {
+				n++
+			}
+			wg.Add(1)
+			go func(instance, n int) {
+				defer wg.Done()
+				ids := make([]string, 0, n)
+				var errs []error
+				for k := 0; k < n; k++ {
+					id, err := gen.GenerateId(cfg.AppName)
+					if err != nil {
+						errs = append(errs, fmt.Errorf("instance %d: %w", instance, err))
+						continue
+					}
+					ids = append(ids, id)
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				report.Errors = append(report.Errors, errs...)
+				for _, id := range ids {
+					owners[id] = append(owners[id], instance)
+				}
+				report.Total += len(ids)
+			}(i, n)
+		}
+	}
+	wg.Wait()
+	//关闭生成器，停止后台协程并按配置保存状态，避免多次调用后协程堆积
+	for i, gen := range gens {
+		if err := gen.Close(); err != nil {
+			report.Errors = append(report.Errors, fmt.Errorf("instance %d close: %w", i, err))
+		}
+	}
+
+	report.Unique = len(owners)
+	for id, instances := range owners {
+		if len(instances) > 1 {
+			sort.Ints(instances)
+			report.Collisions = append(report.Collisions, Collision{ID: id, Instances: instances})
+		}
+	}
+	sort.Slice(report.Collisions, func(i, j int) bool { return report.Collisions[i].ID < report.Collisions[j].ID })
+	return report
+}
+
+// AssertUnique 执行 CheckUniqueness，存在重复 id 或生成错误时标记测试失败
+func AssertUnique(t testing.TB, cfg UniquenessConfig) *UniquenessReport {
+	t.Helper()
+	report := CheckUniqueness(cfg)
+	if !report.OK() {
+		t.Errorf("uniqueness check failed: %s", report)
+	}
+	return report
+}
@@ -0,0 +1,87 @@
+package testsupport
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/betwins/numbers-apply/generator"
+)
+
+func TestCheckUniqueness(t *testing.T) {
+	cases := []struct {
+		name string
+		opts []generator.Option
+	}{
+		{"default", nil},
+		{"small step", []generator.Option{generator.WithStep(7)}},
+		{"range queue", []generator.Option{generator.WithStep(50), generator.WithRangeQueueDepth(2)}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			report := AssertUnique(t, UniquenessConfig{
+				Caller:      NewMemoryCaller().Caller(),
+				Instances:   4,
+				PerInstance: 500,
+				Concurrency: 4,
+				AppName:     "app",
+				Prefix:      "UNQ",
+				Options:     tc.opts,
+			})
+			if report.Total != 2000 || report.Unique != 2000 {
+				t.Fatalf("report %s, want 2000 unique ids", report)
+			}
+		})
+	}
+}
+
+// TestCheckUniquenessReportsCollisions 每次都返回同一个号段的申请函数会让不同实例生成相同的 id
+func TestCheckUniquenessReportsCollisions(t *testing.T) {
+	report := CheckUniqueness(UniquenessConfig{
+		Caller: func(req *generator.ApplyReq) (*generator.NewRangeResp, error) {
+			return &generator.NewRangeResp{RangeStart: 1, RangeEnd: int64(req.Step)}, nil
+		},
+		Instances:   2,
+		PerInstance: 10,
+		AppName:     "app",
+		Prefix:      "UNQ",
+	})
+	if report.OK() || len(report.Collisions) != 10 {
+		t.Fatalf("report %s, want 10 collisions", report)
+	}
+	for _, c := range report.Collisions {
+		if len(c.Instances) != 2 || c.Instances[0] != 0 || c.Instances[1] != 1 {
+			t.Fatalf("collision %s instances %v, want [0 1]", c.ID, c.Instances)
+		}
+	}
+}
+
+// countingStore 记录 Save 的调用次数
+type countingStore struct {
+	mu    sync.Mutex
+	saves int
+}
+
+func (s *countingStore) Load() (*generator.State, error) { return nil, nil }
+func (s *countingStore) Clear() error                    { return nil }
+func (s *countingStore) Save(*generator.State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saves++
+	return nil
+}
+
+// TestCheckUniquenessClosesGenerators 尽量减少空洞模式下 Close 会保存状态，每个实例都应保存一次
+func TestCheckUniquenessClosesGenerators(t *testing.T) {
+	store := &countingStore{}
+	AssertUnique(t, UniquenessConfig{
+		Caller:      NewMemoryCaller().Caller(),
+		Instances:   3,
+		PerInstance: 10,
+		AppName:     "app",
+		Prefix:      "UNQ",
+		Options:     []generator.Option{generator.WithGapPolicy(generator.MinimizeGaps), generator.WithStateStore(store)},
+	})
+	if store.saves != 3 {
+		t.Fatalf("state saved %d times, want once per instance", store.saves)
+	}
+}